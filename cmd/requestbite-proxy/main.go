@@ -31,11 +31,66 @@ func main() {
 	// Command line flags
 	var (
 		port             = flag.IntP("port", "p", DefaultPort, "Port to listen on")
+		basePath         = flag.String("base-path", "", "Mount all routes under this URL path prefix, e.g. /slingshot when behind a reverse proxy")
 		enableLocalFiles = flag.Bool("enable-local-files", false, "Enable local file and directory serving")
 		blacklistFile    = flag.String("enable-blacklist", "", "Enable hostname blacklist from file (one hostname per line)")
 		enableLogging    = flag.BoolP("logging", "l", false, "Enable verbose logging")
+		debugLogging     = flag.Bool("debug", false, "Log streaming diagnostics ([SSE-DEBUG] lines)")
 		enableExec       = flag.Bool("enable-exec", false, "Enable process execution via /exec endpoint")
+		execInheritEnv   = flag.Bool("exec-inherit-env", false, "Pass the proxy's environment to /exec commands (default: only variables from the request)")
+		execRoot         = flag.String("exec-root", "", "Confine /exec working directories to this base directory")
+		fileRoot         = flag.String("file-root", "", "Confine bodyFromPath request body files, /file, /file/batch and /dir to this base directory (relative paths resolve inside it)")
+		execMaxOutput    = flag.Int64("exec-max-output", proxy.DefaultExecMaxOutput, "Maximum bytes of /exec output captured per command (0 = unlimited)")
+		execMaxProcs     = flag.Int("exec-max-concurrent", proxy.DefaultExecMaxConcurrent, "Maximum /exec processes running at once; excess requests are rejected with 503 (0 = no limit)")
+		execAllowlist    = flag.String("exec-allowlist", "", "Restrict /exec to commands listed in file (one command per line, optional argument regex)")
+		passThrough      = flag.Bool("default-pass-through", false, "Default /proxy/request to passThrough mode when the request doesn't specify it")
+		errorStatus      = flag.Bool("error-status-codes", false, "Answer failed upstream requests with 502/504/4xx instead of 200 when the request doesn't specify errorStatusCodes")
+		mirrorStatus     = flag.Bool("mirror-status", false, "Answer /proxy/request and /proxy/form with the upstream's status instead of 200 when the request doesn't specify mirrorStatus")
+		notFoundFormat   = flag.String("not-found-format", proxy.NotFoundFormatJSON, "Format of 404 responses for unknown endpoints: json or text")
+		notFoundBody     = flag.String("not-found-body", "", "Custom body for 404 responses (must be valid JSON with --not-found-format json)")
+		trailingSlash    = flag.String("trailing-slash", proxy.TrailingSlashStrict, "Handling of endpoint paths with a trailing slash, e.g. /proxy/request/: strict (404), accept or redirect (308)")
+		passThroughTypes = flag.String("pass-through-types", "", "Allow/deny content types served as-is in passThrough mode from file (one \"prefix: allow|deny\" per line)")
+		maxPerHost       = flag.Int("max-requests-per-host", 0, "Maximum concurrent upstream requests per target host; excess requests queue (0 = no limit)")
+		noLoopDetection  = flag.Bool("disable-loop-detection", false, "Disable loop detection, including the hostname blacklist (UNSAFE: trusted test setups only)")
+		blockPrivate     = flag.Bool("block-private-networks", false, "Refuse upstream connections to loopback, private and link-local addresses (SSRF protection)")
+		allowedSchemes   = flag.StringSlice("allowed-schemes", proxy.DefaultAllowedSchemes, "Comma-separated URL schemes accepted for upstream requests")
+		allowedPorts     = flag.StringSlice("allowed-ports", nil, "Comma-separated upstream ports or ranges accepted, e.g. 80,443,8000-8999; URLs without a port use the scheme's default (default: any port)")
+		formForward      = flag.StringSlice("form-forward-headers", nil, "Comma-separated headers of the client's request that /proxy/form forwards upstream, e.g. Authorization (default: none)")
+		healthHosts      = flag.StringSlice("health-check-hosts", nil, "Comma-separated blocked hostnames whose /health and / may still be requested (default: all; \"\" = none)")
+		maxResponseBytes = flag.Int64("max-response-bytes", 0, "Maximum upstream response body size held in memory, also in streaming fallback (0 = unlimited)")
+		responseCacheTTL = flag.Duration("response-cache-ttl", 0, "Reuse successful GET/HEAD responses to identical requests for this long (0 = no caching)")
+		coalesce         = flag.Bool("coalesce-requests", false, "Share one upstream call between identical concurrent GET/HEAD requests")
+		tcpKeepAlive     = flag.Duration("tcp-keepalive", proxy.DefaultTCPKeepAlive, "TCP keep-alive probe period of upstream connections, keeps idle streams alive through NATs (0 = disabled)")
+		tcpNoDelay       = flag.Bool("tcp-nodelay", true, "Disable Nagle's algorithm on upstream connections; --tcp-nodelay=false coalesces small writes instead")
+		tcpFastOpen      = flag.Bool("tcp-fast-open", false, "Request TCP Fast Open on upstream connections (Linux; ignored where unsupported)")
+		sourceAddress    = flag.String("source-address", "", "Local IP address upstream connections are made from, e.g. on multi-homed hosts (default: chosen by the OS)")
+		maxStreams       = flag.Int("max-streaming-connections", 0, "Maximum concurrent streaming requests; excess requests are rejected with 503 (0 = no limit)")
+		headerTimeout    = flag.Duration("response-header-timeout", 0, "Fail requests whose upstream doesn't send response headers within this duration; requests can override it (0 = no limit)")
+		streamMaxTime    = flag.Duration("stream-max-duration", 0, "Close streaming responses after this duration, even if still active (0 = no limit)")
+		enableCapture    = flag.Bool("enable-body-capture", false, "Allow requests to capture request/response bodies to temp files")
+		captureDir       = flag.String("body-capture-dir", "", "Directory for captured body files (default: system temp dir)")
+		captureTTL       = flag.Duration("body-capture-ttl", proxy.DefaultBodyCaptureTTL, "How long captured body files are kept before removal")
+		contentTypes     = flag.String("content-types", "", "Override binary/text classification of MIME types from file (one \"prefix: binary|text\" per line)")
+		mimeTypes        = flag.String("mime-types", "", "Override the Content-Type /file serves per file extension from file (one \".ext: type\" per line)")
+		responseHeaders  = flag.String("response-headers", "", "Rewrite upstream response headers using rules from file (\"remove Name\" or \"set Name: value\" per line)")
+		readyCheckURL    = flag.String("ready-check-url", "", "Upstream URL /ready must reach before reporting ready (default: no upstream check)")
+		readyTimeout     = flag.Duration("ready-check-timeout", proxy.DefaultReadyCheckTimeout, "Maximum duration of the /ready upstream check, body included")
+		readyMaxBytes    = flag.Int64("ready-check-max-bytes", proxy.DefaultReadyCheckMaxBytes, "Maximum response body bytes read by the /ready upstream check (0 = unlimited)")
+		defaultHeaders   = flag.String("default-headers", "", "Add headers from file to every outbound request unless the caller sets them (one \"Name: value\" per line)")
+		allowedMethods   = flag.String("allowed-methods", "", "Restrict upstream methods per endpoint using rules from file (\"<endpoint>|*: METHOD, METHOD\" per line)")
+		enablePprof      = flag.Bool("pprof", false, "Serve Go profiling endpoints under /debug/pprof/ (localhost only)")
+		proxyProtocol    = flag.Bool("proxy-protocol", false, "Require a PROXY protocol v1/v2 header on every connection (behind an L4 load balancer) and use its client address")
+		proxyTrusted     = flag.StringSlice("proxy-protocol-trusted", nil, "Comma-separated CIDRs or IPs of the load balancers allowed to send PROXY headers; connections from other peers are dropped (default: any peer)")
+		decompressBodies = flag.Bool("decompress-request-bodies", false, "Decode gzip/deflate client request bodies (Content-Encoding) before handling them")
+		bodyRules        = flag.String("body-rules", "", "Redact or remove JSON body fields using rules from file (\"redact|remove request|response|both field.path\" per line)")
+		oauth2Config     = flag.String("oauth2-config", "", "Enable OAuth2 client-credentials token injection from JSON config file")
 		noUpgradeCheck   = flag.Bool("no-upgrade-check", false, "Disable automatic upgrade check")
+		maxPathParams    = flag.Int("max-path-params", proxy.DefaultMaxPathParams, "Maximum number of path parameters per request (0 = unlimited)")
+		maxURLLength     = flag.Int("max-url-length", proxy.DefaultMaxURLLength, "Maximum URL length after path parameter substitution (0 = unlimited)")
+		maxHeaderValue   = flag.Int("max-header-value-length", proxy.DefaultMaxHeaderValueLength, "Maximum length of a single outbound header value (0 = unlimited)")
+		maxDirEntries    = flag.Int("max-dir-entries", 0, "Maximum number of entries scanned per /dir listing (0 = unlimited)")
+		maxHeaderBytes   = flag.Int("max-header-bytes", proxy.DefaultMaxHeaderBytes, "Maximum total size of outbound request headers (0 = unlimited)")
+		maxHeaderCount   = flag.Int("max-header-count", proxy.DefaultMaxHeaderCount, "Maximum number of headers per request (0 = unlimited)")
 		showVersion      = flag.BoolP("version", "v", false, "Show version information")
 		showHelp         = flag.BoolP("help", "h", false, "Show help information")
 	)
@@ -68,13 +123,106 @@ func main() {
 		checkForUpdates()
 	}
 
+	// An explicit --health-check-hosts, even an empty one, restricts the exemption
+	var healthCheckHosts []string
+	if flag.CommandLine.Changed("health-check-hosts") {
+		healthCheckHosts = append([]string{}, *healthHosts...)
+	}
+
 	// Start the proxy server
-	server, err := proxy.NewServer(*port, Version, *enableLocalFiles, *blacklistFile, *enableLogging, *enableExec)
+	server, err := proxy.NewServer(proxy.Config{
+		Port:             *port,
+		BasePath:         *basePath,
+		Version:          Version,
+		BuildTime:        BuildTime,
+		GitCommit:        GitCommit,
+		EnableLocalFiles: *enableLocalFiles,
+		EnableLogging:    *enableLogging,
+		EnableExec:       *enableExec,
+		ExecInheritEnv:   *execInheritEnv,
+		ExecRoot:         *execRoot,
+		FileRoot:         *fileRoot,
+		ExecMaxOutput:    *execMaxOutput,
+
+		ExecMaxConcurrent: *execMaxProcs,
+
+		Debug: *debugLogging,
+
+		DefaultPassThrough: *passThrough,
+		ErrorStatusCodes:   *errorStatus,
+		MirrorStatus:       *mirrorStatus,
+		NoColor:            os.Getenv("NO_COLOR") != "",
+		MaxRequestsPerHost: *maxPerHost,
+		StreamMaxDuration:  *streamMaxTime,
+		TCPKeepAlive:       *tcpKeepAlive,
+		DisableTCPNoDelay:  !*tcpNoDelay,
+		TCPFastOpen:        *tcpFastOpen,
+		SourceAddress:      *sourceAddress,
+		CoalesceRequests:   *coalesce,
+		ResponseCacheTTL:   *responseCacheTTL,
+		MaxResponseBytes:   *maxResponseBytes,
+
+		MaxStreamingConnections: *maxStreams,
+		ResponseHeaderTimeout:   *headerTimeout,
+
+		BlockPrivateNetworks: *blockPrivate,
+		DisableLoopDetection: *noLoopDetection,
+
+		AllowedSchemes: *allowedSchemes,
+		AllowedPorts:   *allowedPorts,
+
+		HealthCheckHosts: healthCheckHosts,
+
+		FormForwardHeaders: *formForward,
+
+		DecompressRequestBodies: *decompressBodies,
+		EnablePprof:             *enablePprof,
+
+		ProxyProtocol:        *proxyProtocol,
+		ProxyProtocolTrusted: *proxyTrusted,
+
+		NotFoundFormat: *notFoundFormat,
+		NotFoundBody:   *notFoundBody,
+		TrailingSlash:  *trailingSlash,
+
+		EnableBodyCapture: *enableCapture,
+		BodyCaptureDir:    *captureDir,
+		BodyCaptureTTL:    *captureTTL,
+
+		BlacklistFile:       *blacklistFile,
+		OAuth2ConfigFile:    *oauth2Config,
+		ContentTypesFile:    *contentTypes,
+		ResponseHeadersFile: *responseHeaders,
+		ExecAllowlistFile:   *execAllowlist,
+
+		PassThroughTypesFile: *passThroughTypes,
+		BodyRulesFile:        *bodyRules,
+		DefaultHeadersFile:   *defaultHeaders,
+		AllowedMethodsFile:   *allowedMethods,
+
+		MimeTypesFile: *mimeTypes,
+
+		MaxPathParams: *maxPathParams,
+		MaxURLLength:  *maxURLLength,
+
+		MaxHeaderValueLength: *maxHeaderValue,
+		MaxHeaderBytes:       *maxHeaderBytes,
+		MaxHeaderCount:       *maxHeaderCount,
+
+		MaxDirEntries: *maxDirEntries,
+
+		ReadyCheckURL:      *readyCheckURL,
+		ReadyCheckTimeout:  *readyTimeout,
+		ReadyCheckMaxBytes: *readyMaxBytes,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create proxy server: %v", err)
 	}
 
 	fmt.Printf("RequestBite Slingshot Proxy v%s listening on port %d\n", Version, *port)
+	if *basePath != "" {
+		fmt.Printf("\033[33mInfo:\033[0m Routes are mounted under %s (e.g. %s/health)\n", server.BasePath(), server.BasePath())
+	}
 
 	// Show security warnings for enabled features
 	if *enableLocalFiles || *enableExec {
@@ -105,9 +253,52 @@ func main() {
 		fmt.Println("╚═══════════════════════════════════════════════════════════════════════════╝\033[0m")
 	}
 
+	if *noLoopDetection {
+		fmt.Printf("\033[31mWarning:\033[0m Loop detection is disabled. Requests back to this proxy (and to blacklisted\n")
+		fmt.Printf("hostnames) are no longer blocked, so a request can loop forever. Only use this in isolated test setups.\n")
+	}
+	if *proxyProtocol {
+		fmt.Printf("\033[33mInfo:\033[0m Connections must start with a PROXY protocol header; client addresses are taken from it\n")
+		if len(*proxyTrusted) > 0 {
+			fmt.Printf("\033[33mInfo:\033[0m PROXY headers are only accepted from: %s\n", strings.Join(*proxyTrusted, ","))
+		} else {
+			fmt.Printf("\033[31mWarning:\033[0m PROXY headers are accepted from any peer, so a client that reaches this port directly\n")
+			fmt.Printf("can claim any address. Use --proxy-protocol-trusted to only accept the load balancer's.\n")
+		}
+	}
+	if *enablePprof {
+		fmt.Printf("\033[33mInfo:\033[0m Profiling endpoints are served under %s/debug/pprof/ (localhost only)\n", server.BasePath())
+	}
 	if *blacklistFile != "" {
 		fmt.Printf("\033[33mInfo:\033[0m Hostname blacklist enabled from file: %s\n", *blacklistFile)
 	}
+	if *allowedMethods != "" {
+		fmt.Printf("\033[33mInfo:\033[0m Upstream methods restricted by policy from file: %s\n", *allowedMethods)
+	}
+	if *blockPrivate {
+		fmt.Printf("\033[33mInfo:\033[0m Upstream requests to private network addresses are blocked\n")
+	}
+	if len(*allowedPorts) > 0 {
+		fmt.Printf("\033[33mInfo:\033[0m Upstream ports restricted to: %s\n", strings.Join(*allowedPorts, ","))
+	}
+	if *execAllowlist != "" {
+		fmt.Printf("\033[33mInfo:\033[0m Exec allowlist enabled from file: %s\n", *execAllowlist)
+	}
+	if *enableLocalFiles && *fileRoot != "" {
+		fmt.Printf("\033[33mInfo:\033[0m bodyFromPath, /file, /file/batch and /dir paths confined to: %s\n", *fileRoot)
+	}
+	if *enableExec && *execRoot != "" {
+		fmt.Printf("\033[33mInfo:\033[0m Exec working directories confined to: %s\n", *execRoot)
+	}
+	if *enableExec && *execInheritEnv {
+		fmt.Printf("\033[33mInfo:\033[0m Exec commands inherit the proxy's environment\n")
+	}
+	if *enableCapture {
+		fmt.Printf("\033[33mInfo:\033[0m Body capture enabled (files removed after %s)\n", *captureTTL)
+	}
+	if *oauth2Config != "" {
+		fmt.Printf("\033[33mInfo:\033[0m OAuth2 token injection enabled from file: %s\n", *oauth2Config)
+	}
 	fmt.Println("Press Ctrl+C to stop")
 
 	if err := server.Start(); err != nil {
@@ -205,7 +396,7 @@ func checkForUpdates() {
 
 	// Handle platform-specific installation
 	if runtime.GOOS == "windows" {
-		fmt.Println("See https://github.com/requestbite/proxy/ for installation details.\n")
+		fmt.Print("See https://github.com/requestbite/proxy/ for installation details.\n\n")
 		return
 	}
 
@@ -223,10 +414,10 @@ func checkForUpdates() {
 		fmt.Println("\nInstalling update...")
 		if err := installUpdate(); err != nil {
 			fmt.Printf("\033[31mFailed to install update: %v\033[0m\n", err)
-			fmt.Println("Please visit https://github.com/requestbite/proxy/ for manual installation.\n")
+			fmt.Print("Please visit https://github.com/requestbite/proxy/ for manual installation.\n\n")
 		} else {
 			fmt.Println("\033[32mUpdate installed successfully!\033[0m")
-			fmt.Println("Please restart the proxy to use the new version.\n")
+			fmt.Print("Please restart the proxy to use the new version.\n\n")
 			os.Exit(0)
 		}
 	} else {
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// schemeDefaultPorts are the ports assumed for URLs that don't name one
+var schemeDefaultPorts = map[string]int{
+	"http":  80,
+	"https": 443,
+	"ws":    80,
+	"wss":   443,
+}
+
+// portRange is an inclusive range of upstream ports accepted by --allowed-ports
+type portRange struct {
+	low, high int
+}
+
+func (r portRange) String() string {
+	if r.low == r.high {
+		return strconv.Itoa(r.low)
+	}
+	return fmt.Sprintf("%d-%d", r.low, r.high)
+}
+
+// portNotAllowedError reports an upstream URL, possibly a redirect target, whose port isn't allowed
+type portNotAllowedError struct {
+	message string
+}
+
+func (e *portNotAllowedError) Error() string {
+	return e.message
+}
+
+// setAllowedPorts replaces the upstream ports accepted for requests (empty = any port)
+// Each entry is a port like 443 or an inclusive range like 8000-8999.
+func (c *HTTPClient) setAllowedPorts(specs []string) error {
+	var allowed []portRange
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		lowSpec, highSpec := spec, spec
+		if i := strings.Index(spec, "-"); i >= 0 {
+			lowSpec, highSpec = spec[:i], spec[i+1:]
+		}
+		low, errLow := strconv.Atoi(lowSpec)
+		high, errHigh := strconv.Atoi(highSpec)
+		if errLow != nil || errHigh != nil || low < 1 || high > 65535 || low > high {
+			return fmt.Errorf("invalid port or port range %q", spec)
+		}
+		allowed = append(allowed, portRange{low, high})
+	}
+	c.allowedPorts = allowed
+	return nil
+}
+
+// checkPort enforces --allowed-ports on a URL, using the scheme's default port if it names none
+func (c *HTTPClient) checkPort(u *url.URL) error {
+	if c.allowedPorts == nil {
+		return nil
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	port, ok := schemeDefaultPorts[scheme]
+	if u.Port() != "" {
+		var err error
+		if port, err = strconv.Atoi(u.Port()); err != nil {
+			return &portNotAllowedError{fmt.Sprintf("URL port %q is invalid", u.Port())}
+		}
+	} else if !ok {
+		return &portNotAllowedError{fmt.Sprintf("URL scheme %q has no default port; name an allowed port explicitly", scheme)}
+	}
+
+	ranges := make([]string, 0, len(c.allowedPorts))
+	for _, r := range c.allowedPorts {
+		if port >= r.low && port <= r.high {
+			return nil
+		}
+		ranges = append(ranges, r.String())
+	}
+	return &portNotAllowedError{fmt.Sprintf("URL port %d is not allowed (allowed: %s)", port, strings.Join(ranges, ", "))}
+}
+
+// checkRedirect is the redirect policy when --allowed-ports is set
+// It keeps net/http's limit of 10 redirects and rejects hops to a port that isn't allowed.
+func (c *HTTPClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return c.checkPort(req.URL)
+}
@@ -0,0 +1,275 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Batch limits
+const (
+	MaxBatchRequests = 50 // Maximum number of sub-requests in one batch
+	batchConcurrency = 10 // Sub-requests executed in parallel
+)
+
+// handleBatchRequest handles /proxy/batch endpoint
+// Sub-requests run concurrently; results are returned together, or streamed as NDJSON
+// in completion order when stream is set.
+func (s *Server) handleBatchRequest(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
+		return
+	}
+
+	var batch BatchRequest
+	if err := json.Unmarshal(body, &batch); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
+		return
+	}
+
+	if len(batch.Requests) == 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing Requests", "Batch must contain at least one request")
+		return
+	}
+	if len(batch.Requests) > MaxBatchRequests {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Batch Too Large",
+			fmt.Sprintf("Batch has %d requests, maximum is %d", len(batch.Requests), MaxBatchRequests))
+		return
+	}
+	if batch.Stream && batch.Multipart {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Conflicting Batch Options",
+			"stream and multipart can't be combined")
+		return
+	}
+
+	s.logger.Printf("Batch request: %d sub-request(s) (stream: %v)", len(batch.Requests), batch.Stream)
+
+	started := time.Now()
+	results := make(chan BatchResult)
+	go s.executeBatch(r, batch.Requests, results)
+
+	if batch.Stream {
+		s.writeBatchStream(w, results)
+		return
+	}
+
+	response := &BatchResponse{
+		Success: true,
+		Results: make([]BatchResult, len(batch.Requests)),
+	}
+	for result := range results {
+		response.Results[result.Index] = result
+	}
+	response.Summary = summarizeBatch(response.Results, time.Since(started))
+
+	if batch.Multipart {
+		s.writeBatchMultipart(w, response.Results)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Printf("Failed to encode batch response: %v", err)
+	}
+}
+
+// summarizeBatch aggregates the results of a batch that took elapsed overall
+func summarizeBatch(results []BatchResult, elapsed time.Duration) *BatchSummary {
+	summary := &BatchSummary{Total: len(results)}
+	var totalTime time.Duration
+	for _, result := range results {
+		if result.Success {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+		metrics := result.metrics
+		if metrics.RequestSize > 0 {
+			summary.RequestBytes += metrics.RequestSize
+		}
+		summary.ResponseBytes += metrics.ResponseSize
+		if !metrics.StartTime.IsZero() {
+			totalTime += metrics.EndTime.Sub(metrics.StartTime)
+		}
+	}
+	summary.TotalTime = formatMilliseconds(totalTime)
+	summary.Duration = formatMilliseconds(elapsed)
+	return summary
+}
+
+// formatMilliseconds formats d like RequestMetrics.FormatDuration
+func formatMilliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.2f ms", float64(d.Nanoseconds())/1000000)
+}
+
+// writeBatchMultipart writes the results as a multipart/mixed body, one part per result in request order
+// A part carries the upstream body as sent, binary included, under its own Content-Type. Results
+// without an inline body (failures and captured bodies) are written as their JSON result instead;
+// X-Slingshot-Success tells the two apart.
+func (s *Server) writeBatchMultipart(w http.ResponseWriter, results []BatchResult) {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+
+	for _, result := range results {
+		if err := writeBatchPart(mw, result); err != nil {
+			s.logger.Printf("Failed to write batch part: %v", err)
+			return
+		}
+	}
+	if err := mw.Close(); err != nil {
+		s.logger.Printf("Failed to write batch part: %v", err)
+	}
+}
+
+// writeBatchPart writes one result as a part of a multipart batch response
+func writeBatchPart(mw *multipart.Writer, result BatchResult) error {
+	response := result.ProxyResponse
+	header := textproto.MIMEHeader{}
+	header.Set("X-Slingshot-Index", strconv.Itoa(result.Index))
+	header.Set("X-Slingshot-Success", strconv.FormatBool(response.Success))
+
+	var body []byte
+	if hasInlineBody(response) {
+		decoded, err := responseBodyBytes(response)
+		if err != nil {
+			return err
+		}
+		body = decoded
+		setBodyPartHeader(header, response)
+	} else {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		body = encoded
+		header.Set("Content-Type", "application/json")
+	}
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(body)
+	return err
+}
+
+// writeBatchStream writes each result as one NDJSON line as soon as it is available
+func (s *Server) writeBatchStream(w http.ResponseWriter, results <-chan BatchResult) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Slingshot-Streaming", "true")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	clientGone := false
+	for result := range results {
+		// Keep draining after a write error so the sub-request goroutines can finish
+		if clientGone {
+			continue
+		}
+		if err := encoder.Encode(result); err != nil {
+			s.logger.Printf("Failed to write batch result: %v", err)
+			clientGone = true
+			continue
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// executeBatch runs the sub-requests with bounded concurrency and sends each result as it completes
+// The results channel is closed once all sub-requests are done.
+func (s *Server) executeBatch(r *http.Request, requests []ProxyRequest, results chan<- BatchResult) {
+	defer close(results)
+
+	semaphore := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(index int, req *ProxyRequest) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results <- BatchResult{Index: index, ProxyResponse: s.executeBatchItem(r, req)}
+		}(i, &requests[i])
+	}
+	wg.Wait()
+}
+
+// executeBatchItem validates and executes a single sub-request of a batch
+func (s *Server) executeBatchItem(r *http.Request, req *ProxyRequest) *ProxyResponse {
+	if reqErr := s.validateProxyRequest(r, req); reqErr != nil {
+		return &ProxyResponse{
+			Success:      false,
+			ErrorType:    reqErr.errType,
+			ErrorTitle:   reqErr.title,
+			ErrorMessage: reqErr.message,
+		}
+	}
+
+	if req.Streaming || req.PassThrough {
+		return &ProxyResponse{
+			Success:      false,
+			ErrorType:    "request_format_error",
+			ErrorTitle:   "Unsupported Batch Option",
+			ErrorMessage: "streaming and passThrough are not supported in batch requests",
+		}
+	}
+
+	if reqErr := s.checkMethodPolicy("/proxy/batch", req.Method); reqErr != nil {
+		return &ProxyResponse{
+			Success:      false,
+			ErrorType:    reqErr.errType,
+			ErrorTitle:   reqErr.title,
+			ErrorMessage: reqErr.message,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	untrack, reqErr := s.trackRequest(req, cancel)
+	if reqErr != nil {
+		return &ProxyResponse{
+			Success:      false,
+			ErrorType:    reqErr.errType,
+			ErrorTitle:   reqErr.title,
+			ErrorMessage: reqErr.message,
+		}
+	}
+	defer untrack()
+
+	s.logger.Printf("Batch %s %s", req.Method, req.URL)
+
+	response, err := s.executeTargets(ctx, req)
+	if err != nil {
+		s.logger.Printf("Batch request failed: %v", err)
+		return &ProxyResponse{
+			Success:      false,
+			ErrorType:    "unknown_error",
+			ErrorTitle:   "Request Failed",
+			ErrorMessage: err.Error(),
+		}
+	}
+
+	return response
+}
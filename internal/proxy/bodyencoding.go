@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Outbound request body encodings (ProxyRequest.RequestEncoding)
+const (
+	RequestEncodingGzip    = "gzip"
+	RequestEncodingDeflate = "deflate" // zlib-wrapped, which is what HTTP's deflate coding means
+)
+
+// isRequestEncoding reports whether encoding is empty or a supported request body encoding
+func isRequestEncoding(encoding string) bool {
+	return encoding == "" || encoding == RequestEncodingGzip || encoding == RequestEncodingDeflate
+}
+
+// newBodyEncoder returns a writer compressing into w with encoding
+func newBodyEncoder(w io.Writer, encoding string) io.WriteCloser {
+	if encoding == RequestEncodingDeflate {
+		return zlib.NewWriter(w)
+	}
+	return gzip.NewWriter(w)
+}
+
+// encodeRequestBody compresses the outbound body as requested by req.RequestEncoding
+// A streamed body is compressed as it is read and then sent chunked. It reports whether
+// the body was encoded; empty bodies are sent as they are, without Content-Encoding.
+func encodeRequestBody(req *ProxyRequest, body string) (string, bool, error) {
+	if req.RequestEncoding == "" {
+		return body, false, nil
+	}
+
+	if req.bodyStream != nil {
+		if req.bodyLength == 0 {
+			return body, false, nil
+		}
+		req.bodyStream = encodeBodyStream(req.bodyStream, req.RequestEncoding)
+		req.bodyLength = -1
+		return body, true, nil
+	}
+
+	if body == "" {
+		return body, false, nil
+	}
+	var buf bytes.Buffer
+	encoder := newBodyEncoder(&buf, req.RequestEncoding)
+	if _, err := io.WriteString(encoder, body); err != nil {
+		return "", false, err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", false, err
+	}
+	return buf.String(), true, nil
+}
+
+// encodeBodyStream returns a reader yielding src compressed with encoding
+// The transport closes the reader when it is done with the body, which also stops the
+// compressing goroutine if the upstream request fails early.
+func encodeBodyStream(src io.Reader, encoding string) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		encoder := newBodyEncoder(pw, encoding)
+		_, err := io.Copy(encoder, src)
+		if closeErr := encoder.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// decompressRequestMiddleware decodes gzip and deflate client request bodies (--decompress-request-bodies)
+// Handlers then see the plain body, and a streamed body is forwarded upstream decompressed.
+// Other encodings are left untouched.
+func (s *Server) decompressRequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var decoded io.ReadCloser
+		var err error
+		switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+		case "gzip", "x-gzip":
+			decoded, err = gzip.NewReader(r.Body)
+		case "deflate":
+			decoded, err = zlib.NewReader(r.Body)
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid Compressed Body",
+				"Failed to decompress request body: "+err.Error())
+			return
+		}
+		defer decoded.Close()
+
+		r.Body = decoded
+		r.ContentLength = -1
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// prepareBodyFile checks a bodyFromPath request and records the file to send as its body
+// Like /file it needs --enable-local-files and a localhost caller. The path must be absolute,
+// or with --file-root it may be relative to that root and must stay inside it.
+func (s *Server) prepareBodyFile(r *http.Request, req *ProxyRequest) *requestError {
+	if !s.enableLocalFiles {
+		return &requestError{http.StatusForbidden, FeatureDisabledError.Type, FeatureDisabledError.Title,
+			"Reading request bodies from local files is disabled. Enable with --enable-local-files flag."}
+	}
+	if !s.isLocalhostRequest(r) {
+		return &requestError{http.StatusForbidden, LocalhostOnlyError.Type, LocalhostOnlyError.Title,
+			"bodyFromPath is only accepted from localhost (127.0.0.1)"}
+	}
+	if req.Body != "" || req.bodyStream != nil {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Body",
+			"bodyFromPath can't be combined with body or a streamed request body"}
+	}
+	if req.Signing != nil || req.CaptureRequestBody {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Streamed Body Not Supported",
+			"signing and captureRequestBody need the whole body and can't be used with bodyFromPath"}
+	}
+
+	path := filepath.Clean(req.BodyFromPath)
+	if s.fileRoot != "" {
+		confined, err := confinePath(s.fileRoot, path)
+		if os.IsNotExist(err) {
+			return &requestError{http.StatusNotFound, FileNotFoundError.Type, FileNotFoundError.Title, fmt.Sprintf("File not found: %s", path)}
+		}
+		if err != nil {
+			return &requestError{http.StatusForbidden, FileAccessError.Type, FileAccessError.Title, err.Error()}
+		}
+		path = confined
+	} else if !filepath.IsAbs(path) {
+		return &requestError{http.StatusBadRequest, FileAccessError.Type, FileAccessError.Title, "Path must be absolute"}
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &requestError{http.StatusNotFound, FileNotFoundError.Type, FileNotFoundError.Title, fmt.Sprintf("File not found: %s", path)}
+		}
+		return &requestError{http.StatusInternalServerError, FileAccessError.Type, FileAccessError.Title, fmt.Sprintf("Cannot access file: %v", err)}
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return &requestError{http.StatusBadRequest, FileAccessError.Type, FileAccessError.Title, "Path is not a regular file"}
+	}
+
+	req.bodyFile = path
+	req.bodyLength = fileInfo.Size()
+	return nil
+}
+
+// openBodyFile opens the bodyFromPath file of req as its streamed body
+// The returned file must be closed by the caller once the request is done; the transport
+// closes it too when it has sent the body, which is harmless.
+func openBodyFile(req *ProxyRequest) (*os.File, error) {
+	file, err := os.Open(req.bodyFile)
+	if err != nil {
+		return nil, err
+	}
+	req.bodyStream = file
+	return file, nil
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Escaping of body_params values (ProxyRequest.BodyParamsEscape)
+const (
+	BodyParamsEscapeJSON = "json" // Escaped for use inside a JSON string: "a\"b" -> a\"b (default)
+	BodyParamsEscapeURL  = "url"  // Query-escaped for form bodies: "a b&c" -> a+b%26c
+	BodyParamsEscapeRaw  = "raw"  // Inserted as they are
+)
+
+// isBodyParamsEscape reports whether escape is empty or a supported body_params escaping
+func isBodyParamsEscape(escape string) bool {
+	return escape == "" || escape == BodyParamsEscapeJSON || escape == BodyParamsEscapeURL || escape == BodyParamsEscapeRaw
+}
+
+// substituteBodyParams replaces each {{name}} placeholder in body with the escaped value of name
+// Like path parameters, substitution is single-pass, so values are never re-scanned, and
+// placeholders without a value in params are left as they are.
+func substituteBodyParams(body string, params map[string]string, escape string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	replacements := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		replacements = append(replacements, "{{"+name+"}}", escapeBodyParam(params[name], escape))
+	}
+
+	return strings.NewReplacer(replacements...).Replace(body)
+}
+
+// escapeBodyParam escapes value as selected by escape
+func escapeBodyParam(value, escape string) string {
+	switch escape {
+	case BodyParamsEscapeRaw:
+		return value
+	case BodyParamsEscapeURL:
+		return url.QueryEscape(value)
+	}
+
+	// Encode as a JSON string without the surrounding quotes; <, > and & stay readable
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	encoded := strings.TrimSuffix(buf.String(), "\n")
+	return encoded[1 : len(encoded)-1]
+}
@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Body rule operations
+const (
+	BodyRuleRedact = "redact" // Replace the field's value with BodyRedactedValue
+	BodyRuleRemove = "remove" // Delete the field
+)
+
+// Body rule directions
+const (
+	BodyRuleRequest  = "request"  // Outbound request bodies
+	BodyRuleResponse = "response" // Upstream response bodies
+	BodyRuleBoth     = "both"     // Request and response bodies
+)
+
+// BodyRedactedValue replaces the value of redacted fields
+const BodyRedactedValue = "[REDACTED]"
+
+// BodyRule redacts or removes a field of JSON request or response bodies
+type BodyRule struct {
+	Op        string   // BodyRuleRedact or BodyRuleRemove
+	Direction string   // BodyRuleRequest, BodyRuleResponse or BodyRuleBoth
+	Path      []string // Field path; "*" matches any object key or array element
+}
+
+// appliesTo reports whether the rule covers bodies travelling in direction
+func (r BodyRule) appliesTo(direction string) bool {
+	return r.Direction == BodyRuleBoth || r.Direction == direction
+}
+
+// loadBodyRulesFile reads a JSON body transformation rules file
+// Format: one rule per line, "<op> <direction> <path>", applied in order; the path is
+// dot-separated and "*" matches every key or array element at that level
+// Example:
+//
+//	redact request password
+//	redact both user.ssn
+//	remove response items.*.internalId
+//	# This is a comment
+func loadBodyRulesFile(filename string) ([]BodyRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []BodyRule
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected \"<op> <direction> <path>\"", i+1)
+		}
+
+		op := strings.ToLower(fields[0])
+		if op != BodyRuleRedact && op != BodyRuleRemove {
+			return nil, fmt.Errorf("line %d: unknown operation %q (expected redact or remove)", i+1, fields[0])
+		}
+
+		direction := strings.ToLower(fields[1])
+		if direction != BodyRuleRequest && direction != BodyRuleResponse && direction != BodyRuleBoth {
+			return nil, fmt.Errorf("line %d: unknown direction %q (expected request, response or both)", i+1, fields[1])
+		}
+
+		path := strings.Split(fields[2], ".")
+		for _, segment := range path {
+			if segment == "" {
+				return nil, fmt.Errorf("line %d: invalid field path %q", i+1, fields[2])
+			}
+		}
+
+		rules = append(rules, BodyRule{Op: op, Direction: direction, Path: path})
+	}
+
+	return rules, nil
+}
+
+// applyBodyRules applies the rules for direction to a JSON body
+// Bodies that aren't a JSON object or array are returned unchanged, as is the body if no
+// rule matched. A rewritten body is re-encoded, so object keys come out sorted.
+func applyBodyRules(rules []BodyRule, direction string, body []byte) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return body, false
+	}
+
+	var applicable []BodyRule
+	for _, rule := range rules {
+		if rule.appliesTo(direction) {
+			applicable = append(applicable, rule)
+		}
+	}
+	if len(applicable) == 0 {
+		return body, false
+	}
+
+	// UseNumber keeps numbers exactly as sent instead of round-tripping them through float64
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return body, false
+	}
+
+	changed := false
+	for _, rule := range applicable {
+		if applyBodyRule(doc, rule.Path, rule.Op) {
+			changed = true
+		}
+	}
+	if !changed {
+		return body, false
+	}
+
+	// Don't escape <, > and & so string values come back as they were sent
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return body, false
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), true
+}
+
+// applyBodyRule applies op to the fields of node at path and reports whether any matched
+func applyBodyRule(node interface{}, path []string, op string) bool {
+	segment := path[0]
+	last := len(path) == 1
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		matched := false
+		for key, child := range value {
+			if segment != "*" && segment != key {
+				continue
+			}
+			if last {
+				if op == BodyRuleRemove {
+					delete(value, key)
+				} else {
+					value[key] = BodyRedactedValue
+				}
+				matched = true
+			} else if applyBodyRule(child, path[1:], op) {
+				matched = true
+			}
+		}
+		return matched
+
+	case []interface{}:
+		// Arrays are addressed by index or "*"; removing an element would shift the others, so it is redacted instead
+		matched := false
+		for index, child := range value {
+			if segment != "*" && segment != strconv.Itoa(index) {
+				continue
+			}
+			if last {
+				value[index] = BodyRedactedValue
+				matched = true
+			} else if applyBodyRule(child, path[1:], op) {
+				matched = true
+			}
+		}
+		return matched
+	}
+
+	return false
+}
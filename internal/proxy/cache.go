@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// responseCacheMaxEntries bounds the number of responses kept by the response cache
+const responseCacheMaxEntries = 1000
+
+// responseCache keeps successful responses to safe requests for a fixed TTL
+// Requests are matched like coalesced requests (see coalesceKey), so only identical
+// requests share a cached response.
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cachedResponse
+}
+
+// cachedResponse is a stored response and when it was stored
+type cachedResponse struct {
+	response ProxyResponse
+	stored   time.Time
+}
+
+// newResponseCache creates an empty cache whose entries expire after ttl
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]*cachedResponse)}
+}
+
+// isCacheable reports whether a response may be stored
+// Only complete 2xx responses qualify, and not if the upstream forbids storing them.
+func isCacheable(response *ProxyResponse) bool {
+	if !response.Success || response.Partial || response.ResponseBodyFile != "" {
+		return false
+	}
+	if response.ResponseStatus < 200 || response.ResponseStatus >= 300 {
+		return false
+	}
+	cacheControl := strings.ToLower(response.ResponseHeaders["cache-control"])
+	return !strings.Contains(cacheControl, "no-store") && !strings.Contains(cacheControl, "private")
+}
+
+// get returns a copy of the unexpired response stored for req, marked as served from cache
+func (c *responseCache) get(req *ProxyRequest, now time.Time) (*ProxyResponse, bool) {
+	key := coalesceKey(req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	age := now.Sub(entry.stored)
+	if age >= c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	response := entry.response
+	response.FromCache = true
+	response.CacheAge = int(age / time.Second)
+	return &response, true
+}
+
+// put stores a copy of response for req, making room by dropping expired and then the oldest entries
+func (c *responseCache) put(req *ProxyRequest, response *ProxyResponse, now time.Time) {
+	key := coalesceKey(req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= responseCacheMaxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = &cachedResponse{response: *response, stored: now}
+}
+
+// evict removes expired entries, or the oldest entry if none have expired; c.mu must be held
+func (c *responseCache) evict(now time.Time) {
+	oldestKey := ""
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if now.Sub(entry.stored) >= c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.stored.Before(oldest) {
+			oldestKey, oldest = key, entry.stored
+		}
+	}
+	if len(c.entries) >= responseCacheMaxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
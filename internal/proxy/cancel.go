@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// maxRequestIDLength bounds the requestId clients may choose
+const maxRequestIDLength = 256
+
+// activeRequests tracks the in-flight requests that set a requestId, so /proxy/cancel can abort them
+type activeRequests struct {
+	mu       sync.Mutex
+	requests map[string]context.CancelFunc
+}
+
+func newActiveRequests() *activeRequests {
+	return &activeRequests{requests: make(map[string]context.CancelFunc)}
+}
+
+// add registers cancel under id, reporting false if another request already uses the id
+func (a *activeRequests) add(id string, cancel context.CancelFunc) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.requests[id]; ok {
+		return false
+	}
+	a.requests[id] = cancel
+	return true
+}
+
+// remove forgets id once its request is done
+func (a *activeRequests) remove(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.requests, id)
+}
+
+// cancel cancels the context of the request with id, reporting whether there was one
+func (a *activeRequests) cancel(id string) bool {
+	a.mu.Lock()
+	cancel, ok := a.requests[id]
+	a.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// trackRequest makes req cancellable by its requestId until the returned function is called
+// Requests without a requestId aren't tracked.
+func (s *Server) trackRequest(req *ProxyRequest, cancel context.CancelFunc) (func(), *requestError) {
+	if req.RequestID == "" {
+		return func() {}, nil
+	}
+	if !s.active.add(req.RequestID, cancel) {
+		return nil, &requestError{http.StatusConflict, "request_format_error", "Duplicate Request ID",
+			fmt.Sprintf("Another active request already uses requestId %q", req.RequestID)}
+	}
+	return func() { s.active.remove(req.RequestID) }, nil
+}
+
+// CancelRequest is the body of /proxy/cancel
+type CancelRequest struct {
+	RequestID string `json:"requestId"`
+}
+
+// CancelResponse reports whether /proxy/cancel found the request
+type CancelResponse struct {
+	RequestID string `json:"requestId"`
+	Found     bool   `json:"found"`
+}
+
+// handleCancelRequest handles /proxy/cancel endpoint
+// It cancels the context of the active request with the given requestId, which aborts its
+// upstream call; the request itself then answers with a request_cancelled error.
+func (s *Server) handleCancelRequest(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
+		return
+	}
+	if req.RequestID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing Request ID", "requestId is required")
+		return
+	}
+
+	found := s.active.cancel(req.RequestID)
+	if found {
+		s.logger.Printf("Cancelled request %s", req.RequestID)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CancelResponse{RequestID: req.RequestID, Found: found})
+}
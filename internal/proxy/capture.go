@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultBodyCaptureTTL is how long captured body files are kept before being removed
+const DefaultBodyCaptureTTL = 10 * time.Minute
+
+// bodyCapture spills request and response bodies to temporary files instead of memory
+type bodyCapture struct {
+	dir    string        // Directory for capture files (empty = os.TempDir())
+	ttl    time.Duration // Files are removed after this duration
+	logger *log.Logger
+}
+
+// newBodyCapture creates a body capture writing to dir with the given TTL
+func newBodyCapture(dir string, ttl time.Duration, logger *log.Logger) *bodyCapture {
+	if ttl <= 0 {
+		ttl = DefaultBodyCaptureTTL
+	}
+	return &bodyCapture{dir: dir, ttl: ttl, logger: logger}
+}
+
+// capture copies source into a new temp file and schedules its removal
+// Returns the file path and number of bytes written
+func (b *bodyCapture) capture(kind string, source io.Reader) (string, int64, error) {
+	file, err := os.CreateTemp(b.dir, "rb-proxy-"+kind+"-*.body")
+	if err != nil {
+		return "", 0, err
+	}
+	path := file.Name()
+
+	written, copyErr := io.Copy(file, source)
+	closeErr := file.Close()
+	if copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		os.Remove(path)
+		return "", written, copyErr
+	}
+
+	b.scheduleCleanup(path)
+	return path, written, nil
+}
+
+// scheduleCleanup removes the file once its TTL has elapsed
+func (b *bodyCapture) scheduleCleanup(path string) {
+	time.AfterFunc(b.ttl, func() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			b.logger.Printf("Failed to remove captured body %s: %v", path, err)
+		}
+	})
+}
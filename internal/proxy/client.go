@@ -5,12 +5,19 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,11 +26,66 @@ type HTTPClient struct {
 	client        *http.Client
 	version       string // Version for User-Agent
 	enableLogging bool   // Enable verbose logging
+	logger        *log.Logger
+
+	debug bool // Log streaming diagnostics as [SSE-DEBUG] lines
+
+	oauth2Sources     []*oauth2TokenSource // Client-credentials token sources keyed by target host
+	binaryOverrides   map[string]bool      // MIME prefix -> true (binary) / false (text), consulted before built-in defaults
+	headerRules       []HeaderRule         // Rewrite rules applied to upstream response headers
+	bodyRules         []BodyRule           // Redaction rules applied to JSON request and response bodies
+	http10            *http10Transport     // Transport for requests that ask for HTTP/1.0
+	bodyCapture       *bodyCapture         // Spills bodies to temp files when enabled (nil = disabled)
+	streamMaxDuration time.Duration        // Absolute cap on how long a stream may run (0 = unlimited)
+	hostLimiter       *hostLimiter         // Caps concurrent requests per upstream host (nil = unlimited)
+	coalescer         *requestCoalescer    // Shares upstream calls between identical in-flight requests (nil = disabled)
+	cache             *responseCache       // Answers repeated safe requests while their response is fresh (nil = disabled)
+	blocked           *blockCounters       // Counts requests rejected by the private network guard (nil = not counted)
+	maxResponseBytes  int64                // Maximum response body size read into memory (0 = unlimited)
+
+	dialer  *net.Dialer   // Dialer of the shared transport (keep-alive period, private network guard)
+	sockets socketOptions // Low-latency options applied to every upstream connection
+
+	responseHeaderTimeout time.Duration // Default wait for response headers of every request (0 = overall timeout only)
+
+	h2c     *http.Transport // Pooled transport for h2c requests, copied from the shared one on first use
+	h2cOnce sync.Once
+
+	defaultHeaders []DefaultHeader // Headers added to every outbound request that doesn't set them
+
+	allowedSchemes []string // Lowercased URL schemes validateURL accepts (nil = DefaultAllowedSchemes)
+
+	allowedPorts []portRange // Upstream ports validateURL and redirects accept (nil = any port)
+}
+
+// Header rule operations
+const (
+	HeaderRuleRemove = "remove"
+	HeaderRuleSet    = "set"
+)
+
+// HeaderRule removes or sets a response header before it is returned to the client
+type HeaderRule struct {
+	Op    string // HeaderRuleRemove or HeaderRuleSet
+	Name  string // Header name (case-insensitive)
+	Value string // Value for HeaderRuleSet
 }
 
 // NewHTTPClient creates a new HTTP client with sensible defaults
-func NewHTTPClient(version string, enableLogging bool) *HTTPClient {
+// Log output goes to logger, or to the standard logger if nil
+func NewHTTPClient(version string, enableLogging bool, logger *log.Logger) *HTTPClient {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	// Shared by all upstream connections of the standard transport; setters adjust it before serving
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: DefaultTCPKeepAlive,
+	}
+
 	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 10,
 		IdleConnTimeout:     30 * time.Second,
@@ -42,11 +104,225 @@ func NewHTTPClient(version string, enableLogging bool) *HTTPClient {
 		},
 		version:       version,
 		enableLogging: enableLogging,
+		logger:        logger,
+		http10:        newHTTP10Transport(),
+		dialer:        dialer,
+	}
+}
+
+// errResponseTooLarge is returned by readResponseBody when the body exceeds maxResponseBytes
+var errResponseTooLarge = errors.New("response body exceeds the maximum size")
+
+// readResponseBody reads a whole response body, failing with errResponseTooLarge once it
+// exceeds maxResponseBytes. On other errors the bytes read so far are returned too.
+func (c *HTTPClient) readResponseBody(body io.Reader) ([]byte, error) {
+	if c.maxResponseBytes <= 0 {
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, c.maxResponseBytes+1))
+	if int64(len(data)) > c.maxResponseBytes {
+		return nil, errResponseTooLarge
+	}
+	return data, err
+}
+
+// responseTooLargeMessage describes the response size limit for ResponseTooLargeError
+func (c *HTTPClient) responseTooLargeMessage() string {
+	return fmt.Sprintf("The response body is larger than the maximum of %s. Use captureResponseBody to save large responses to a file.",
+		FormatFileSize(c.maxResponseBytes))
+}
+
+// blockPrivateNetworks makes all upstream dials refuse loopback, private and link-local addresses
+func (c *HTTPClient) blockPrivateNetworks() {
+	c.dialer.Control = privateNetworkControl
+	c.http10.dialer.Control = privateNetworkControl
+}
+
+// blocksPrivateNetworks reports whether upstream dials refuse private network addresses
+func (c *HTTPClient) blocksPrivateNetworks() bool {
+	return c.dialer.Control != nil
+}
+
+// setTCPKeepAlive sets the TCP keep-alive probe period of upstream connections (0 disables probes)
+// Probes keep long idle streams alive through NATs and firewalls that drop silent connections,
+// independently of anything sent at the HTTP level.
+func (c *HTTPClient) setTCPKeepAlive(period time.Duration) {
+	if period <= 0 {
+		period = -1 // net.Dialer treats a negative period as disabled and zero as its default
+	}
+	c.dialer.KeepAlive = period
+	c.http10.dialer.KeepAlive = period
+}
+
+// setSourceAddress makes upstream connections originate from the local IP address addr (empty = chosen by the OS)
+// The address must be assigned to a local interface. Hostnames are only dialed at their
+// addresses of the same family, so an IPv4 source can't reach IPv6-only upstreams.
+func (c *HTTPClient) setSourceAddress(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return fmt.Errorf("%q is not an IP address", addr)
+	}
+
+	// Binding fails the same way for every request if the address isn't local, so check it once
+	conn, err := net.ListenPacket("udp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return err
+	}
+	conn.Close()
+
+	c.dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	c.http10.dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	return nil
+}
+
+// setResponseHeaderTimeout bounds the wait for response headers of every request (0 = no separate limit)
+// A request's own responseHeaderTimeout replaces it; reading the body stays bounded only by the
+// overall timeout, so slow bodies and long streams aren't affected.
+func (c *HTTPClient) setResponseHeaderTimeout(timeout time.Duration) {
+	c.responseHeaderTimeout = timeout
+	c.http10.responseHeaderTimeout = timeout
+	if transport, ok := c.client.Transport.(*http.Transport); ok {
+		transport.ResponseHeaderTimeout = timeout
+	}
+}
+
+// setSocketOptions applies low-latency socket options to all upstream connections
+func (c *HTTPClient) setSocketOptions(sockets socketOptions) {
+	c.sockets = sockets
+	c.http10.sockets = sockets
+	if transport, ok := c.client.Transport.(*http.Transport); ok {
+		transport.DialContext = sockets.dialContext(c.dialer)
+	}
+}
+
+// setAllowedSchemes replaces the URL schemes accepted for upstream requests (empty = DefaultAllowedSchemes)
+// Schemes are matched case-insensitively; a scheme without a transport still fails at request time.
+func (c *HTTPClient) setAllowedSchemes(schemes []string) error {
+	allowed := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if !validScheme(scheme) {
+			return fmt.Errorf("invalid URL scheme %q", scheme)
+		}
+		allowed = append(allowed, scheme)
+	}
+	if len(allowed) == 0 {
+		allowed = nil
+	}
+	c.allowedSchemes = allowed
+	return nil
+}
+
+// validScheme reports whether scheme has the RFC 3986 syntax: a letter followed by letters, digits, "+", "-" or "."
+func validScheme(scheme string) bool {
+	for i, r := range scheme {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return scheme != ""
+}
+
+// classifyFailure classifies a failed upstream request, counting and logging private network blocks
+// Expired phase timeouts of the request are reported as their own error types.
+func (c *HTTPClient) classifyFailure(err error, req *ProxyRequest) (*ProxyError, string) {
+	if errType, message, ok := phaseTimeoutsFor(req).classify(err); ok {
+		return errType, message
+	}
+	if c.responseHeaderTimeout > 0 && isResponseHeaderTimeout(err) {
+		return ResponseHeaderTimeoutError, fmt.Sprintf("The server did not send response headers within the --response-header-timeout of %s.", c.responseHeaderTimeout)
+	}
+
+	var portErr *portNotAllowedError
+	if errors.As(err, &portErr) {
+		return URLValidationError, fmt.Sprintf("Redirect rejected: %s", portErr.Error())
+	}
+
+	targetURL := req.URL
+	errType, message := classifyConnectionError(err)
+	if errType == SSRFBlockedError {
+		if c.blocked != nil {
+			atomic.AddInt64(&c.blocked.ssrfBlocked, 1)
+		}
+		c.logger.Printf("BLOCKED ssrf_blocked: private network address prevented request to: %s", targetURL)
+	}
+	return errType, message
+}
+
+// debugf logs streaming diagnostics, only when debug logging is enabled
+func (c *HTTPClient) debugf(format string, args ...interface{}) {
+	if c.debug {
+		c.logger.Printf("[SSE-DEBUG] "+format, args...)
 	}
 }
 
 // ExecuteRequest executes an HTTP request with proper timeout and redirect handling
+// The response reports the timeout the request ran under in effective_timeout.
 func (c *HTTPClient) ExecuteRequest(ctx context.Context, req *ProxyRequest) (*ProxyResponse, error) {
+	response, err := c.executeCached(ctx, req)
+	if response != nil {
+		response.EffectiveTimeout = req.Timeout
+	}
+	return response, err
+}
+
+// executeCached executes a request for ExecuteRequest
+// With the response cache enabled, repeated safe requests are answered from it while fresh.
+func (c *HTTPClient) executeCached(ctx context.Context, req *ProxyRequest) (*ProxyResponse, error) {
+	if c.cache == nil || !canCoalesce(req) {
+		return c.executeShared(ctx, req)
+	}
+
+	if response, ok := c.cache.get(req, time.Now()); ok {
+		if req.timings != nil {
+			req.timings.served(servedFromCache)
+		}
+		return response, nil
+	}
+	response, err := c.executeShared(ctx, req)
+	if err == nil && isCacheable(response) {
+		c.cache.put(req, response, time.Now())
+	}
+	return response, err
+}
+
+// executeShared executes a request not answered from the cache
+// With coalescing enabled, identical concurrent safe requests share one upstream call.
+func (c *HTTPClient) executeShared(ctx context.Context, req *ProxyRequest) (*ProxyResponse, error) {
+	if c.coalescer != nil && canCoalesce(req) {
+		metrics := &RequestMetrics{StartTime: time.Now()}
+
+		// The shared call may outlive this caller, so it records its phases on its own timings,
+		// copied back only once the call is done
+		shared := *req
+		if req.timings != nil {
+			shared.timings = &phaseTimings{start: req.timings.start}
+		}
+		response, err := c.coalescer.do(ctx, req, func(ctx context.Context) (*ProxyResponse, error) {
+			return c.executeRequest(ctx, &shared)
+		})
+		if err == nil && req.timings != nil {
+			req.timings.copyPhases(shared.timings)
+		}
+		if err == context.DeadlineExceeded {
+			return c.createErrorResponse(TimeoutError, "The server took too long to respond.", metrics), nil
+		}
+		if err == context.Canceled {
+			return c.createCancelledResponse(metrics), nil
+		}
+		return response, err
+	}
+	return c.executeRequest(ctx, req)
+}
+
+// executeRequest performs the upstream call for ExecuteRequest
+func (c *HTTPClient) executeRequest(ctx context.Context, req *ProxyRequest) (*ProxyResponse, error) {
 	metrics := &RequestMetrics{
 		StartTime: time.Now(),
 	}
@@ -56,11 +332,31 @@ func (c *HTTPClient) ExecuteRequest(ctx context.Context, req *ProxyRequest) (*Pr
 		return c.createErrorResponse(URLValidationError, err.Error(), metrics), nil
 	}
 
-	// Parse headers
+	// Parse headers, then add the configured defaults the caller didn't set
 	headers := c.parseHeaders(req.Headers)
+	c.applyDefaultHeaders(headers)
+
+	// Send a bodyFromPath file as a streamed body
+	if req.bodyFile != "" {
+		file, err := openBodyFile(req)
+		if err != nil {
+			return c.createErrorResponse(FileAccessError, fmt.Sprintf("Failed to open body file: %v", err), metrics), nil
+		}
+		defer file.Close()
+	}
+
+	// Transform the body first so Content-Length and signatures cover what is actually sent
+	requestBody, encoded, err := encodeRequestBody(req, c.outboundBody(req))
+	if err != nil {
+		return c.createErrorResponse(RequestFormatError, fmt.Sprintf("Failed to encode request body: %v", err), metrics), nil
+	}
+	metrics.RequestSize = int64(len(requestBody))
+	if req.bodyStream != nil {
+		metrics.RequestSize = req.bodyLength
+	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, strings.NewReader(req.Body))
+	httpReq, err := newUpstreamRequest(ctx, req, requestBody)
 	if err != nil {
 		return c.createErrorResponse(URLValidationError, fmt.Sprintf("Failed to create request: %v", err), metrics), nil
 	}
@@ -69,15 +365,59 @@ func (c *HTTPClient) ExecuteRequest(ctx context.Context, req *ProxyRequest) (*Pr
 	for key, value := range headers {
 		httpReq.Header.Set(key, value)
 	}
+	if encoded {
+		httpReq.Header.Set("Content-Encoding", req.RequestEncoding)
+	}
 
 	// Set default User-Agent if not provided
-	if httpReq.Header.Get("User-Agent") == "" {
-		httpReq.Header.Set("User-Agent", fmt.Sprintf("rb-slingshot/%s (https://requestbite.com/slingshot)", c.version))
-	}
+	c.setUserAgent(httpReq, req)
 
 	// Set Content-Length for POST/PUT/PATCH requests with body
-	if req.Body != "" && (req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH") {
-		httpReq.Header.Set("Content-Length", fmt.Sprintf("%d", len(req.Body)))
+	if requestBody != "" && (req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH") {
+		httpReq.Header.Set("Content-Length", fmt.Sprintf("%d", len(requestBody)))
+	}
+
+	// Inject OAuth2 Bearer token for configured hosts
+	if err := c.applyOAuth2(ctx, httpReq); err != nil {
+		return c.createErrorResponse(OAuth2TokenError, fmt.Sprintf("Failed to obtain OAuth2 access token: %v", err), metrics), nil
+	}
+
+	// Sign the final request if signing is configured
+	if req.Signing != nil {
+		if err := signRequest(httpReq, requestBody, req.Signing, time.Now()); err != nil {
+			return c.createErrorResponse(RequestFormatError, fmt.Sprintf("Failed to sign request: %v", err), metrics), nil
+		}
+	}
+
+	// Keep a copy of the outbound body on disk if requested
+	recorders := &responseRecorders{}
+	if req.CaptureRequestBody && c.bodyCapture != nil {
+		path, _, err := c.bodyCapture.capture("request", strings.NewReader(requestBody))
+		if err != nil {
+			return c.createErrorResponse(BodyCaptureError, fmt.Sprintf("Failed to capture request body: %v", err), metrics), nil
+		}
+		recorders.requestBodyFile = path
+	}
+
+	// Force the connection to be closed after this request if asked to
+	if req.CloseConnection {
+		httpReq.Close = true
+	}
+
+	// Rename headers last, once nothing needs to look them up by canonical name
+	if req.PreserveHeaderCase {
+		preserveHeaderCase(httpReq.Header, headers)
+	}
+
+	// Record the address each hop connects to; after redirects the last one is the final hop's
+	if req.IncludeResolvedIP {
+		httpReq = httpReq.WithContext(traceResolvedIP(httpReq.Context(), &recorders.resolvedIP))
+	}
+	if req.IncludeInformational {
+		httpReq = httpReq.WithContext(recorders.informational.trace(httpReq.Context()))
+	}
+	if req.timings != nil {
+		httpReq = httpReq.WithContext(req.timings.trace(httpReq.Context()))
 	}
 
 	// Handle redirects based on followRedirects setting
@@ -85,42 +425,184 @@ func (c *HTTPClient) ExecuteRequest(ctx context.Context, req *ProxyRequest) (*Pr
 	if req.FollowRedirects != nil {
 		followRedirects = *req.FollowRedirects
 	}
+	returnLocation := req.RedirectMode == RedirectModeReturnLocation
+	if returnLocation {
+		followRedirects = false
+	}
+
+	// Record the response head off the connection to keep the header order net/http discards
+	transport := c.transportFor(req)
+	if req.OrderedHeaders {
+		transport = c.orderedHeadersTransport(transport, &recorders.headerOrder)
+	}
 
 	// Execute request with potential redirect handling
-	resp, err := c.executeWithRedirects(ctx, httpReq, followRedirects, metrics)
+	resp, err := c.executeWithRedirects(ctx, httpReq, followRedirects, transport, metrics)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return c.createErrorResponse(TimeoutError, "The server took too long to respond.", metrics), nil
 		}
+		if ctx.Err() == context.Canceled {
+			return c.createCancelledResponse(metrics), nil
+		}
 
 		// Check if this is a redirect error when redirects are disabled
 		if strings.Contains(err.Error(), "redirect") && !followRedirects {
 			return c.createErrorResponse(RedirectNotFollowedError, "Server attempted to redirect but followRedirects is disabled.", metrics), nil
 		}
 
-		return c.createErrorResponse(ConnectionError, fmt.Sprintf("Failed to connect to server: %v", err), metrics), nil
+		errType, message := c.classifyFailure(err, req)
+		return c.createErrorResponse(errType, message, metrics), nil
 	}
 
 	defer resp.Body.Close()
 	metrics.EndTime = time.Now()
 
-	// Check for redirects when follow_redirects is false
-	if !followRedirects && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+	// Check for redirects when follow_redirects is false; return-location answers them below instead
+	if !followRedirects && !returnLocation && resp.StatusCode >= 300 && resp.StatusCode < 400 {
 		return c.createErrorResponse(RedirectNotFollowedError,
 			fmt.Sprintf("Server returned %d redirect but following redirects is disabled. Please check your settings.", resp.StatusCode),
 			metrics), nil
 	}
 
+	// Fire-and-forget: closing the body unread frees the connection without downloading the rest
+	if req.DiscardBody {
+		resp.Body.Close()
+
+		response := c.processResponse(resp, nil, metrics, false)
+		response.ResponseSize = ""
+		response.BodyDiscarded = true
+		if returnLocation {
+			response.RedirectLocation = redirectLocation(resp)
+		}
+		c.decorateResponse(response, resp, req, recorders)
+		return response, nil
+	}
+
+	// Spill the response body to a temp file instead of memory if requested
+	if req.CaptureResponseBody && c.bodyCapture != nil {
+		path, written, err := c.bodyCapture.capture("response", resp.Body)
+		if err != nil {
+			return c.createErrorResponse(ConnectionError, fmt.Sprintf("Failed to capture response: %v", err), metrics), nil
+		}
+		metrics.ResponseSize = written
+
+		response := c.processResponse(resp, nil, metrics, false)
+		response.ResponseBodyFile = path
+		c.decorateResponse(response, resp, req, recorders)
+		return response, nil
+	}
+
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.readResponseBody(resp.Body)
+	if err == errResponseTooLarge {
+		return c.createErrorResponse(ResponseTooLargeError, c.responseTooLargeMessage(), metrics), nil
+	}
 	if err != nil {
-		return c.createErrorResponse(ConnectionError, fmt.Sprintf("Failed to read response: %v", err), metrics), nil
+		if ctx.Err() == context.Canceled {
+			return c.createCancelledResponse(metrics), nil
+		}
+
+		// Salvage what arrived before the deadline if the caller asked for it
+		if !req.PartialOnTimeout || ctx.Err() != context.DeadlineExceeded {
+			return c.createErrorResponse(ConnectionError, fmt.Sprintf("Failed to read response: %v", err), metrics), nil
+		}
+		metrics.ResponseSize = int64(len(body))
+
+		response := c.processResponse(resp, body, metrics, false)
+		response.Partial = true
+		response.PartialReason = PartialReasonTimeout
+		c.decorateResponse(response, resp, req, recorders)
+		return response, nil
 	}
 
+	body = c.rewriteResponseBody(resp, body)
+	body = c.normalizeResponseText(resp, body, req.NormalizeText)
 	metrics.ResponseSize = int64(len(body))
 
 	// Process response
-	return c.processResponse(resp, body, metrics, req.PassThrough), nil
+	response := c.processResponse(resp, body, metrics, req.PassThrough)
+	if req.NormalizeLength {
+		normalizeLengthHeaders(response, resp, body)
+	}
+	if returnLocation {
+		response.RedirectLocation = redirectLocation(resp)
+	}
+	c.decorateResponse(response, resp, req, recorders)
+	if req.ParseJSON && !req.PassThrough {
+		embedResponseJSON(response, body)
+	}
+	return response, nil
+}
+
+// responseRecorders collects what is observed about a request while it runs, for decorateResponse
+type responseRecorders struct {
+	requestBodyFile string                // Path of the captured request body (captureRequestBody)
+	resolvedIP      string                // Address of the final hop's connection (includeResolvedIP)
+	informational   informationalRecorder // 1xx responses (includeInformational)
+	headerOrder     headerOrderRecorder   // Response head as received off the connection (orderedHeaders)
+}
+
+// decorateResponse adds the recorded request details the request asked for to a response built from resp
+// Every ProxyResponse an upstream answered goes through here, whatever happened to its body.
+func (c *HTTPClient) decorateResponse(response *ProxyResponse, resp *http.Response, req *ProxyRequest, recorders *responseRecorders) {
+	response.RequestBodyFile = recorders.requestBodyFile
+	response.ResolvedIP = recorders.resolvedIP
+	response.InformationalResponses = recorders.informational.responses()
+	if req.OrderedHeaders {
+		response.ResponseHeaderList = recorders.headerOrder.list(resp.Header)
+	}
+	if req.IncludeHeaderDiff {
+		response.HeaderDiff = diffHeaders(resp, response.ResponseHeaders)
+	}
+}
+
+// decorateStreamingResponse is decorateResponse for the metadata line of a streamed response
+func (c *HTTPClient) decorateStreamingResponse(response *StreamingResponse, resp *http.Response, req *ProxyRequest, recorders *responseRecorders) {
+	response.InformationalResponses = recorders.informational.responses()
+	if req.OrderedHeaders {
+		response.ResponseHeaderList = recorders.headerOrder.list(resp.Header)
+	}
+	if req.IncludeHeaderDiff {
+		response.HeaderDiff = diffHeaders(resp, response.ResponseHeaders)
+	}
+}
+
+// redirectLocation returns the Location of a 3xx response resolved against the request URL
+// A Location that can't be parsed is returned as sent; other responses have none.
+func redirectLocation(resp *http.Response) string {
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return ""
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return resp.Header.Get("Location")
+	}
+	return location.String()
+}
+
+// normalizeLengthHeaders makes the reported headers describe the body as returned: without
+// Transfer-Encoding and with the Content-Length of the body after dechunking, decompression and
+// body rules. HEAD responses and statuses that carry no body keep the upstream's headers.
+func normalizeLengthHeaders(response *ProxyResponse, resp *http.Response, body []byte) {
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return
+	}
+	if resp.StatusCode < 200 || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return
+	}
+	delete(response.ResponseHeaders, "transfer-encoding")
+	response.ResponseHeaders["content-length"] = fmt.Sprintf("%d", len(body))
+}
+
+// embedResponseJSON moves a JSON body from response_data into response_json as a nested value
+// Bodies that are binary or not valid JSON are left in response_data.
+func embedResponseJSON(response *ProxyResponse, body []byte) {
+	if response.IsBinary || !json.Valid(body) {
+		return
+	}
+	response.ResponseJSON = json.RawMessage(body)
+	response.ResponseData = ""
 }
 
 // ExecuteStreamingRequest handles streaming SSE requests
@@ -133,32 +615,84 @@ func (c *HTTPClient) ExecuteStreamingRequest(ctx context.Context, req *ProxyRequ
 	// Validate URL
 	if err := c.validateURL(req.URL); err != nil {
 		errorResp := c.createStreamingErrorResponse(URLValidationError, err.Error(), metrics)
-		return c.writeStreamingErrorResponse(responseWriter, errorResp)
+		return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
 	}
 
-	// Parse headers
+	// Parse headers, then add the configured defaults the caller didn't set
 	headers := c.parseHeaders(req.Headers)
+	c.applyDefaultHeaders(headers)
+
+	// Send a bodyFromPath file as a streamed body
+	if req.bodyFile != "" {
+		file, err := openBodyFile(req)
+		if err != nil {
+			errorResp := c.createStreamingErrorResponse(FileAccessError, fmt.Sprintf("Failed to open body file: %v", err), metrics)
+			return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
+		}
+		defer file.Close()
+	}
+
+	// Transform the body first so Content-Length and signatures cover what is actually sent
+	requestBody, encoded, err := encodeRequestBody(req, c.outboundBody(req))
+	if err != nil {
+		errorResp := c.createStreamingErrorResponse(RequestFormatError, fmt.Sprintf("Failed to encode request body: %v", err), metrics)
+		return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
+	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, strings.NewReader(req.Body))
+	httpReq, err := newUpstreamRequest(ctx, req, requestBody)
 	if err != nil {
 		errorResp := c.createStreamingErrorResponse(URLValidationError, fmt.Sprintf("Failed to create request: %v", err), metrics)
-		return c.writeStreamingErrorResponse(responseWriter, errorResp)
+		return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
 	}
 
 	// Set headers
 	for key, value := range headers {
 		httpReq.Header.Set(key, value)
 	}
+	if encoded {
+		httpReq.Header.Set("Content-Encoding", req.RequestEncoding)
+	}
 
 	// Set default User-Agent if not provided
-	if httpReq.Header.Get("User-Agent") == "" {
-		httpReq.Header.Set("User-Agent", fmt.Sprintf("rb-slingshot/%s (https://requestbite.com/slingshot)", c.version))
-	}
+	c.setUserAgent(httpReq, req)
 
 	// Set Content-Length for POST/PUT/PATCH requests with body
-	if req.Body != "" && (req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH") {
-		httpReq.Header.Set("Content-Length", fmt.Sprintf("%d", len(req.Body)))
+	if requestBody != "" && (req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH") {
+		httpReq.Header.Set("Content-Length", fmt.Sprintf("%d", len(requestBody)))
+	}
+
+	// Inject OAuth2 Bearer token for configured hosts
+	if err := c.applyOAuth2(ctx, httpReq); err != nil {
+		errorResp := c.createStreamingErrorResponse(OAuth2TokenError, fmt.Sprintf("Failed to obtain OAuth2 access token: %v", err), metrics)
+		return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
+	}
+
+	// Sign the final request if signing is configured
+	if req.Signing != nil {
+		if err := signRequest(httpReq, requestBody, req.Signing, time.Now()); err != nil {
+			errorResp := c.createStreamingErrorResponse(RequestFormatError, fmt.Sprintf("Failed to sign request: %v", err), metrics)
+			return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
+		}
+	}
+
+	// Force the connection to be closed after this request if asked to
+	if req.CloseConnection {
+		httpReq.Close = true
+	}
+
+	// Rename headers last, once nothing needs to look them up by canonical name
+	if req.PreserveHeaderCase {
+		preserveHeaderCase(httpReq.Header, headers)
+	}
+
+	// Record the address each hop connects to; after redirects the last one is the final hop's
+	recorders := &responseRecorders{}
+	if req.IncludeResolvedIP {
+		httpReq = httpReq.WithContext(traceResolvedIP(httpReq.Context(), &recorders.resolvedIP))
+	}
+	if req.IncludeInformational {
+		httpReq = httpReq.WithContext(recorders.informational.trace(httpReq.Context()))
 	}
 
 	// Handle redirects based on followRedirects setting
@@ -167,18 +701,28 @@ func (c *HTTPClient) ExecuteStreamingRequest(ctx context.Context, req *ProxyRequ
 		followRedirects = *req.FollowRedirects
 	}
 
+	// Record the response head off the connection to keep the header order net/http discards
+	transport := c.transportFor(req)
+	if req.OrderedHeaders {
+		transport = c.orderedHeadersTransport(transport, &recorders.headerOrder)
+	}
+
 	// Execute request with potential redirect handling
-	resp, err := c.executeWithRedirects(ctx, httpReq, followRedirects, metrics)
+	resp, err := c.executeWithRedirects(ctx, httpReq, followRedirects, transport, metrics)
 	if err != nil {
 		var errorResp *StreamingResponse
 		if ctx.Err() == context.DeadlineExceeded {
 			errorResp = c.createStreamingErrorResponse(TimeoutError, "The server took too long to respond.", metrics)
+		} else if ctx.Err() == context.Canceled {
+			errorResp = c.createStreamingErrorResponse(RequestCancelledError, cancelledMessage, metrics)
+			errorResp.Cancelled = true
 		} else if strings.Contains(err.Error(), "redirect") && !followRedirects {
 			errorResp = c.createStreamingErrorResponse(RedirectNotFollowedError, "Server attempted to redirect but followRedirects is disabled.", metrics)
 		} else {
-			errorResp = c.createStreamingErrorResponse(ConnectionError, fmt.Sprintf("Failed to connect to server: %v", err), metrics)
+			errType, message := c.classifyFailure(err, req)
+			errorResp = c.createStreamingErrorResponse(errType, message, metrics)
 		}
-		return c.writeStreamingErrorResponse(responseWriter, errorResp)
+		return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
 	}
 
 	defer resp.Body.Close()
@@ -188,37 +732,43 @@ func (c *HTTPClient) ExecuteStreamingRequest(ctx context.Context, req *ProxyRequ
 		errorResp := c.createStreamingErrorResponse(RedirectNotFollowedError,
 			fmt.Sprintf("Server returned %d redirect but following redirects is disabled. Please check your settings.", resp.StatusCode),
 			metrics)
-		return c.writeStreamingErrorResponse(responseWriter, errorResp)
+		return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
 	}
 
 	// Check if this is actually an SSE response
 	if !c.isSSEResponse(resp) {
-		if c.enableLogging {
-			log.Printf("Not an SSE response, falling back to standard processing")
+		c.debugf("Not an SSE response, falling back to standard processing")
+		// If it's not SSE, fall back to regular processing (bounded like a standard request)
+		body, err := c.readResponseBody(resp.Body)
+		if err == errResponseTooLarge {
+			errorResp := c.createStreamingErrorResponse(ResponseTooLargeError, c.responseTooLargeMessage(), metrics)
+			return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
 		}
-		// If it's not SSE, fall back to regular processing
-		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			errorResp := c.createStreamingErrorResponse(ConnectionError, fmt.Sprintf("Failed to read response: %v", err), metrics)
-			return c.writeStreamingErrorResponse(responseWriter, errorResp)
+			return c.writeStreamingErrorResponse(responseWriter, req, errorResp)
 		}
 
 		// Complete the metrics timing
 		metrics.EndTime = time.Now()
+		body = c.rewriteResponseBody(resp, body)
 		metrics.ResponseSize = int64(len(body))
 
 		// Write the standard response instead of streaming
 		standardResp := c.processResponse(resp, body, metrics, false)
+		if req.NormalizeLength {
+			normalizeLengthHeaders(standardResp, resp, body)
+		}
+		c.decorateResponse(standardResp, resp, req, recorders)
 		responseWriter.Header().Set("Content-Type", "application/json")
-		return json.NewEncoder(responseWriter).Encode(standardResp)
+		return jsonEncoder(responseWriter).Encode(standardResp)
 	}
 
-	if c.enableLogging {
-		log.Printf("Confirmed SSE response, starting streaming")
-	}
+	c.debugf("Confirmed SSE response, starting streaming")
 
 	// This is an SSE response - prepare for streaming
 	streamingResp := c.createStreamingResponse(resp)
+	c.decorateStreamingResponse(streamingResp, resp, req, recorders)
 
 	// Set response headers for streaming (mixed content: JSON metadata + SSE data)
 	responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -233,9 +783,7 @@ func (c *HTTPClient) ExecuteStreamingRequest(ctx context.Context, req *ProxyRequ
 		return fmt.Errorf("failed to serialize streaming metadata: %v", err)
 	}
 
-	if c.enableLogging {
-		log.Printf("Writing metadata: %s", string(metadataBytes))
-	}
+	c.debugf("Writing metadata: %s", string(metadataBytes))
 
 	// Write metadata as first line
 	if _, err := responseWriter.Write(metadataBytes); err != nil {
@@ -250,20 +798,48 @@ func (c *HTTPClient) ExecuteStreamingRequest(ctx context.Context, req *ProxyRequ
 	// Flush the metadata + separator immediately
 	if flusher, ok := responseWriter.(http.Flusher); ok {
 		flusher.Flush()
-		if c.enableLogging {
-			log.Printf("Flushed metadata to client")
-		}
+		c.debugf("Flushed metadata to client")
 	}
 
-	if c.enableLogging {
-		log.Printf("Starting SSE data stream")
+	c.debugf("Starting SSE data stream")
+
+	// Cut the stream off once the maximum streaming duration is reached, however active it is
+	var maxDurationReached atomic.Bool
+	if c.streamMaxDuration > 0 {
+		timer := time.AfterFunc(c.streamMaxDuration, func() {
+			maxDurationReached.Store(true)
+			resp.Body.Close()
+		})
+		defer timer.Stop()
 	}
 
-	// Stream the SSE data with immediate flushing (no buffering)
-	if err := c.streamResponseWithFlush(responseWriter, resp.Body); err != nil {
-		if c.enableLogging {
-			log.Printf("Error during SSE streaming: %v", err)
-		}
+	// Stop reading at the request's byte ceiling; unlike maxResponseBytes nothing is buffered,
+	// so the stream simply ends there (possibly mid-event) with a completion record saying so
+	var source io.Reader = resp.Body
+	if req.MaxStreamBytes > 0 {
+		source = io.LimitReader(resp.Body, req.MaxStreamBytes)
+	}
+
+	// Stream the SSE data with immediate flushing (no buffering), tracking event ids for resumption
+	eventIDs := &sseEventIDTracker{}
+	streamed, err := c.streamResponseWithFlush(responseWriter, io.TeeReader(source, eventIDs))
+	if maxDurationReached.Load() {
+		// Closing the body makes the read fail, but this is a deliberate, clean end
+		c.debugf("Maximum streaming duration of %s reached, closing stream", c.streamMaxDuration)
+		err = nil
+	}
+	if err != nil && ctx.Err() == context.Canceled && req.RequestID != "" {
+		// Cancelled through /proxy/cancel: the client is still there to be told
+		metrics.EndTime = time.Now()
+		return c.writeStreamCompletion(responseWriter, &StreamCompletion{
+			BytesStreamed: streamed,
+			ResponseTime:  metrics.FormatDuration(),
+			LastEventID:   eventIDs.LastEventID(),
+			Cancelled:     true,
+		})
+	}
+	if err != nil {
+		c.debugf("Error during SSE streaming: %v", err)
 		// Check if this is a timeout error and provide specific error message
 		if strings.Contains(err.Error(), "context deadline exceeded") || strings.Contains(err.Error(), "context canceled") {
 			return fmt.Errorf("streaming timeout: %v", err)
@@ -271,25 +847,139 @@ func (c *HTTPClient) ExecuteStreamingRequest(ctx context.Context, req *ProxyRequ
 		return fmt.Errorf("failed to stream response: %v", err)
 	}
 
-	if c.enableLogging {
-		log.Printf("SSE streaming completed")
+	c.debugf("SSE streaming completed")
+
+	// The ceiling only cut the stream short if the upstream had more to send; a body of
+	// exactly maxStreamBytes ended on its own
+	maxBytesReached := false
+	if req.MaxStreamBytes > 0 && streamed >= req.MaxStreamBytes && !maxDurationReached.Load() {
+		var probe [1]byte
+		n, _ := io.ReadFull(resp.Body, probe[:])
+		maxBytesReached = n > 0
 	}
+
+	// Only a clean end of stream gets a completion record, so clients can tell it
+	// apart from a dropped connection
+	metrics.EndTime = time.Now()
+	metrics.ResponseSize = streamed
+	return c.writeStreamCompletion(responseWriter, &StreamCompletion{
+		StreamComplete:     true,
+		BytesStreamed:      streamed,
+		ResponseTime:       metrics.FormatDuration(),
+		LastEventID:        eventIDs.LastEventID(),
+		MaxDurationReached: maxDurationReached.Load(),
+		MaxBytesReached:    maxBytesReached,
+	})
+}
+
+// writeStreamCompletion appends the completion record after the streamed data
+// The record is written on its own line and prefixed with StreamRecordSeparator,
+// which cannot appear in SSE text, so it can't be confused with upstream data.
+func (c *HTTPClient) writeStreamCompletion(w http.ResponseWriter, completion *StreamCompletion) error {
+	completionBytes, err := json.Marshal(completion)
+	if err != nil {
+		return fmt.Errorf("failed to serialize stream completion: %v", err)
+	}
+
+	record := make([]byte, 0, len(completionBytes)+3)
+	record = append(record, '\n', StreamRecordSeparator)
+	record = append(record, completionBytes...)
+	record = append(record, '\n')
+	if _, err := w.Write(record); err != nil {
+		return fmt.Errorf("failed to write stream completion: %v", err)
+	}
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	c.debugf("Wrote completion record: %s", string(completionBytes))
 	return nil
 }
 
 // executeWithRedirects handles the request execution with manual redirect control
-func (c *HTTPClient) executeWithRedirects(ctx context.Context, req *http.Request, followRedirects bool, metrics *RequestMetrics) (*http.Response, error) {
+// A nil transport uses the client's shared transport
+func (c *HTTPClient) executeWithRedirects(ctx context.Context, req *http.Request, followRedirects bool, transport http.RoundTripper, metrics *RequestMetrics) (*http.Response, error) {
+	// Work on a copy so per-request settings never leak into concurrent requests
+	client := *c.client
+	if transport != nil {
+		client.Transport = transport
+	}
+	// HTTP/1.0 requests are sent as they are, without transparent gzip
+	if _, ok := client.Transport.(*http10Transport); !ok {
+		client.Transport = &wireSizeTransport{base: client.Transport, metrics: metrics}
+	}
+	if c.hostLimiter != nil {
+		client.Transport = &hostLimitedTransport{base: client.Transport, limiter: c.hostLimiter}
+	}
 	if followRedirects {
-		// Temporarily enable automatic redirects
-		c.client.CheckRedirect = nil
-		defer func() {
-			c.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			}
-		}()
+		// Enable automatic redirects for this request, to allowed ports only
+		client.CheckRedirect = nil
+		if c.allowedPorts != nil {
+			client.CheckRedirect = c.checkRedirect
+		}
+	}
+
+	return client.Do(req)
+}
+
+// transportFor returns a dedicated transport if the request needs one, or nil for the shared transport
+// Requests with phase timeouts or a certificate pin get a single-use transport carrying them;
+// h2c requests without phase timeouts share a pooled h2c transport.
+func (c *HTTPClient) transportFor(req *ProxyRequest) http.RoundTripper {
+	timeouts := phaseTimeoutsFor(req)
+	if req.HTTP10 {
+		transport := c.http10
+		if timeouts.isSet() {
+			transport = timeouts.http10Transport(transport)
+		}
+		if req.certPin != nil {
+			transport = pinnedHTTP10Transport(transport, req.certPin)
+		}
+		return transport
+	}
+
+	base, ok := c.client.Transport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+	if req.H2C && !timeouts.isSet() {
+		c.h2cOnce.Do(func() { c.h2c = h2cTransport(base) })
+		return c.h2c
+	}
+	if !timeouts.isSet() && req.certPin == nil {
+		return nil
+	}
+	transport := base
+	if timeouts.isSet() {
+		transport = timeouts.transport(base, c.dialer, c.sockets)
+	}
+	if req.H2C {
+		transport = h2cTransport(transport)
 	}
+	if req.certPin != nil {
+		transport = pinnedTransport(transport, req.certPin)
+	}
+	return transport
+}
 
-	return c.client.Do(req)
+// orderedHeadersTransport returns transport (nil = the shared one) as a copy whose responses headerOrder records
+// The shared transport is copied single-use, since its pooled connections aren't recorded.
+func (c *HTTPClient) orderedHeadersTransport(transport http.RoundTripper, headerOrder *headerOrderRecorder) http.RoundTripper {
+	switch t := transport.(type) {
+	case nil:
+		if base, ok := c.client.Transport.(*http.Transport); ok {
+			single := base.Clone()
+			single.DisableKeepAlives = true
+			return headerOrder.transport(single)
+		}
+	case *http.Transport:
+		return headerOrder.transport(t)
+	case *http10Transport:
+		copied := *t
+		copied.wrapConn = headerOrder.wrap
+		return &copied
+	}
+	return transport
 }
 
 // validateURL validates the URL format and scheme
@@ -307,11 +997,20 @@ func (c *HTTPClient) validateURL(urlStr string) error {
 		return fmt.Errorf("Invalid URL format")
 	}
 
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return fmt.Errorf("Only HTTP and HTTPS schemes are supported")
+	allowed := c.allowedSchemes
+	if allowed == nil {
+		allowed = DefaultAllowedSchemes
+	}
+	scheme := strings.ToLower(parsedURL.Scheme)
+	for _, s := range allowed {
+		if s == scheme {
+			return c.checkPort(parsedURL)
+		}
 	}
 
-	return nil
+	sorted := append([]string(nil), allowed...)
+	sort.Strings(sorted)
+	return fmt.Errorf("URL scheme %q is not allowed (allowed: %s)", scheme, strings.Join(sorted, ", "))
 }
 
 // parseHeaders converts header array to map
@@ -333,8 +1032,160 @@ func (c *HTTPClient) parseHeaders(headerArray []string) map[string]string {
 	return headers
 }
 
+// headerCaseExempt are headers net/http writes itself by canonical name; renaming them
+// would send them twice
+var headerCaseExempt = map[string]bool{
+	"Host":              true,
+	"User-Agent":        true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Trailer":           true,
+	"Connection":        true,
+}
+
+// preserveHeaderCase moves headers back under the exact names the caller gave
+// http.Header canonicalizes names ("api-KEY" becomes "Api-Key"), but HTTP/1.x writes map keys
+// verbatim, so a value stored under its original spelling is sent as-is. HTTP/2 always
+// lowercases header names, so this has no effect there.
+func preserveHeaderCase(header http.Header, headers map[string]string) {
+	for name := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if name == canonical || headerCaseExempt[canonical] {
+			continue
+		}
+		if values, ok := header[canonical]; ok {
+			delete(header, canonical)
+			header[name] = values
+		}
+	}
+}
+
+// newUpstreamRequest creates the outbound request carrying requestBody, or the client's streamed body
+// A streamed body of unknown length is sent with chunked encoding instead of a Content-Length.
+func newUpstreamRequest(ctx context.Context, req *ProxyRequest, requestBody string) (*http.Request, error) {
+	if req.bodyStream == nil {
+		return http.NewRequestWithContext(ctx, req.Method, req.URL, strings.NewReader(requestBody))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, req.bodyStream)
+	if err != nil {
+		return nil, err
+	}
+	if req.bodyLength == 0 {
+		httpReq.Body = http.NoBody
+	}
+	httpReq.ContentLength = req.bodyLength
+	return httpReq, nil
+}
+
+// setUserAgent applies the default User-Agent unless the request sets its own or asked for none
+// An empty value makes net/http (and http10Transport) leave the header out entirely; note that
+// without the rb-slingshot User-Agent, a downstream proxy can't detect a loop back to itself.
+func (c *HTTPClient) setUserAgent(httpReq *http.Request, req *ProxyRequest) {
+	if req.OmitUserAgent {
+		httpReq.Header.Set("User-Agent", "")
+		return
+	}
+	if httpReq.Header.Get("User-Agent") == "" {
+		httpReq.Header.Set("User-Agent", fmt.Sprintf("rb-slingshot/%s (https://requestbite.com/slingshot)", c.version))
+	}
+}
+
+// traceResolvedIP returns a context that stores the IP address of each connection the request gets in ip
+// Hops of a redirect chain run one after another, so the last write is the final hop's.
+func traceResolvedIP(ctx context.Context, ip *string) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				*ip = host
+			}
+		},
+	})
+}
+
+// informationalRecorder collects the 1xx responses of a request
+// The transport reports them from its own goroutine, hence the lock.
+type informationalRecorder struct {
+	mu        sync.Mutex
+	collected []InformationalResponse
+}
+
+// trace returns a context that records each 1xx response of the request
+func (r *informationalRecorder) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			headers := make(map[string]string, len(header))
+			for key, values := range header {
+				headers[strings.ToLower(key)] = strings.Join(values, ", ")
+			}
+			r.mu.Lock()
+			r.collected = append(r.collected, InformationalResponse{Status: code, Headers: headers})
+			r.mu.Unlock()
+			return nil
+		},
+	})
+}
+
+// responses returns the 1xx responses recorded so far
+func (r *informationalRecorder) responses() []InformationalResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.collected
+}
+
+// outboundBody returns the request body with the configured request body rules applied
+func (c *HTTPClient) outboundBody(req *ProxyRequest) string {
+	if len(c.bodyRules) == 0 {
+		return req.Body
+	}
+	if rewritten, ok := applyBodyRules(c.bodyRules, BodyRuleRequest, []byte(req.Body)); ok {
+		return string(rewritten)
+	}
+	return req.Body
+}
+
+// rewriteResponseBody applies the configured response body rules, keeping Content-Length in step
+func (c *HTTPClient) rewriteResponseBody(resp *http.Response, body []byte) []byte {
+	if len(c.bodyRules) == 0 {
+		return body
+	}
+	rewritten, ok := applyBodyRules(c.bodyRules, BodyRuleResponse, body)
+	if !ok {
+		return body
+	}
+	if resp.Header.Get("Content-Length") != "" {
+		resp.Header.Set("Content-Length", fmt.Sprintf("%d", len(rewritten)))
+	}
+	return rewritten
+}
+
+// applyHeaderRules applies the configured rewrite rules to the upstream response headers in order
+func (c *HTTPClient) applyHeaderRules(header http.Header) {
+	for _, rule := range c.headerRules {
+		switch rule.Op {
+		case HeaderRuleRemove:
+			header.Del(rule.Name)
+		case HeaderRuleSet:
+			header.Set(rule.Name, rule.Value)
+		}
+	}
+}
+
+// responseContentType returns the upstream Content-Type, or the type sniffed from body if there is none
+// Sniffing (http.DetectContentType) only looks at the first 512 bytes and falls back to
+// application/octet-stream, so an untyped body is never guessed to be text without evidence.
+func responseContentType(header http.Header, body []byte) string {
+	if contentType := header.Get("Content-Type"); contentType != "" || len(body) == 0 {
+		return contentType
+	}
+	return http.DetectContentType(body)
+}
+
 // processResponse converts HTTP response to ProxyResponse format
 func (c *HTTPClient) processResponse(resp *http.Response, body []byte, metrics *RequestMetrics, passThrough bool) *ProxyResponse {
+	// Apply response header rewrite rules before exposing headers
+	c.applyHeaderRules(resp.Header)
+
 	// Convert headers to map
 	responseHeaders := make(map[string]string)
 	for key, values := range resp.Header {
@@ -343,7 +1194,7 @@ func (c *HTTPClient) processResponse(resp *http.Response, body []byte, metrics *
 		}
 	}
 
-	contentType := resp.Header.Get("Content-Type")
+	contentType := responseContentType(resp.Header, body)
 	isBinary := c.isBinaryContent(contentType)
 
 	responseData := string(body)
@@ -362,6 +1213,9 @@ func (c *HTTPClient) processResponse(resp *http.Response, body []byte, metrics *
 		IsBinary:        isBinary,
 		Cancelled:       false,
 		PassThrough:     passThrough,
+		metrics:         *metrics,
+
+		CompressedBytes: metrics.CompressedBytes,
 	}
 
 	// Store raw body for pass-through mode
@@ -373,11 +1227,25 @@ func (c *HTTPClient) processResponse(resp *http.Response, body []byte, metrics *
 }
 
 // isBinaryContent determines if content is binary based on Content-Type
+// Configured overrides win over the built-in list; the longest matching prefix is used
 func (c *HTTPClient) isBinaryContent(contentType string) bool {
 	if contentType == "" {
 		return false
 	}
 
+	if len(c.binaryOverrides) > 0 {
+		contentTypeLower := strings.ToLower(strings.TrimSpace(contentType))
+		matched := ""
+		for prefix := range c.binaryOverrides {
+			if strings.HasPrefix(contentTypeLower, prefix) && len(prefix) > len(matched) {
+				matched = prefix
+			}
+		}
+		if matched != "" {
+			return c.binaryOverrides[matched]
+		}
+	}
+
 	binaryTypes := []string{
 		"image/",
 		"video/",
@@ -404,12 +1272,12 @@ func (c *HTTPClient) isBinaryContent(contentType string) bool {
 // isSSEResponse determines if the response is a Server-Sent Events stream
 // SSE streams should have Content-Type: text/event-stream and typically Transfer-Encoding: chunked
 func (c *HTTPClient) isSSEResponse(resp *http.Response) bool {
-	if c.enableLogging {
+	if c.debug {
 		// Debug: Log all response headers
-		log.Printf("Response status: %d", resp.StatusCode)
-		log.Printf("Response headers:")
+		c.debugf("Response status: %d", resp.StatusCode)
+		c.debugf("Response headers:")
 		for key, values := range resp.Header {
-			log.Printf("  %s: %v", key, values)
+			c.debugf("  %s: %v", key, values)
 		}
 	}
 
@@ -417,14 +1285,10 @@ func (c *HTTPClient) isSSEResponse(resp *http.Response) bool {
 	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
 	hasEventStream := strings.Contains(contentType, "text/event-stream")
 
-	if c.enableLogging {
-		log.Printf("Content-Type: %s, hasEventStream: %v", contentType, hasEventStream)
-	}
+	c.debugf("Content-Type: %s, hasEventStream: %v", contentType, hasEventStream)
 
 	if !hasEventStream {
-		if c.enableLogging {
-			log.Printf("Not SSE - no text/event-stream content type")
-		}
+		c.debugf("Not SSE - no text/event-stream content type")
 		return false
 	}
 
@@ -432,23 +1296,17 @@ func (c *HTTPClient) isSSEResponse(resp *http.Response) bool {
 	transferEncoding := strings.ToLower(resp.Header.Get("Transfer-Encoding"))
 	hasChunked := strings.Contains(transferEncoding, "chunked")
 
-	if c.enableLogging {
-		log.Printf("Transfer-Encoding: %s, hasChunked: %v", transferEncoding, hasChunked)
-	}
+	c.debugf("Transfer-Encoding: %s, hasChunked: %v", transferEncoding, hasChunked)
 
 	contentLength := resp.Header.Get("Content-Length")
 	noContentLength := contentLength == ""
 
-	if c.enableLogging {
-		log.Printf("Content-Length: %s, noContentLength: %v", contentLength, noContentLength)
-	}
+	c.debugf("Content-Length: %s, noContentLength: %v", contentLength, noContentLength)
 
 	// For SSE, we expect either chunked encoding OR no content-length (indicating streaming)
 	isSSE := hasChunked || noContentLength
 
-	if c.enableLogging {
-		log.Printf("Final SSE determination: %v (hasChunked: %v OR noContentLength: %v)", isSSE, hasChunked, noContentLength)
-	}
+	c.debugf("Final SSE determination: %v (hasChunked: %v OR noContentLength: %v)", isSSE, hasChunked, noContentLength)
 
 	return isSSE
 }
@@ -464,29 +1322,104 @@ func (c *HTTPClient) createErrorResponse(errType *ProxyError, message string, me
 		ErrorMessage: message,
 		ResponseTime: metrics.FormatDuration(),
 		Cancelled:    false,
+		metrics:      *metrics,
 	}
 }
 
+// cancelledMessage is the error_message of requests whose context was cancelled
+const cancelledMessage = "The request was cancelled before it completed."
+
+// createCancelledResponse creates the error response of a request whose context was cancelled
+func (c *HTTPClient) createCancelledResponse(metrics *RequestMetrics) *ProxyResponse {
+	response := c.createErrorResponse(RequestCancelledError, cancelledMessage, metrics)
+	response.Cancelled = true
+	return response
+}
+
 // SubstitutePathParams replaces :param patterns in URL with actual values
-func (c *HTTPClient) SubstitutePathParams(targetURL string, pathParams map[string]string) string {
+// Substitution is single-pass: the URL is scanned once and substituted values are
+// never re-scanned, so a value containing ":other" is not itself substituted.
+// When parameter names overlap (e.g. :id and :idx) the longest name wins.
+// List values are URL encoded element by element and joined according to joinStyle:
+// PathParamJoinComma (default) yields "a,b", PathParamJoinSegment yields "a/b".
+func (c *HTTPClient) SubstitutePathParams(targetURL string, pathParams map[string]PathParamValue, joinStyle string) string {
 	if pathParams == nil {
 		return targetURL
 	}
 
-	resultURL := targetURL
-	for paramName, paramValue := range pathParams {
+	separator := ","
+	if joinStyle == PathParamJoinSegment {
+		separator = "/"
+	}
+
+	// Collect patterns, longest first so overlapping names match the longest candidate
+	names := make([]string, 0, len(pathParams))
+	for paramName := range pathParams {
+		names = append(names, paramName)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := strings.TrimPrefix(names[i], ":"), strings.TrimPrefix(names[j], ":")
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+
+	replacements := make([]string, 0, len(names)*2)
+	for _, paramName := range names {
 		// Remove leading colon from param name if present, then add it back
 		cleanParamName := strings.TrimPrefix(paramName, ":")
+		if cleanParamName == "" {
+			continue
+		}
 		pattern := ":" + cleanParamName
 
-		// URL encode the parameter value
-		encodedValue := url.QueryEscape(paramValue)
+		// URL encode each value, then join list values
+		values := pathParams[paramName]
+		encodedValues := make([]string, len(values))
+		for i, value := range values {
+			encodedValues[i] = url.QueryEscape(value)
+		}
+
+		replacements = append(replacements, pattern, strings.Join(encodedValues, separator))
+	}
+
+	// strings.Replacer performs all replacements in a single pass over the URL
+	return strings.NewReplacer(replacements...).Replace(targetURL)
+}
+
+// unresolvedPathParams returns the :placeholders left in the path of targetURL, in order of appearance
+// Only path segments starting with ":" followed by a name count, so ports, colons inside
+// segments (e.g. "/v1/items:batchGet") and the query string are never flagged. Substituted
+// values are URL encoded, so a value containing ":name" can't be mistaken for a placeholder.
+func unresolvedPathParams(targetURL string) []string {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil // Invalid URL, let validation handle it
+	}
 
-		// Replace all occurrences
-		resultURL = strings.ReplaceAll(resultURL, pattern, encodedValue)
+	var missing []string
+	seen := make(map[string]bool)
+	for _, segment := range strings.Split(parsedURL.EscapedPath(), "/") {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		name := segment[1:]
+		if end := strings.IndexFunc(name, func(r rune) bool { return !isPathParamNameRune(r) }); end != -1 {
+			name = name[:end]
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		missing = append(missing, ":"+name)
 	}
+	return missing
+}
 
-	return resultURL
+// isPathParamNameRune reports whether r may appear in a path parameter name
+func isPathParamNameRune(r rune) bool {
+	return r == '_' || r == '-' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
 }
 
 // ExecuteFormRequest executes a form-based request
@@ -511,6 +1444,7 @@ func (c *HTTPClient) ExecuteFormRequest(ctx context.Context, queryParams *FormPr
 			}
 		}
 	}
+	req.Headers = append(req.Headers, queryParams.ForwardedHeaders...)
 
 	// Handle path parameters
 	if queryParams.PathParams != "" {
@@ -538,6 +1472,9 @@ func (c *HTTPClient) ExecuteFormRequest(ctx context.Context, queryParams *FormPr
 
 // createStreamingResponse creates a StreamingResponse from HTTP response
 func (c *HTTPClient) createStreamingResponse(resp *http.Response) *StreamingResponse {
+	// Apply response header rewrite rules before exposing headers
+	c.applyHeaderRules(resp.Header)
+
 	// Convert headers to map
 	responseHeaders := make(map[string]string)
 	for key, values := range resp.Header {
@@ -573,26 +1510,28 @@ func (c *HTTPClient) createStreamingErrorResponse(errType *ProxyError, message s
 }
 
 // writeStreamingErrorResponse writes a streaming error response
-func (c *HTTPClient) writeStreamingErrorResponse(w http.ResponseWriter, resp *StreamingResponse) error {
+func (c *HTTPClient) writeStreamingErrorResponse(w http.ResponseWriter, req *ProxyRequest, resp *StreamingResponse) error {
 	w.Header().Set("Content-Type", "application/json")
+	if req.errorStatus {
+		w.WriteHeader(upstreamErrorStatus(resp.ErrorType))
+	}
 	return json.NewEncoder(w).Encode(resp)
 }
 
 // streamResponseWithFlush streams data from source to destination with immediate flushing
 // This ensures SSE events are sent to the client as soon as they arrive from the source
-func (c *HTTPClient) streamResponseWithFlush(w http.ResponseWriter, source io.Reader) error {
+// Returns the number of bytes written to the client
+func (c *HTTPClient) streamResponseWithFlush(w http.ResponseWriter, source io.Reader) (int64, error) {
 	flusher, canFlush := w.(http.Flusher)
 	if !canFlush {
-		if c.enableLogging {
-			log.Printf("Warning: ResponseWriter doesn't support flushing")
-		}
+		c.debugf("Warning: ResponseWriter doesn't support flushing")
 		// Fallback to regular copy if flushing not supported
-		_, err := io.Copy(w, source)
-		return err
+		return io.Copy(w, source)
 	}
 
 	// Buffer for reading data in small chunks
 	buffer := make([]byte, 1024)
+	var total int64
 
 	for {
 		// Read a chunk of data
@@ -600,31 +1539,24 @@ func (c *HTTPClient) streamResponseWithFlush(w http.ResponseWriter, source io.Re
 		if n > 0 {
 			// Write the chunk immediately
 			if _, writeErr := w.Write(buffer[:n]); writeErr != nil {
-				if c.enableLogging {
-					log.Printf("Write error: %v", writeErr)
-				}
-				return writeErr
+				c.debugf("Write error: %v", writeErr)
+				return total, writeErr
 			}
+			total += int64(n)
 
 			// Flush immediately to ensure data reaches client
 			flusher.Flush()
-			if c.enableLogging {
-				log.Printf("Flushed %d bytes to client", n)
-			}
+			c.debugf("Flushed %d bytes to client", n)
 		}
 
 		// Handle read errors
 		if err != nil {
 			if err == io.EOF {
-				if c.enableLogging {
-					log.Printf("Reached end of stream")
-				}
-				return nil // Normal end of stream
-			}
-			if c.enableLogging {
-				log.Printf("Read error: %v", err)
+				c.debugf("Reached end of stream")
+				return total, nil // Normal end of stream
 			}
-			return err
+			c.debugf("Read error: %v", err)
+			return total, err
 		}
 	}
 }
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// requestCoalescer shares one upstream call between identical concurrent requests
+// The shared call runs under its own timeout rather than the first caller's context, so
+// one client disconnecting doesn't fail the others waiting on the same response.
+type requestCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+
+	coalesced int64 // Requests answered by another request's upstream call (atomic)
+}
+
+// coalescedCall is an upstream call in progress; done is closed once response/err are set
+type coalescedCall struct {
+	done     chan struct{}
+	response *ProxyResponse
+	err      error
+}
+
+// newRequestCoalescer creates an empty coalescer
+func newRequestCoalescer() *requestCoalescer {
+	return &requestCoalescer{calls: make(map[string]*coalescedCall)}
+}
+
+// canCoalesce reports whether a request is safe to share with identical concurrent requests
+// Only safe methods qualify, and only without per-request side effects (signing nonces, capture
+// files) or a streamed or local file body, which only one upstream call could consume. Requests
+// with a requestId don't either: /proxy/cancel must abort their own upstream call, which a shared
+// call outlives.
+func canCoalesce(req *ProxyRequest) bool {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	return req.Timeout > 0 && req.RequestID == "" && req.Signing == nil && !req.CaptureRequestBody && !req.CaptureResponseBody && req.bodyStream == nil && req.bodyFile == ""
+}
+
+// coalesceKey identifies requests that would produce the same upstream call and response
+func coalesceKey(req *ProxyRequest) string {
+	headers := make([]string, 0, len(req.Headers))
+	for _, header := range req.Headers {
+		if parts := strings.SplitN(header, ":", 2); len(parts) == 2 {
+			headers = append(headers, strings.ToLower(strings.TrimSpace(parts[0]))+":"+strings.TrimSpace(parts[1]))
+		}
+	}
+	sort.Strings(headers)
+
+	key, _ := json.Marshal([]interface{}{
+		req.Method, req.URL, headers, req.Body, req.Timeout, req.FollowRedirects, req.RedirectMode,
+		req.PassThrough, req.HTTP10, req.H2C, req.CloseConnection, req.PartialOnTimeout, req.ParseJSON, req.PreserveHeaderCase, req.IncludeResolvedIP, req.OmitUserAgent, req.IncludeInformational,
+		req.OrderedHeaders, req.PinnedCertSHA256, req.MultipartResponse, req.IncludeHeaderDiff, req.DiscardBody, req.NormalizeLength, req.NormalizeText,
+		req.ConnectTimeout, req.TLSTimeout, req.ResponseHeaderTimeout, req.RequestEncoding,
+	})
+	return string(key)
+}
+
+// do returns the response of the in-flight call for req, starting one with execute if there is none
+// Each caller stops waiting when its own context ends; the call itself is bounded by req.Timeout.
+func (c *requestCoalescer) do(ctx context.Context, req *ProxyRequest, execute func(context.Context) (*ProxyResponse, error)) (*ProxyResponse, error) {
+	key := coalesceKey(req)
+
+	c.mu.Lock()
+	call, ok := c.calls[key]
+	if ok {
+		atomic.AddInt64(&c.coalesced, 1)
+		if req.timings != nil {
+			req.timings.served(servedFromCoalesced)
+		}
+	} else {
+		call = &coalescedCall{done: make(chan struct{})}
+		c.calls[key] = call
+		go c.run(key, call, time.Duration(req.Timeout)*time.Second, execute)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		// Callers may set per-request fields on the response, so each gets its own copy
+		response := *call.response
+		return &response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run executes the shared upstream call and publishes its result
+func (c *requestCoalescer) run(key string, call *coalescedCall, timeout time.Duration, execute func(context.Context) (*ProxyResponse, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	call.response, call.err = execute(ctx)
+
+	// Requests arriving from now on start a new call
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// Coalesced returns the number of requests that were answered by another request's upstream call
+func (c *requestCoalescer) Coalesced() int64 {
+	return atomic.LoadInt64(&c.coalesced)
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import "time"
+
+// Default limits applied when the corresponding flags are not provided
+const (
+	DefaultMaxPathParams = 100  // Maximum number of path parameters per request
+	DefaultMaxURLLength  = 8192 // Maximum length of the URL after path parameter substitution
+
+	DefaultMaxHeaderValueLength = 8192  // Maximum length of a single outbound header value
+	DefaultMaxHeaderBytes       = 65536 // Maximum total size of the outbound request headers
+	DefaultMaxHeaderCount       = 100   // Maximum number of headers in a single request
+
+	DefaultTCPKeepAlive = 15 * time.Second // TCP keep-alive probe period of upstream connections
+
+	DefaultReadyCheckTimeout  = 5 * time.Second // Bound of the /ready upstream check
+	DefaultReadyCheckMaxBytes = 65536           // Response body bytes read by the /ready upstream check
+)
+
+// DefaultAllowedSchemes are the upstream URL schemes accepted when --allowed-schemes isn't provided
+var DefaultAllowedSchemes = []string{"http", "https"}
+
+// Formats of the 404 response for unknown endpoints
+const (
+	NotFoundFormatJSON = "json" // ProxyResponse-shaped JSON error (default)
+	NotFoundFormatText = "text" // Plain-text message
+)
+
+// Handling of request paths that only match an endpoint without their trailing slash
+const (
+	TrailingSlashStrict   = "strict"   // Answer with the 404 of unknown endpoints (default)
+	TrailingSlashAccept   = "accept"   // Serve the endpoint as if the slash weren't there
+	TrailingSlashRedirect = "redirect" // Redirect to the path without the slash (308, method and body kept)
+)
+
+// Config holds the server configuration, typically populated from command line flags
+type Config struct {
+	Port             int    // Port to listen on
+	BasePath         string // URL path prefix all routes are mounted under (empty = root)
+	Version          string // Version for User-Agent and health endpoint
+	BuildTime        string // Build timestamp reported by the version endpoint
+	GitCommit        string // Git commit reported by the version endpoint
+	EnableLocalFiles bool   // Enable local file serving via /file and /dir endpoints
+	EnableLogging    bool   // Enable verbose logging
+	EnableExec       bool   // Enable process execution via /exec endpoint
+	ExecInheritEnv   bool   // Pass the proxy's environment to /exec children (default: request env only)
+	ExecRoot         string // Confine /exec working directories to this base (empty = unrestricted)
+	ExecMaxOutput    int64  // Maximum bytes of /exec output captured per command (0 = unlimited)
+	FileRoot         string // Confine bodyFromPath request body files, /file, /file/batch and /dir to this base (empty = any absolute path)
+
+	ExecMaxConcurrent int // Maximum /exec processes running at once; excess ones are rejected (0 = unlimited)
+
+	Debug bool // Log streaming diagnostics ([SSE-DEBUG] lines), independently of EnableLogging
+
+	// Request defaults
+	DefaultPassThrough bool // Default passThrough for /proxy/request when omitted from the request
+	ErrorStatusCodes   bool // Answer failed upstream requests with 502/504/4xx instead of 200 when omitted from the request
+	MirrorStatus       bool // Answer with the upstream's status instead of 200 when omitted from the request
+
+	// Welcome message at the root endpoint
+	NoColor bool // Leave out color codes unless requested with ?color=true (set from NO_COLOR)
+
+	// Response for unknown endpoints (status is always 404)
+	NotFoundFormat string // NotFoundFormatJSON (default) or NotFoundFormatText
+	NotFoundBody   string // Custom response body (empty = built-in message); must be valid JSON in json format
+
+	// Upstream and streaming limits
+	MaxRequestsPerHost int           // Maximum concurrent upstream requests per target host (0 = unlimited)
+	StreamMaxDuration  time.Duration // Absolute maximum duration of a streaming response (0 = unlimited)
+	CoalesceRequests   bool          // Share one upstream call between identical concurrent GET/HEAD requests
+	MaxResponseBytes   int64         // Maximum response body size read into memory (0 = unlimited)
+	TCPKeepAlive       time.Duration // TCP keep-alive probe period of upstream connections (0 = no probes)
+	DisableTCPNoDelay  bool          // Keep Nagle's algorithm on upstream connections (Go disables it by default)
+	TCPFastOpen        bool          // Request TCP Fast Open on upstream connections where supported (Linux)
+	SourceAddress      string        // Local IP address upstream connections are made from (empty = chosen by the OS)
+	ResponseCacheTTL   time.Duration // How long successful GET/HEAD responses are reused (0 = no caching)
+
+	MaxStreamingConnections int // Maximum concurrent streaming requests; excess ones get a 503 (0 = unlimited)
+
+	ResponseHeaderTimeout time.Duration // Default limit on waiting for response headers (0 = overall timeout only)
+
+	// Upstream address restrictions
+	BlockPrivateNetworks bool // Refuse upstream connections to loopback, private and link-local addresses
+	DisableLoopDetection bool // Skip the User-Agent and hostname/blacklist loop checks (trusted test setups only)
+
+	AllowedSchemes []string // URL schemes accepted for upstream requests (empty = DefaultAllowedSchemes)
+	AllowedPorts   []string // Upstream ports accepted, like 443 or 8000-8999; URLs without one use the scheme's default port (empty = any port)
+
+	HealthCheckHosts []string // Blocked hostnames whose /health and / stay reachable (nil = all, empty = none)
+
+	FormForwardHeaders []string // Headers of the client's request /proxy/form forwards upstream unless its headers param sets them
+
+	TrailingSlash string // TrailingSlashStrict, TrailingSlashAccept or TrailingSlashRedirect (empty = strict)
+
+	DecompressRequestBodies bool // Decode gzip/deflate client request bodies (Content-Encoding) before handling them
+	EnablePprof             bool // Serve net/http/pprof profiling endpoints under /debug/pprof/ (localhost only)
+
+	ProxyProtocol        bool     // Require a PROXY protocol v1/v2 header on every connection and use its client address
+	ProxyProtocolTrusted []string // CIDRs or IPs of the load balancers allowed to send the header; other peers are dropped (empty = any peer)
+
+	// Body capture to temp files
+	EnableBodyCapture bool          // Allow requests to spill bodies to disk
+	BodyCaptureDir    string        // Directory for capture files (empty = system temp dir)
+	BodyCaptureTTL    time.Duration // How long capture files are kept
+
+	// Optional configuration files (empty disables the feature)
+	BlacklistFile       string // Additional hostnames to block
+	OAuth2ConfigFile    string // JSON OAuth2 client-credentials configurations
+	ContentTypesFile    string // Binary/text classification overrides by MIME prefix
+	ResponseHeadersFile string // Response header remove/set rules
+	ExecAllowlistFile   string // Commands permitted via /exec
+
+	PassThroughTypesFile string // Content types allowed/denied as-is in pass-through mode
+	BodyRulesFile        string // JSON field redact/remove rules for request and response bodies
+	DefaultHeadersFile   string // Headers added to every outbound request unless the caller sets them
+	AllowedMethodsFile   string // Upstream methods allowed through /proxy/request and /proxy/form
+
+	MimeTypesFile string // Content-Type served by /file per file extension, before the system MIME database
+
+	// Path parameter limits (0 disables the limit)
+	MaxPathParams int // Maximum number of entries in path_params
+	MaxURLLength  int // Maximum URL length after substitution
+
+	// Outbound header limits (0 disables the limit)
+	MaxHeaderValueLength int // Maximum length of any one header value
+	MaxHeaderBytes       int // Maximum total size of all headers ("Name: value\r\n" each)
+	MaxHeaderCount       int // Maximum number of entries in the headers array
+
+	// Directory listing limit (0 disables the limit)
+	MaxDirEntries int // Maximum number of entries scanned per /dir listing
+
+	// Readiness upstream check (empty URL = /ready doesn't check anything upstream)
+	ReadyCheckURL      string        // Target requested by /ready
+	ReadyCheckTimeout  time.Duration // Bound of the whole check (0 = DefaultReadyCheckTimeout)
+	ReadyCheckMaxBytes int64         // Maximum response body bytes read (0 = unlimited)
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultHeader is a header added to every outbound request that doesn't set it itself
+type DefaultHeader struct {
+	Name  string
+	Value string
+}
+
+// loadDefaultHeadersFile reads the default outbound request headers file
+// Format: one "Name: value" header per line
+// Example:
+//
+//	X-Proxy-Source: slingshot
+//	Accept-Language: en
+//	# This is a comment
+func loadDefaultHeadersFile(filename string) ([]DefaultHeader, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers []DefaultHeader
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("line %d: expected \"Name: value\"", i+1)
+		}
+		name := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		if name == "" || value == "" {
+			return nil, fmt.Errorf("line %d: header name and value must not be empty", i+1)
+		}
+		if strings.EqualFold(name, "Host") {
+			return nil, fmt.Errorf("line %d: Host can't be set as a default header", i+1)
+		}
+
+		headers = append(headers, DefaultHeader{Name: name, Value: value})
+	}
+
+	return headers, nil
+}
+
+// applyDefaultHeaders adds the configured default headers to the parsed caller headers
+// Caller headers win, compared case-insensitively; the proxy's own User-Agent and OAuth2
+// Authorization are only added later if neither the caller nor a default header set them.
+func (c *HTTPClient) applyDefaultHeaders(headers map[string]string) {
+	for _, header := range c.defaultHeaders {
+		if !hasParsedHeader(headers, header.Name) {
+			headers[header.Name] = header.Value
+		}
+	}
+}
+
+// hasParsedHeader reports whether headers parsed by parseHeaders contain the named header
+func hasParsedHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
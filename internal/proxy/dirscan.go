@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// dirScanChunk is the number of entries requested from the directory per read
+const dirScanChunk = 256
+
+// readDirEntries reads the entries of dir sorted by name, stopping after max entries (0 = unlimited)
+// Entries are read in chunks, so a huge directory is only scanned as far as the cap; the
+// returned flag reports that entries were left unread. With a cap, the names are sorted
+// within the scanned window only, since the directory's own order is arbitrary.
+func readDirEntries(dir string, max int) ([]os.DirEntry, bool, error) {
+	if max <= 0 {
+		entries, err := os.ReadDir(dir)
+		return entries, false, err
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	var entries []os.DirEntry
+	truncated := false
+	for len(entries) < max {
+		n := max - len(entries)
+		if n > dirScanChunk {
+			n = dirScanChunk
+		}
+		chunk, err := f.ReadDir(n)
+		entries = append(entries, chunk...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Hitting the cap exactly doesn't mean there's more; peek for one more entry
+	if len(entries) >= max {
+		if more, _ := f.ReadDir(1); len(more) > 0 {
+			truncated = true
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, truncated, nil
+}
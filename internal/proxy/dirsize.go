@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Bounds on the recursive size rollup of a /dir listing with includeDirSizes
+const (
+	dirSizeMaxDepth   = 32     // Directory levels descended below each listed directory
+	dirSizeMaxEntries = 100000 // Entries visited per request, shared by all listed directories
+)
+
+// dirSizeWalker sums the sizes of the files below directories within a shared entry budget
+// Symlinks are not followed, so links can't cause cycles or double counting.
+type dirSizeWalker struct {
+	remaining int // Entries that may still be visited
+}
+
+// newDirSizeWalker creates a walker with the per-request entry budget
+func newDirSizeWalker() *dirSizeWalker {
+	return &dirSizeWalker{remaining: dirSizeMaxEntries}
+}
+
+// size returns the total size of the files below dir and whether the total is complete
+// The total is incomplete if a cap was hit or a subdirectory could not be read.
+func (w *dirSizeWalker) size(dir string, depth int) (int64, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, false
+	}
+
+	var total int64
+	complete := true
+	for _, entry := range entries {
+		if w.remaining <= 0 {
+			return total, false
+		}
+		w.remaining--
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+
+		if entry.IsDir() {
+			if depth >= dirSizeMaxDepth {
+				complete = false
+				continue
+			}
+			subtotal, subComplete := w.size(filepath.Join(dir, entry.Name()), depth+1)
+			total += subtotal
+			complete = complete && subComplete
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			complete = false
+			continue
+		}
+		total += info.Size()
+	}
+
+	return total, complete
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// errorCatalog lists the predefined error types in the order /errors reports them
+var errorCatalog = []*ProxyError{
+	RequestFormatError,
+	UnknownError,
+	URLValidationError,
+	TimeoutError,
+	ConnectionError,
+	DNSError,
+	ConnectionRefusedError,
+	ConnectionResetError,
+	TLSError,
+	RedirectNotFollowedError,
+	LoopDetectedError,
+	ConnectTimeoutError,
+	TLSTimeoutError,
+	ResponseHeaderTimeoutError,
+	ResponseTooLargeError,
+	SSRFBlockedError,
+	StreamingTimeoutError,
+	FileNotFoundError,
+	FileAccessError,
+	FeatureDisabledError,
+	EndpointNotFoundError,
+	ExecTimeoutError,
+	ExecFailedError,
+	LocalhostOnlyError,
+	OAuth2TokenError,
+	MethodNotAllowedError,
+	StreamingLimitError,
+	RequestCancelledError,
+	CertPinMismatchError,
+	BodyCaptureError,
+}
+
+// ErrorCatalogEntry describes one error_type value
+type ErrorCatalogEntry struct {
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ErrorCatalogResponse is the body of /errors
+type ErrorCatalogResponse struct {
+	Errors []ErrorCatalogEntry `json:"errors"`
+}
+
+// handleErrorCatalog handles /errors endpoint
+// It lists every error_type the proxy reports, so clients can handle or localize them.
+// Some errors use a more specific error_title than the catalog's, e.g. request_format_error.
+func (s *Server) handleErrorCatalog(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	response := ErrorCatalogResponse{Errors: make([]ErrorCatalogEntry, 0, len(errorCatalog))}
+	for _, e := range errorCatalog {
+		response.Errors = append(response.Errors, ErrorCatalogEntry{
+			Type:        e.Type,
+			Title:       e.Title,
+			Description: e.Description,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import "net/http"
+
+// upstreamErrorStatus returns the HTTP status reporting a failed upstream request of errType (--error-status-codes)
+// Timeouts are 504, requests the proxy refused to send are 4xx and other upstream failures 502.
+func upstreamErrorStatus(errType string) int {
+	switch errType {
+	case TimeoutError.Type, ConnectTimeoutError.Type, TLSTimeoutError.Type, ResponseHeaderTimeoutError.Type, StreamingTimeoutError.Type:
+		return http.StatusGatewayTimeout
+	case URLValidationError.Type, RequestFormatError.Type:
+		return http.StatusBadRequest
+	case SSRFBlockedError.Type:
+		return http.StatusForbidden
+	case FileAccessError.Type, BodyCaptureError.Type:
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadGateway
+}
+
+// mirroredStatus returns the proxy status for a successful upstream response of status (mirrorStatus)
+// Statuses that can't carry the JSON body (1xx, 204, 304) and unset ones stay 200.
+func mirroredStatus(status int) int {
+	if status < 200 || status > 999 || status == http.StatusNoContent || status == http.StatusNotModified {
+		return http.StatusOK
+	}
+	return status
+}
+
+// mirrorStatus reports whether the upstream status is used as the proxy's own HTTP status
+// The request's own setting wins over --mirror-status.
+func (s *Server) mirrorStatus(override *bool) bool {
+	if override != nil {
+		return *override
+	}
+	return s.mirrorStatusDefault
+}
+
+// errorStatusCodes reports whether failed upstream requests get a matching HTTP status instead of 200
+// The request's own setting wins over --error-status-codes.
+func (s *Server) errorStatusCodes(override *bool) bool {
+	if override != nil {
+		return *override
+	}
+	return s.errorStatusDefault
+}
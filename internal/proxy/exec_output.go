@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// DefaultExecMaxOutput is the default cap on captured output per /exec stream (10 MiB)
+const DefaultExecMaxOutput = 10 << 20
+
+// DefaultExecMaxConcurrent is the default cap on /exec processes running at the same time
+const DefaultExecMaxConcurrent = 8
+
+// execOutputKillFactor is how far past the cap a command may write before it is killed
+// Output between the cap and this threshold is discarded, so commands that print a little
+// too much still finish normally with truncated output.
+const execOutputKillFactor = 4
+
+// outputLimiter tracks how much output a command has produced against a byte cap
+type outputLimiter struct {
+	limit  int64  // Maximum bytes kept (0 = unlimited)
+	onKill func() // Called once when output exceeds limit * execOutputKillFactor
+
+	mu        sync.Mutex
+	total     int64
+	truncated bool
+	killed    bool
+}
+
+// newOutputLimiter creates a limiter for the given cap
+func newOutputLimiter(limit int64, onKill func()) *outputLimiter {
+	return &outputLimiter{limit: limit, onKill: onKill}
+}
+
+// allow accounts for n more bytes and returns how many of them may be kept
+func (l *outputLimiter) allow(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return n
+	}
+
+	keep := int64(n)
+	if remaining := l.limit - l.total; keep > remaining {
+		if remaining < 0 {
+			remaining = 0
+		}
+		keep = remaining
+		l.truncated = true
+	}
+	l.total += int64(n)
+
+	if !l.killed && l.total > l.limit*execOutputKillFactor {
+		l.killed = true
+		if l.onKill != nil {
+			l.onKill()
+		}
+	}
+
+	return int(keep)
+}
+
+// Truncated reports whether any output was discarded
+func (l *outputLimiter) Truncated() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.truncated
+}
+
+// Killed reports whether the command was killed for producing too much output
+func (l *outputLimiter) Killed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.killed
+}
+
+// cappedBuffer is an io.Writer that keeps output up to its limiter's cap and drops the rest
+// The buffer is deliberately not embedded: its ReadFrom would let io.Copy bypass the cap.
+type cappedBuffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	limiter *outputLimiter
+}
+
+// Write always reports the full length so the command isn't failed with a short write
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	keep := b.limiter.allow(len(p))
+	b.mu.Lock()
+	b.buf.Write(p[:keep])
+	b.mu.Unlock()
+	return len(p), nil
+}
+
+// String returns the captured output
+func (b *cappedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// runCommand runs cmd, copying its output to stdout and stderr (which may be the same writer)
+// Once ctx ends the pipes are closed, so a killed command returns promptly even if
+// grandchildren that inherited its output are still running.
+func runCommand(ctx context.Context, cmd *exec.Cmd, stdin []byte, stdout, stderr io.Writer) error {
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := startCommand(cmd, stdin); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdoutPipe.Close()
+			stderrPipe.Close()
+		case <-done:
+		}
+	}()
+
+	var copiers sync.WaitGroup
+	copiers.Add(2)
+	go func() {
+		defer copiers.Done()
+		io.Copy(stdout, stdoutPipe)
+	}()
+	go func() {
+		defer copiers.Done()
+		io.Copy(stderr, stderrPipe)
+	}()
+
+	// Pipes must be drained before Wait
+	copiers.Wait()
+	return cmd.Wait()
+}
+
+// startCommand starts cmd and writes stdin (if any) to it before closing its input
+// The writer is not waited on, so a command that never reads its input can't block Wait;
+// Wait closes the pipe once the command exits, which ends a pending write.
+func startCommand(cmd *exec.Cmd, stdin []byte) error {
+	if stdin == nil {
+		return cmd.Start()
+	}
+
+	stdinPipe, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		stdinPipe.Write(stdin)
+		stdinPipe.Close()
+	}()
+	return nil
+}
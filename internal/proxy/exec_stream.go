@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ExecStreamEvent is one NDJSON line emitted by /exec in streaming mode
+type ExecStreamEvent struct {
+	Type string `json:"type"`           // "stdout", "stderr", "output" (combined) or "exit"
+	Data string `json:"data,omitempty"` // Output chunk (one line including its newline, or part of a long line)
+
+	// Final event fields (type = "exit")
+	Success       *bool  `json:"success,omitempty"`
+	ExitCode      *int   `json:"exitCode,omitempty"`
+	Truncated     bool   `json:"truncated,omitempty"` // Output exceeded the cap and was cut off
+	ExecutionTime string `json:"executionTime,omitempty"`
+	ErrorType     string `json:"errorType,omitempty"`
+	ErrorTitle    string `json:"errorTitle,omitempty"`
+	ErrorMessage  string `json:"errorMessage,omitempty"`
+}
+
+// executeCommandStreaming runs a command and streams its output line by line as NDJSON
+// The process is killed when the timeout expires or the client disconnects
+func (s *Server) executeCommandStreaming(w http.ResponseWriter, r *http.Request, req *ExecRequest) {
+	startTime := time.Now()
+
+	// Tie the process lifetime to both the timeout and the client connection
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, req.Command, req.Args...)
+	if err := s.prepareCommand(cmd, req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, ExecFailedError.Type, ExecFailedError.Title, err.Error())
+		return
+	}
+
+	stdoutType, stderrType := "stdout", "stderr"
+	if req.CombineOutput {
+		stdoutType, stderrType = "output", "output"
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, ExecFailedError.Type, ExecFailedError.Title, fmt.Sprintf("Failed to capture stdout: %v", err))
+		return
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, ExecFailedError.Type, ExecFailedError.Title, fmt.Sprintf("Failed to capture stderr: %v", err))
+		return
+	}
+
+	if err := startCommand(cmd, req.stdin); err != nil {
+		s.writeErrorResponse(w, http.StatusOK, ExecFailedError.Type, ExecFailedError.Title, fmt.Sprintf("Failed to execute command: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Slingshot-Streaming", "true")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	var writeMu sync.Mutex
+	writeEvent := func(event *ExecStreamEvent) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := encoder.Encode(event); err != nil {
+			// Client went away - stop the process
+			cancel()
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// Forward both pipes concurrently, one event per line, until the output cap is reached
+	limiter := newOutputLimiter(s.execMaxOutput, cancel)
+	var readers sync.WaitGroup
+	forward := func(eventType string, source io.Reader) {
+		defer readers.Done()
+		reader := bufio.NewReader(source)
+		for {
+			// Very long lines are emitted in buffer-sized chunks
+			line, err := reader.ReadSlice('\n')
+			if keep := limiter.allow(len(line)); keep > 0 {
+				writeEvent(&ExecStreamEvent{Type: eventType, Data: string(line[:keep])})
+			}
+			if err != nil && err != bufio.ErrBufferFull {
+				return
+			}
+		}
+	}
+	readers.Add(2)
+	go forward(stdoutType, stdout)
+	go forward(stderrType, stderr)
+
+	// On timeout or disconnect, close the pipes so readers don't block on
+	// grandchildren that inherited them after the main process was killed
+	go func() {
+		<-ctx.Done()
+		stdout.Close()
+		stderr.Close()
+	}()
+
+	// Pipes must be drained before Wait
+	readers.Wait()
+	err = cmd.Wait()
+
+	executionTime := time.Since(startTime)
+	final := &ExecStreamEvent{
+		Type:          "exit",
+		ExecutionTime: fmt.Sprintf("%.2f ms", float64(executionTime.Nanoseconds())/1000000),
+	}
+	final.Truncated = limiter.Truncated()
+	success := err == nil && !limiter.Killed()
+	final.Success = &success
+
+	switch {
+	case limiter.Killed():
+		final.ErrorType = ExecFailedError.Type
+		final.ErrorTitle = ExecFailedError.Title
+		final.ErrorMessage = fmt.Sprintf("Command killed after producing more than %d bytes of output", s.execMaxOutput*execOutputKillFactor)
+		s.logger.Printf("Command killed for excessive output: %s", req.Command)
+	case err == nil:
+		exitCode := 0
+		final.ExitCode = &exitCode
+	case ctx.Err() == context.DeadlineExceeded:
+		final.ErrorType = ExecTimeoutError.Type
+		final.ErrorTitle = ExecTimeoutError.Title
+		final.ErrorMessage = fmt.Sprintf("Command timed out after %d seconds", req.Timeout)
+		s.logger.Printf("Command timed out: %s", req.Command)
+	case r.Context().Err() != nil:
+		s.logger.Printf("Client disconnected, killed command: %s", req.Command)
+		return
+	default:
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode := exitErr.ExitCode()
+			final.ExitCode = &exitCode
+		} else {
+			final.ErrorType = ExecFailedError.Type
+			final.ErrorTitle = ExecFailedError.Title
+			final.ErrorMessage = fmt.Sprintf("Failed to execute command: %v", err)
+		}
+	}
+
+	writeEvent(final)
+	s.logger.Printf("Streamed command finished: %s (success: %v, time: %s)", req.Command, success, final.ExecutionTime)
+}
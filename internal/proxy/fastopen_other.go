@@ -0,0 +1,13 @@
+//go:build !linux
+
+package proxy
+
+import "syscall"
+
+// fastOpenSupported reports whether client TCP Fast Open can be requested on this platform
+const fastOpenSupported = false
+
+// fastOpenControl does nothing where client TCP Fast Open isn't supported
+func fastOpenControl(_, _ string, _ syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File batch limits
+const (
+	MaxFileBatchFiles     = 100              // Maximum number of files in one /file/batch request
+	DefaultFileBatchBytes = 10 * 1024 * 1024 // Total content returned when maxBytes isn't set
+	MaxFileBatchBytes     = 50 * 1024 * 1024 // Largest accepted maxBytes
+	fileBatchConcurrency  = 8                // Files read in parallel
+)
+
+// handleFileBatchRequest handles /file/batch endpoint
+// Files are checked and given their share of the size budget in request order, then read
+// concurrently; errors such as a missing file are reported per file.
+func (s *Server) handleFileBatchRequest(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Check if feature is enabled
+	if !s.enableLocalFiles {
+		s.logger.Printf("File batch endpoint accessed but feature is disabled")
+		s.writeErrorResponse(w, http.StatusForbidden, FeatureDisabledError.Type, FeatureDisabledError.Title,
+			"Local file serving is disabled. Enable with --enable-local-files flag.")
+		return
+	}
+
+	// Check if request is from localhost
+	if !s.isLocalhostRequest(r) {
+		s.logger.Printf("File batch endpoint accessed from non-localhost: %s", r.RemoteAddr)
+		s.writeErrorResponse(w, http.StatusForbidden, LocalhostOnlyError.Type, LocalhostOnlyError.Title,
+			"This endpoint is only accessible from localhost (127.0.0.1)")
+		return
+	}
+
+	// Parse request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
+		return
+	}
+
+	var batch FileBatchRequest
+	if err := json.Unmarshal(body, &batch); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
+		return
+	}
+
+	if len(batch.Files) == 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing Files", "Batch must contain at least one file")
+		return
+	}
+	if len(batch.Files) > MaxFileBatchFiles {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Batch Too Large",
+			fmt.Sprintf("Batch has %d files, maximum is %d", len(batch.Files), MaxFileBatchFiles))
+		return
+	}
+	if batch.MaxBytes < 0 || batch.MaxBytes > MaxFileBatchBytes {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid Size Budget",
+			fmt.Sprintf("maxBytes must be between 0 and %d", MaxFileBatchBytes))
+		return
+	}
+	budget := batch.MaxBytes
+	if budget == 0 {
+		budget = DefaultFileBatchBytes
+	}
+
+	// Resolve every file first so the budget goes to the files in request order
+	results := make([]FileBatchResult, len(batch.Files))
+	paths := make([]string, len(batch.Files))
+	allowed := make([]int64, len(batch.Files))
+	for i, file := range batch.Files {
+		results[i] = FileBatchResult{Index: i, Path: file.Path}
+		path, size, reqErr := s.statBatchFile(file)
+		if reqErr != nil {
+			results[i].ErrorType = reqErr.errType
+			results[i].Error = reqErr.message
+			continue
+		}
+		paths[i] = path
+		results[i].Path = path
+		results[i].SizeBytes = size
+		allowed[i] = size
+		if allowed[i] > budget {
+			allowed[i] = budget
+		}
+		budget -= allowed[i]
+	}
+
+	s.logger.Printf("File batch request: %d files", len(batch.Files))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fileBatchConcurrency)
+	for i := range results {
+		if paths[i] == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			s.readBatchFile(&results[i], paths[i], allowed[i])
+		}(i)
+	}
+	wg.Wait()
+
+	response := FileBatchResponse{Success: true, Results: results}
+	for _, result := range results {
+		if !result.Success {
+			response.Success = false
+		}
+		if result.Truncated {
+			response.Truncated = true
+		}
+		response.TotalBytes += result.ReadBytes
+	}
+
+	if err := jsonEncoder(w).Encode(response); err != nil {
+		s.logger.Printf("Failed to encode file batch response: %v", err)
+	}
+}
+
+// statBatchFile checks one file of a batch like /file does and returns its path and size
+// With --file-root the path may be relative to that root and must stay inside it.
+func (s *Server) statBatchFile(file FileRequest) (string, int64, *requestError) {
+	if file.Path == "" {
+		return "", 0, &requestError{http.StatusBadRequest, "request_format_error", "Missing path", "File path is required"}
+	}
+	if file.Follow {
+		return "", 0, &requestError{http.StatusBadRequest, "request_format_error", "Follow Not Supported", "follow isn't supported by /file/batch"}
+	}
+
+	path := filepath.Clean(file.Path)
+	if s.fileRoot != "" {
+		confined, err := confinePath(s.fileRoot, path)
+		if os.IsNotExist(err) {
+			return "", 0, &requestError{http.StatusNotFound, FileNotFoundError.Type, FileNotFoundError.Title, fmt.Sprintf("File not found: %s", path)}
+		}
+		if err != nil {
+			return "", 0, &requestError{http.StatusForbidden, FileAccessError.Type, FileAccessError.Title, err.Error()}
+		}
+		path = confined
+	} else if !filepath.IsAbs(path) {
+		return "", 0, &requestError{http.StatusBadRequest, FileAccessError.Type, FileAccessError.Title, "Path must be absolute"}
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", 0, &requestError{http.StatusNotFound, FileNotFoundError.Type, FileNotFoundError.Title, fmt.Sprintf("File not found: %s", path)}
+		}
+		return "", 0, &requestError{http.StatusInternalServerError, FileAccessError.Type, FileAccessError.Title, fmt.Sprintf("Cannot access file: %v", err)}
+	}
+	if fileInfo.IsDir() {
+		return "", 0, &requestError{http.StatusBadRequest, FileAccessError.Type, FileAccessError.Title, "Path is a directory, not a file"}
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return "", 0, &requestError{http.StatusBadRequest, FileAccessError.Type, FileAccessError.Title, "Path is not a regular file"}
+	}
+	return path, fileInfo.Size(), nil
+}
+
+// readBatchFile reads at most limit bytes of a checked file into result
+// Binary content, as /proxy/request classifies it, is base64 encoded.
+func (s *Server) readBatchFile(result *FileBatchResult, path string, limit int64) {
+	file, err := os.Open(path)
+	if err != nil {
+		result.ErrorType = FileAccessError.Type
+		result.Error = fmt.Sprintf("Failed to read file: %v", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit))
+	if err != nil {
+		result.ErrorType = FileAccessError.Type
+		result.Error = fmt.Sprintf("Failed to read file: %v", err)
+		return
+	}
+
+	sniff := data
+	if len(sniff) > 512 {
+		sniff = sniff[:512]
+	}
+	result.MimeType = s.detectMimeType(path, sniff)
+	if s.httpClient.isBinaryContent(result.MimeType) {
+		result.Encoding = "base64"
+		result.Content = base64.StdEncoding.EncodeToString(data)
+	} else {
+		result.Encoding = "text"
+		result.Content = string(data)
+	}
+	result.ReadBytes = int64(len(data))
+	result.Truncated = result.ReadBytes < result.SizeBytes
+	result.Success = true
+}
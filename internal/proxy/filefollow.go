@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Follow mode of the /file endpoint
+const (
+	DefaultFileFollowDuration = 10 * time.Minute       // Longest a file is followed unless followSeconds asks for less
+	fileFollowPollInterval    = 250 * time.Millisecond // How often a followed file is checked for appended bytes
+)
+
+// fileFollowDuration returns how long a follow request may stream
+// followSeconds can only shorten DefaultFileFollowDuration, and --stream-max-duration caps both.
+func (s *Server) fileFollowDuration(followSeconds int) time.Duration {
+	duration := DefaultFileFollowDuration
+	if requested := time.Duration(followSeconds) * time.Second; requested > 0 && requested < duration {
+		duration = requested
+	}
+	if limit := s.httpClient.streamMaxDuration; limit > 0 && limit < duration {
+		duration = limit
+	}
+	return duration
+}
+
+// serveFollowedFile streams file like tail -f until the client disconnects or the follow duration ends
+// Followed files count as streaming requests for --max-streaming-connections. Range and
+// conditional headers don't apply: the whole file is sent, then whatever is appended.
+func (s *Server) serveFollowedFile(w http.ResponseWriter, r *http.Request, file *os.File, path, mimeType string, followSeconds int) {
+	if !s.streams.acquire() {
+		s.logger.Printf("File follow rejected: %d streams already active", s.streams.Active())
+		w.Header().Set("Content-Type", "application/json")
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, StreamingLimitError.Type, StreamingLimitError.Title,
+			fmt.Sprintf("The proxy is already serving the maximum of %d concurrent streaming requests. Try again later.", s.streams.limit))
+		return
+	}
+	defer s.streams.release()
+
+	duration := s.fileFollowDuration(followSeconds)
+	ctx, cancel := context.WithTimeout(r.Context(), duration)
+	defer cancel()
+
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Slingshot-Streaming", "true")
+	w.WriteHeader(http.StatusOK)
+
+	s.logger.Printf("Following file: %s (up to %s)", path, duration)
+	written, err := followFile(ctx, w, file)
+	if err != nil {
+		s.logger.Printf("Stopped following %s after %d bytes: %v", path, written, err)
+		return
+	}
+	s.logger.Printf("Stopped following %s after %d bytes", path, written)
+}
+
+// followFile copies file to w, flushing each chunk, and then keeps copying appended bytes until ctx ends
+// A file truncated below the position read so far (e.g. rotated in place) is followed again
+// from its start. It returns the bytes written and the first read or write error.
+func followFile(ctx context.Context, w http.ResponseWriter, file *os.File) (int64, error) {
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(fileFollowPollInterval)
+	defer ticker.Stop()
+
+	buffer := make([]byte, 32*1024)
+	var position, written int64
+	for ctx.Err() == nil {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return written, err
+			}
+			position += int64(n)
+			written += int64(n)
+			if flusher != nil {
+				flusher.Flush()
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return written, err
+		}
+
+		// At the end of the file: wait for it to grow
+		select {
+		case <-ctx.Done():
+			return written, nil
+		case <-ticker.C:
+		}
+		if info, err := file.Stat(); err == nil && info.Size() < position {
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return written, err
+			}
+			position = 0
+		}
+	}
+	return written, nil
+}
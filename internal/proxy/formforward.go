@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// formForwardExcluded are headers /proxy/form can't forward: the framing and connection
+// handling of the client's own request, and Content-Type, which the form body determines
+var formForwardExcluded = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Upgrade":           true,
+	"Te":                true,
+	"Trailer":           true,
+}
+
+// parseFormForwardHeaders canonicalizes the --form-forward-headers allowlist
+func parseFormForwardHeaders(names []string) ([]string, error) {
+	var headers []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.ContainsAny(name, ": \t\r\n") {
+			return nil, fmt.Errorf("%q is not a header name", name)
+		}
+		name = http.CanonicalHeaderKey(name)
+		if formForwardExcluded[name] {
+			return nil, fmt.Errorf("%s can't be forwarded", name)
+		}
+		headers = append(headers, name)
+	}
+	return headers, nil
+}
+
+// forwardedFormHeaders returns the allowlisted headers of the client's request as "Name: value"
+// Headers the headers param already sets (explicit) are left out, so the caller's own values win.
+func (s *Server) forwardedFormHeaders(r *http.Request, explicit []string) []string {
+	var forwarded []string
+	for _, name := range s.formForwardHeaders {
+		values := r.Header.Values(name)
+		if len(values) == 0 || hasHeader(explicit, name) {
+			continue
+		}
+		separator := ", "
+		if name == "Cookie" {
+			separator = "; "
+		}
+		forwarded = append(forwarded, name+": "+strings.Join(values, separator))
+	}
+	return forwarded
+}
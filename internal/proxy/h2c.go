@@ -0,0 +1,20 @@
+//go:build go1.24
+
+package proxy
+
+import "net/http"
+
+// h2cSupported reports whether this build can send cleartext HTTP/2 (h2c) requests
+const h2cSupported = true
+
+// h2cTransport returns a copy of base that speaks HTTP/2 with prior knowledge to http:// URLs
+// HTTP/1.1 isn't offered, so the upstream must accept HTTP/2 without an Upgrade; an https://
+// redirect target is reached over HTTP/2 with TLS.
+func h2cTransport(base *http.Transport) *http.Transport {
+	transport := base.Clone()
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	protocols.SetHTTP2(true)
+	transport.Protocols = protocols
+	return transport
+}
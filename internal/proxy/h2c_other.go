@@ -0,0 +1,13 @@
+//go:build !go1.24
+
+package proxy
+
+import "net/http"
+
+// h2cSupported reports whether this build can send cleartext HTTP/2 (h2c) requests
+const h2cSupported = false
+
+// h2cTransport returns base unchanged where net/http can't send h2c (before Go 1.24)
+func h2cTransport(base *http.Transport) *http.Transport {
+	return base
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HeaderDiff compares the headers sent upstream with the response headers (includeHeaderDiff)
+// Names are lowercased and values are the first of each header, as in response_headers.
+type HeaderDiff struct {
+	Added   map[string]string       `json:"added,omitempty"`   // Only in the response
+	Removed map[string]string       `json:"removed,omitempty"` // Only in the request
+	Changed map[string]HeaderChange `json:"changed,omitempty"` // In both, with different values
+}
+
+// HeaderChange is a header present in both the request and the response with different values
+type HeaderChange struct {
+	Request  string `json:"request"`
+	Response string `json:"response"`
+}
+
+// diffHeaders compares the headers of the request that produced resp with the response headers
+// resp.Request is the final hop's request, so after redirects the diff is against what was sent last.
+// Headers the transport adds while writing the request (Host, Accept-Encoding) aren't included.
+func diffHeaders(resp *http.Response, responseHeaders map[string]string) *HeaderDiff {
+	sent := make(map[string]string)
+	if resp.Request != nil {
+		for key, values := range resp.Request.Header {
+			if len(values) > 0 {
+				sent[strings.ToLower(key)] = values[0]
+			}
+		}
+	}
+
+	diff := &HeaderDiff{
+		Added:   make(map[string]string),
+		Removed: make(map[string]string),
+		Changed: make(map[string]HeaderChange),
+	}
+	for name, value := range responseHeaders {
+		requestValue, ok := sent[name]
+		switch {
+		case !ok:
+			diff.Added[name] = value
+		case requestValue != value:
+			diff.Changed[name] = HeaderChange{Request: requestValue, Response: value}
+		}
+	}
+	for name, value := range sent {
+		if _, ok := responseHeaders[name]; !ok {
+			diff.Removed[name] = value
+		}
+	}
+	return diff
+}
@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxRecordedHeadBytes bounds the raw response head buffered per connection for orderedHeaders;
+// a larger head is left unrecorded and the header list falls back to sorted order
+const maxRecordedHeadBytes = 1 << 20
+
+// headerOrderRecorder captures the response header fields of a request in the order they arrived
+// Go's http.Header is a map, so the order is taken from the raw bytes read off the connection.
+type headerOrderRecorder struct {
+	mu       sync.Mutex
+	fields   []HeaderField
+	recorded bool
+}
+
+// wrap returns conn feeding its final (non-1xx) response head to r
+// Every hop of a redirect chain gets a connection of its own, so the last head recorded is the final hop's.
+func (r *headerOrderRecorder) wrap(conn net.Conn) net.Conn {
+	return &headerRecordingConn{Conn: conn, recorder: r}
+}
+
+// set stores the fields of a recorded response head
+func (r *headerOrderRecorder) set(fields []HeaderField) {
+	r.mu.Lock()
+	r.fields, r.recorded = fields, true
+	r.mu.Unlock()
+}
+
+// list returns the response headers in wire order, reconciled with the final header
+// Fields the proxy dropped or rewrote (Transfer-Encoding, decompression, --response-headers
+// rules) follow header; values of header that didn't arrive as recorded are appended sorted by name.
+func (r *headerOrderRecorder) list(header http.Header) []HeaderField {
+	r.mu.Lock()
+	recorded, fields := r.recorded, r.fields
+	r.mu.Unlock()
+
+	remaining := make(map[string][]string, len(header))
+	for name, values := range header {
+		remaining[name] = append([]string(nil), values...)
+	}
+
+	list := make([]HeaderField, 0, len(fields))
+	if recorded {
+		for _, field := range fields {
+			name := http.CanonicalHeaderKey(field.Name)
+			for i, value := range remaining[name] {
+				if value == field.Value {
+					list = append(list, field)
+					remaining[name] = append(remaining[name][:i], remaining[name][i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(remaining))
+	for name := range remaining {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range remaining[name] {
+			list = append(list, HeaderField{Name: name, Value: value})
+		}
+	}
+	return list
+}
+
+// transport returns a copy of base whose connections are recorded by r
+// base must already be single-use; TLS is set up here so the recorder sees the decrypted bytes,
+// and only HTTP/1.1 is offered, since HTTP/2 frames carry no byte order to read headers from.
+func (r *headerOrderRecorder) transport(base *http.Transport) *http.Transport {
+	transport := base.Clone()
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return r.wrap(conn), nil
+	}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig := &tls.Config{}
+		if transport.TLSClientConfig != nil {
+			tlsConfig = transport.TLSClientConfig.Clone()
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConfig.NextProtos = []string{"http/1.1"}
+
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsHandshake(ctx, tlsConn, transport.TLSHandshakeTimeout); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return r.wrap(tlsConn), nil
+	}
+	return transport
+}
+
+// headerRecordingConn buffers what is read from the connection until the final response head is complete
+// Only the transport's read loop reads from a connection, so the buffer needs no lock.
+type headerRecordingConn struct {
+	net.Conn
+	recorder *headerOrderRecorder
+	head     []byte
+	done     bool
+}
+
+func (c *headerRecordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && !c.done {
+		c.record(p[:n])
+	}
+	return n, err
+}
+
+// record appends data to the buffered head, skipping complete 1xx heads
+func (c *headerRecordingConn) record(data []byte) {
+	c.head = append(c.head, data...)
+	for {
+		head, rest, ok := splitResponseHead(c.head)
+		if !ok {
+			break
+		}
+		status, fields := parseResponseHead(head)
+		if status >= 200 || status < 100 {
+			c.recorder.set(fields)
+			c.done, c.head = true, nil
+			return
+		}
+		c.head = rest
+	}
+	if len(c.head) > maxRecordedHeadBytes {
+		c.done, c.head = true, nil
+	}
+}
+
+// splitResponseHead returns the response head at the start of b and the bytes after it;
+// ok is false until the blank line ending the head has arrived
+func splitResponseHead(b []byte) (head, rest []byte, ok bool) {
+	for i := 0; i < len(b); {
+		nl := bytes.IndexByte(b[i:], '\n')
+		if nl < 0 {
+			return nil, nil, false
+		}
+		next := i + nl + 1
+		if i > 0 && len(bytes.TrimSuffix(b[i:i+nl], []byte("\r"))) == 0 {
+			return b[:i], b[next:], true
+		}
+		i = next
+	}
+	return nil, nil, false
+}
+
+// parseResponseHead returns the status code and header fields of a raw response head
+// Names keep the case they were sent in; obsolete line folding is joined with a space.
+func parseResponseHead(head []byte) (int, []HeaderField) {
+	lines := strings.Split(strings.TrimRight(string(head), "\r\n"), "\n")
+
+	status := 0
+	if statusLine := strings.Fields(lines[0]); len(statusLine) >= 2 {
+		status, _ = strconv.Atoi(statusLine[1])
+	}
+
+	var fields []HeaderField
+	for _, line := range lines[1:] {
+		line = strings.TrimSuffix(line, "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(fields) > 0 {
+			last := &fields[len(fields)-1]
+			last.Value = strings.TrimSpace(last.Value + " " + strings.TrimSpace(line))
+			continue
+		}
+		if idx := strings.Index(line, ":"); idx > 0 {
+			fields = append(fields, HeaderField{Name: line[:idx], Value: strings.TrimSpace(line[idx+1:])})
+		}
+	}
+	return status, fields
+}
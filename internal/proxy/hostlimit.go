@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// hostLimiter caps the number of concurrent upstream requests per target host
+// Requests over the limit wait for a free slot until their context ends.
+type hostLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots is the semaphore for one host; refs counts holders and waiters so
+// idle hosts can be dropped from the map without breaking the limit
+type hostSlots struct {
+	slots chan struct{}
+	refs  int
+}
+
+// newHostLimiter creates a limiter allowing limit concurrent requests per host
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		hosts: make(map[string]*hostSlots),
+	}
+}
+
+// acquire waits for a slot for host and returns the function that releases it
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	entry, ok := l.hosts[host]
+	if !ok {
+		entry = &hostSlots{slots: make(chan struct{}, l.limit)}
+		l.hosts[host] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	select {
+	case entry.slots <- struct{}{}:
+	case <-ctx.Done():
+		l.unref(host, entry)
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-entry.slots
+			l.unref(host, entry)
+		})
+	}, nil
+}
+
+// unref drops a reference to a host entry, removing it once unused
+func (l *hostLimiter) unref(host string, entry *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.refs--
+	if entry.refs == 0 {
+		delete(l.hosts, host)
+	}
+}
+
+// InFlight returns the number of requests currently holding a slot, per host
+func (l *hostLimiter) InFlight() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inFlight := make(map[string]int, len(l.hosts))
+	for host, entry := range l.hosts {
+		if n := len(entry.slots); n > 0 {
+			inFlight[host] = n
+		}
+	}
+	return inFlight
+}
+
+// hostLimitedTransport holds a host slot from before dialing until the response body is closed
+type hostLimitedTransport struct {
+	base    http.RoundTripper
+	limiter *hostLimiter
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hostLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context(), strings.ToLower(req.URL.Host))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the host slot when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
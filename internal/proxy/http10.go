@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// http10Transport is a minimal RoundTripper that speaks HTTP/1.0 to the upstream
+// Go's http.Transport always writes HTTP/1.1 request lines, so legacy upstreams that
+// misbehave with HTTP/1.1 features need a dedicated writer. Each request uses a fresh
+// connection which is closed once the response body has been consumed.
+type http10Transport struct {
+	dialer    *net.Dialer
+	tlsConfig *tls.Config
+
+	tlsTimeout            time.Duration // Limit on the TLS handshake (0 = bounded by the request context)
+	responseHeaderTimeout time.Duration // Limit on waiting for the response headers (0 = bounded by the request context)
+
+	wrapConn func(net.Conn) net.Conn // Wraps each connection once TLS is set up (nil = used as is)
+	sockets  socketOptions           // Low-latency options applied to each connection
+}
+
+// newHTTP10Transport creates an HTTP/1.0 transport
+func newHTTP10Transport() *http10Transport {
+	return &http10Transport{
+		dialer:    &net.Dialer{Timeout: 30 * time.Second, KeepAlive: DefaultTCPKeepAlive},
+		tlsConfig: &tls.Config{},
+	}
+}
+
+// RoundTrip sends a single HTTP/1.0 request and reads its response
+func (t *http10Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	host := req.URL.Host
+	if req.URL.Port() == "" {
+		if req.URL.Scheme == "https" {
+			host = net.JoinHostPort(req.URL.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(req.URL.Hostname(), "80")
+		}
+	}
+
+	conn, err := t.sockets.dialContext(t.dialer)(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	if trace := httptrace.ContextClientTrace(ctx); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: conn})
+	}
+
+	if req.URL.Scheme == "https" {
+		tlsConfig := t.tlsConfig.Clone()
+		tlsConfig.ServerName = req.URL.Hostname()
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsHandshake(ctx, tlsConn, t.tlsTimeout); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	if t.wrapConn != nil {
+		conn = t.wrapConn(conn)
+	}
+
+	// Abort the connection if the request context ends while we're still using it
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := t.writeRequest(conn, req); err != nil {
+		close(done)
+		conn.Close()
+		return nil, err
+	}
+
+	if t.responseHeaderTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(t.responseHeaderTimeout))
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		close(done)
+		conn.Close()
+		var netErr net.Error
+		if t.responseHeaderTimeout > 0 && errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+			return nil, errResponseHeaderTimeout
+		}
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Time{})
+
+	resp.Body = &http10Body{ReadCloser: resp.Body, conn: conn, done: done}
+	return resp, nil
+}
+
+// tlsHandshake performs the TLS handshake within timeout, if set
+func tlsHandshake(ctx context.Context, conn *tls.Conn, timeout time.Duration) error {
+	if timeout <= 0 {
+		return conn.HandshakeContext(ctx)
+	}
+	handshakeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := conn.HandshakeContext(handshakeCtx)
+	if err != nil && handshakeCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return errTLSHandshakeTimeout
+	}
+	return err
+}
+
+// writeRequest serializes the request using an HTTP/1.0 request line
+func (t *http10Transport) writeRequest(conn net.Conn, req *http.Request) error {
+	// HTTP/1.0 has no chunked encoding, so the body must be fully known up front
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	hostHeader := req.Host
+	if hostHeader == "" {
+		hostHeader = req.URL.Host
+	}
+
+	bw := bufio.NewWriter(conn)
+	fmt.Fprintf(bw, "%s %s HTTP/1.0\r\n", req.Method, req.URL.RequestURI())
+	fmt.Fprintf(bw, "Host: %s\r\n", hostHeader)
+	if req.Close {
+		// The connection isn't reused either way, but closeConnection asks for the header
+		bw.WriteString("Connection: close\r\n")
+	}
+
+	header := req.Header.Clone()
+	header.Del("Host")
+	header.Del("Connection")
+	header.Del("Transfer-Encoding")
+	header.Del("Content-Length")
+	if header.Get("User-Agent") == "" {
+		// Like net/http, an empty User-Agent means none is sent
+		header.Del("User-Agent")
+	}
+	if err := header.Write(bw); err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		fmt.Fprintf(bw, "Content-Length: %d\r\n", len(body))
+	}
+	bw.WriteString("\r\n")
+	bw.Write(body)
+
+	return bw.Flush()
+}
+
+// http10Body closes the underlying connection together with the response body
+// Close may be called concurrently with reads and more than once
+type http10Body struct {
+	io.ReadCloser
+	conn      net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (b *http10Body) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.ReadCloser.Close()
+		err = b.conn.Close()
+	})
+	return err
+}
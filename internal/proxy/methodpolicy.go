@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// methodPolicyEndpoints are the endpoints an allowed methods policy can restrict
+var methodPolicyEndpoints = map[string]bool{
+	"/proxy/request": true,
+	"/proxy/form":    true,
+	"/proxy/batch":   true, // Checked per sub-request
+}
+
+// methodPolicy maps an endpoint to the upstream methods it may send ("" = every endpoint)
+type methodPolicy map[string]map[string]bool
+
+// loadAllowedMethodsFile reads the allowed upstream methods per endpoint
+// Format: an endpoint (or "*" for all of them) followed by a colon and a comma-separated
+// method list; an endpoint's own line wins over "*", and endpoints without a rule allow any method
+// Example:
+//
+//	*: GET, HEAD
+//	/proxy/form: GET, HEAD, POST
+//	# This is a comment
+func loadAllowedMethodsFile(filename string) (methodPolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := make(methodPolicy)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("line %d: expected \"<endpoint>: METHOD, METHOD\"", i+1)
+		}
+
+		endpoint := strings.TrimSpace(line[:idx])
+		if endpoint == "*" {
+			endpoint = ""
+		} else if !methodPolicyEndpoints[endpoint] {
+			return nil, fmt.Errorf("line %d: unknown endpoint %q (expected /proxy/request, /proxy/form, /proxy/batch or *)", i+1, endpoint)
+		}
+		if _, exists := policy[endpoint]; exists {
+			return nil, fmt.Errorf("line %d: duplicate rule for %q", i+1, strings.TrimSpace(line[:idx]))
+		}
+
+		methods := make(map[string]bool)
+		for _, method := range strings.Split(line[idx+1:], ",") {
+			method = strings.ToUpper(strings.TrimSpace(method))
+			if method == "" {
+				continue
+			}
+			if !overridableMethods[method] {
+				return nil, fmt.Errorf("line %d: %q is not a supported HTTP method", i+1, method)
+			}
+			methods[method] = true
+		}
+		if len(methods) == 0 {
+			return nil, fmt.Errorf("line %d: at least one method is required", i+1)
+		}
+		policy[endpoint] = methods
+	}
+
+	return policy, nil
+}
+
+// rule returns the methods allowed through endpoint, or nil if the policy doesn't restrict it
+func (p methodPolicy) rule(endpoint string) map[string]bool {
+	if methods, ok := p[endpoint]; ok {
+		return methods
+	}
+	return p[""]
+}
+
+// checkMethodPolicy rejects upstream methods the allowed methods policy doesn't permit for endpoint
+func (s *Server) checkMethodPolicy(endpoint, method string) *requestError {
+	methods := s.methodPolicy.rule(endpoint)
+	if methods == nil || methods[strings.ToUpper(method)] {
+		return nil
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for m := range methods {
+		allowed = append(allowed, m)
+	}
+	sort.Strings(allowed)
+	return &requestError{http.StatusMethodNotAllowed, MethodNotAllowedError.Type, MethodNotAllowedError.Title,
+		fmt.Sprintf("Method %s is not allowed through %s by the allowed methods policy (allowed: %s)",
+			strings.ToUpper(method), endpoint, strings.Join(allowed, ", "))}
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// MultipartPart describes one part of a multipart/form-data body built by the proxy (ProxyRequest.Multipart)
+type MultipartPart struct {
+	Name        string `json:"name"`                  // Form field name
+	Filename    string `json:"filename,omitempty"`    // Makes the part a file upload
+	Content     string `json:"content,omitempty"`     // Part body
+	Encoding    string `json:"encoding,omitempty"`    // "text" (default) or "base64" for binary content
+	ContentType string `json:"contentType,omitempty"` // Part Content-Type (file parts default to application/octet-stream)
+}
+
+// quoteEscaper escapes Content-Disposition parameter values like mime/multipart does
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// buildMultipartBody encodes parts as a multipart/form-data body
+// It returns the body and the Content-Type carrying its boundary.
+func buildMultipartBody(parts []MultipartPart) (string, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	for i, part := range parts {
+		if part.Name == "" {
+			return "", "", fmt.Errorf("multipart[%d] has no name", i)
+		}
+		// Part headers are written as given, so line breaks would start new headers
+		if strings.ContainsAny(part.Name+part.Filename+part.ContentType, "\r\n") {
+			return "", "", fmt.Errorf("multipart[%d]: name, filename and contentType must not contain line breaks", i)
+		}
+
+		content := []byte(part.Content)
+		switch part.Encoding {
+		case "", "text":
+		case "base64":
+			decoded, err := base64.StdEncoding.DecodeString(part.Content)
+			if err != nil {
+				return "", "", fmt.Errorf("multipart[%d]: failed to decode base64 content: %v", i, err)
+			}
+			content = decoded
+		default:
+			return "", "", fmt.Errorf("multipart[%d]: unknown encoding %q (expected \"text\" or \"base64\")", i, part.Encoding)
+		}
+
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(part.Name))
+		if part.Filename != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(part.Filename))
+		}
+		header := textproto.MIMEHeader{"Content-Disposition": {disposition}}
+		if part.ContentType != "" {
+			header.Set("Content-Type", part.ContentType)
+		} else if part.Filename != "" {
+			header.Set("Content-Type", "application/octet-stream")
+		}
+
+		w, err := mw.CreatePart(header)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := w.Write(content); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return "", "", err
+	}
+	return buf.String(), mw.FormDataContentType(), nil
+}
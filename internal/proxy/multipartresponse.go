@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+)
+
+// writeMultipartResponse writes a response as multipart/mixed (multipartResponse)
+// The first part is the ProxyResponse JSON without the body, the second the upstream body
+// as received, so binary bodies aren't inflated by base64.
+func (s *Server) writeMultipartResponse(w http.ResponseWriter, response *ProxyResponse) {
+	body, err := responseBodyBytes(response)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "unknown_error", "Request Failed", err.Error())
+		return
+	}
+
+	// Copy before clearing the body, since cached responses are shared
+	metadata := *response
+	metadata.ResponseData = ""
+	metadata.ResponseJSON = nil
+	encoded, err := json.Marshal(&metadata)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "unknown_error", "Request Failed", err.Error())
+		return
+	}
+
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "application/json")
+	part, err := mw.CreatePart(header)
+	if err == nil {
+		_, err = part.Write(encoded)
+	}
+	if err == nil {
+		header = textproto.MIMEHeader{}
+		setBodyPartHeader(header, response)
+		part, err = mw.CreatePart(header)
+	}
+	if err == nil {
+		_, err = part.Write(body)
+	}
+	if err == nil {
+		err = mw.Close()
+	}
+	if err != nil {
+		s.logger.Printf("Failed to write multipart response: %v", err)
+	}
+}
+
+// hasInlineBody reports whether a response carries its body, which failures and captured bodies don't
+func hasInlineBody(response *ProxyResponse) bool {
+	return response.Success && response.ResponseBodyFile == ""
+}
+
+// responseBodyBytes returns the upstream body of a response with an inline body, as received
+func responseBodyBytes(response *ProxyResponse) ([]byte, error) {
+	if response.ResponseJSON != nil {
+		return response.ResponseJSON, nil
+	}
+	if response.IsBinary {
+		return base64.StdEncoding.DecodeString(response.ResponseData)
+	}
+	return []byte(response.ResponseData), nil
+}
+
+// setBodyPartHeader describes the upstream body of response in the header of its part
+func setBodyPartHeader(header textproto.MIMEHeader, response *ProxyResponse) {
+	contentType := response.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("X-Slingshot-Status", strconv.Itoa(response.ResponseStatus))
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// classifyConnectionError maps a failed upstream request to a specific error type
+// and an actionable message. Errors that don't match a known cause are reported
+// as ConnectionError.
+func classifyConnectionError(err error) (*ProxyError, string) {
+	var blockedErr *blockedAddressError
+	if errors.As(err, &blockedErr) {
+		return SSRFBlockedError, fmt.Sprintf("The target resolves to %s, which is a private network address. Requests to private networks are blocked by --block-private-networks.", blockedErr.ip)
+	}
+
+	var pinErr *certPinMismatchError
+	if errors.As(err, &pinErr) {
+		return CertPinMismatchError, fmt.Sprintf("The server's certificate (SHA-256 %s) does not match pinnedCertSHA256. The connection was aborted before the request was sent.", pinErr.fingerprint)
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return DNSError, fmt.Sprintf("DNS lookup for %s timed out. Check your network and DNS settings.", dnsErr.Name)
+		}
+		return DNSError, fmt.Sprintf("Could not resolve host %s. Check the hostname for typos.", dnsErr.Name)
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ConnectionRefusedError, fmt.Sprintf("The server refused the connection. Check that it is running and the port is correct: %v", err)
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return ConnectionResetError, fmt.Sprintf("The server closed the connection unexpectedly. It may have crashed or rejected the request: %v", err)
+	}
+
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	if errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &certInvalidErr) {
+		return TLSError, fmt.Sprintf("The server's TLS certificate could not be verified: %v", err)
+	}
+	if strings.Contains(err.Error(), "tls: ") {
+		return TLSError, fmt.Sprintf("The TLS handshake with the server failed: %v", err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return TimeoutError, fmt.Sprintf("Connecting to the server timed out: %v", err)
+	}
+
+	return ConnectionError, fmt.Sprintf("Failed to connect to server: %v", err)
+}
@@ -0,0 +1,218 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauth2RefreshMargin is how long before expiry a cached token is refreshed
+// Tokens living less than twice as long are refreshed halfway through their lifetime instead.
+const oauth2RefreshMargin = 30 * time.Second
+
+// OAuth2Config describes a client-credentials token source and the hosts it applies to
+type OAuth2Config struct {
+	Hosts        []string `json:"hosts"`        // Target hostnames that receive the token
+	TokenURL     string   `json:"tokenUrl"`     // Token endpoint
+	ClientID     string   `json:"clientId"`     // Client identifier
+	ClientSecret string   `json:"clientSecret"` // Client secret
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// oauth2TokenSource fetches and caches access tokens for one OAuth2Config
+// The lock only guards the cache: at most one fetch runs at a time, and requests wait on it
+// only when there is no unexpired token to use meanwhile.
+type oauth2TokenSource struct {
+	config OAuth2Config
+	client *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+	refreshAt   time.Time    // When a new token is fetched while the cached one is still used
+	fetching    *oauth2Fetch // Fetch in flight (nil = none)
+}
+
+// oauth2Fetch is a token request shared by every caller that needs it; done is closed when it ends
+type oauth2Fetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// loadOAuth2ConfigFile reads a JSON file containing an array of OAuth2Config entries
+func loadOAuth2ConfigFile(filename string) ([]OAuth2Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []OAuth2Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	for i, cfg := range configs {
+		if cfg.TokenURL == "" || cfg.ClientID == "" || len(cfg.Hosts) == 0 {
+			return nil, fmt.Errorf("entry %d: tokenUrl, clientId and hosts are required", i)
+		}
+	}
+
+	return configs, nil
+}
+
+// newOAuth2TokenSource creates a token source for the given configuration
+func newOAuth2TokenSource(config OAuth2Config) *oauth2TokenSource {
+	return &oauth2TokenSource{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// matchesHost reports whether the token source applies to the given hostname
+func (ts *oauth2TokenSource) matchesHost(hostname string) bool {
+	for _, host := range ts.config.Hosts {
+		if strings.EqualFold(host, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// Token returns a cached access token, fetching a new one if missing or about to expire
+// A token due for refresh is still returned while it hasn't expired, with the refresh running
+// in the background; otherwise the caller waits for the fetch or until ctx is done.
+func (ts *oauth2TokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	now := time.Now()
+	if ts.accessToken != "" && now.Before(ts.refreshAt) {
+		token := ts.accessToken
+		ts.mu.Unlock()
+		return token, nil
+	}
+
+	call := ts.fetching
+	if call == nil {
+		call = &oauth2Fetch{done: make(chan struct{})}
+		ts.fetching = call
+		go ts.fetch(call)
+	}
+	if ts.accessToken != "" && now.Before(ts.expiresAt) {
+		token := ts.accessToken
+		ts.mu.Unlock()
+		return token, nil
+	}
+	ts.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// fetch requests a new token for call and caches it on success
+// It doesn't use a caller's context, since the fetch is shared and outlives a cancelled caller;
+// the client timeout bounds it.
+func (ts *oauth2TokenSource) fetch(call *oauth2Fetch) {
+	token, lifetime, err := ts.requestToken(context.Background())
+
+	ts.mu.Lock()
+	if err == nil {
+		margin := oauth2RefreshMargin
+		if margin > lifetime/2 {
+			margin = lifetime / 2
+		}
+		now := time.Now()
+		ts.accessToken = token
+		ts.expiresAt = now.Add(lifetime)
+		ts.refreshAt = ts.expiresAt.Add(-margin)
+	}
+	ts.fetching = nil
+	ts.mu.Unlock()
+
+	call.token, call.err = token, err
+	close(call.done)
+}
+
+// requestToken performs the client-credentials request and returns the token and its lifetime
+func (ts *oauth2TokenSource) requestToken(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(ts.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(ts.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", ts.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid token endpoint: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(ts.config.ClientID), url.QueryEscape(ts.config.ClientSecret))
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token endpoint unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("invalid token response: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response did not contain an access_token")
+	}
+
+	if token.ExpiresIn > 0 {
+		return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+	}
+	// No expiry given - cache for a conservative default
+	return token.AccessToken, 5 * time.Minute, nil
+}
+
+// applyOAuth2 injects a Bearer token for requests to configured hosts
+// An Authorization header supplied by the caller takes precedence and is left untouched
+func (c *HTTPClient) applyOAuth2(ctx context.Context, httpReq *http.Request) error {
+	if len(c.oauth2Sources) == 0 || httpReq.Header.Get("Authorization") != "" {
+		return nil
+	}
+
+	hostname := httpReq.URL.Hostname()
+	for _, source := range c.oauth2Sources {
+		if !source.matchesHost(hostname) {
+			continue
+		}
+
+		token, err := source.Token(ctx)
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// confinePath resolves path and ensures it stays within root
+// Relative paths are interpreted relative to root. Symlinks are resolved on both sides,
+// so a link inside root pointing outside of it is rejected. Returns the resolved path.
+func confinePath(root, path string) (string, error) {
+	resolvedRoot, err := filepath.EvalSymlinks(filepath.Clean(root))
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve root %s: %v", root, err)
+	}
+	resolvedRoot, err = filepath.Abs(resolvedRoot)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(resolvedRoot, path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(resolvedRoot, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside of %s", path, root)
+	}
+
+	return resolved, nil
+}
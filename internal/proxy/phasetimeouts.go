@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors reported by http10Transport when a phase timeout expires, mirroring net/http's own
+var (
+	errTLSHandshakeTimeout   = errors.New("TLS handshake timeout")
+	errResponseHeaderTimeout = errors.New("timeout awaiting response headers")
+)
+
+// phaseTimeouts are the per-phase limits of one request; zero leaves a phase bounded only by the overall timeout
+type phaseTimeouts struct {
+	connect        time.Duration // Establishing the TCP connection
+	tls            time.Duration // TLS handshake
+	responseHeader time.Duration // From the request being written to the response headers arriving
+}
+
+// phaseTimeoutsFor returns the phase timeouts requested by req
+func phaseTimeoutsFor(req *ProxyRequest) phaseTimeouts {
+	return phaseTimeouts{
+		connect:        time.Duration(req.ConnectTimeout) * time.Second,
+		tls:            time.Duration(req.TLSTimeout) * time.Second,
+		responseHeader: time.Duration(req.ResponseHeaderTimeout) * time.Second,
+	}
+}
+
+// isSet reports whether any phase has its own limit
+func (t phaseTimeouts) isSet() bool {
+	return t.connect > 0 || t.tls > 0 || t.responseHeader > 0
+}
+
+// transport returns a single-use copy of base with the phase timeouts applied
+// Keep-alives are disabled so the connection doesn't outlive the request in an unshared
+// pool. baseDialer is the shared transport's dialer, whose other settings are kept along with sockets.
+func (t phaseTimeouts) transport(base *http.Transport, baseDialer *net.Dialer, sockets socketOptions) *http.Transport {
+	transport := base.Clone()
+	transport.DisableKeepAlives = true
+	if t.tls > 0 {
+		transport.TLSHandshakeTimeout = t.tls
+	}
+	if t.responseHeader > 0 {
+		transport.ResponseHeaderTimeout = t.responseHeader
+	}
+
+	dialer := *baseDialer
+	if t.connect > 0 {
+		dialer.Timeout = t.connect
+	}
+	transport.DialContext = sockets.dialContext(&dialer)
+	return transport
+}
+
+// http10Transport returns a copy of base with the phase timeouts applied
+// Without a responseHeader limit of its own, base's (--response-header-timeout) is kept.
+func (t phaseTimeouts) http10Transport(base *http10Transport) *http10Transport {
+	dialer := *base.dialer
+	if t.connect > 0 {
+		dialer.Timeout = t.connect
+	}
+	responseHeaderTimeout := base.responseHeaderTimeout
+	if t.responseHeader > 0 {
+		responseHeaderTimeout = t.responseHeader
+	}
+	return &http10Transport{
+		dialer:                &dialer,
+		tlsConfig:             base.tlsConfig,
+		tlsTimeout:            t.tls,
+		responseHeaderTimeout: responseHeaderTimeout,
+		sockets:               base.sockets,
+	}
+}
+
+// classify maps an error caused by one of the phase timeouts to its error type
+// The overall request timeout is checked by the caller first, so a match here means a
+// phase limit expired before the request's own deadline.
+func (t phaseTimeouts) classify(err error) (*ProxyError, string, bool) {
+	if t.responseHeader > 0 && isResponseHeaderTimeout(err) {
+		return ResponseHeaderTimeoutError, fmt.Sprintf("The server did not send response headers within the responseHeaderTimeout of %s.", t.responseHeader), true
+	}
+
+	if t.tls > 0 && (errors.Is(err, errTLSHandshakeTimeout) || strings.Contains(err.Error(), "TLS handshake timeout")) {
+		return TLSTimeoutError, fmt.Sprintf("The TLS handshake did not complete within the tlsTimeout of %s.", t.tls), true
+	}
+
+	var opErr *net.OpError
+	if t.connect > 0 && errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout() {
+		return ConnectTimeoutError, fmt.Sprintf("Connecting to the server did not complete within the connectTimeout of %s.", t.connect), true
+	}
+
+	return nil, "", false
+}
+
+// isResponseHeaderTimeout reports whether err is a transport's response header timeout
+func isResponseHeaderTimeout(err error) bool {
+	return errors.Is(err, errResponseHeaderTimeout) || strings.Contains(err.Error(), "timeout awaiting response headers")
+}
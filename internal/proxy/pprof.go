@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// pprofPrefix is the path the profiling endpoints are mounted under (after the base path)
+const pprofPrefix = "/debug/pprof/"
+
+// handlePprof serves the net/http/pprof profiling endpoints (--pprof), to localhost only
+// pprof.Index expects paths starting with /debug/pprof/, so the base path is stripped first.
+func (s *Server) handlePprof(w http.ResponseWriter, r *http.Request) {
+	if !s.isLocalhostRequest(r) {
+		w.Header().Set("Content-Type", "application/json")
+		s.logger.Printf("Profiling endpoint accessed from non-localhost: %s", r.RemoteAddr)
+		s.writeErrorResponse(w, http.StatusForbidden, LocalhostOnlyError.Type, LocalhostOnlyError.Title,
+			"This endpoint is only accessible from localhost (127.0.0.1)")
+		return
+	}
+
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, s.basePath)
+	switch strings.TrimPrefix(r.URL.Path, pprofPrefix) {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		// The index page and named profiles such as heap, goroutine and allocs
+		pprof.Index(w, r)
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// corsSafelistedMethods never need to be listed in Access-Control-Allow-Methods
+var corsSafelistedMethods = map[string]bool{"GET": true, "HEAD": true, "POST": true}
+
+// handlePreflightRequest handles /proxy/preflight endpoint
+// OPTIONS sent to the proxy itself is always answered by the proxy's own CORS handling, so
+// this endpoint sends the preflight a browser would send to the upstream instead, and reports
+// the upstream's CORS headers and whether a browser would go on with the actual request.
+func (s *Server) handlePreflightRequest(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
+		return
+	}
+
+	var preflight PreflightRequest
+	if err := json.Unmarshal(body, &preflight); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
+		return
+	}
+
+	if preflight.Origin == "" || preflight.RequestMethod == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing Preflight Fields",
+			"origin and requestMethod are required")
+		return
+	}
+
+	// Browsers don't follow redirects of a preflight, so neither does this one
+	followRedirects := false
+	req := ProxyRequest{
+		Method:          http.MethodOptions,
+		URL:             preflight.URL,
+		Headers:         append(preflight.Headers, preflightHeaders(&preflight)...),
+		Timeout:         preflight.Timeout,
+		FollowRedirects: &followRedirects,
+	}
+	if reqErr := s.validateProxyRequest(r, &req); reqErr != nil {
+		s.writeErrorResponse(w, reqErr.status, reqErr.errType, reqErr.title, reqErr.message)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	s.logger.Printf("PREFLIGHT %s %s (origin %s)", preflight.RequestMethod, req.URL, preflight.Origin)
+
+	response, err := s.httpClient.ExecuteRequest(ctx, &req)
+	if err != nil {
+		s.logger.Printf("Preflight request failed: %v", err)
+		s.writeErrorResponse(w, http.StatusInternalServerError, "unknown_error", "Request Failed", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(evaluatePreflight(&preflight, response))
+}
+
+// preflightHeaders returns the CORS request headers a browser sends with the preflight
+// Requested header names are lowercased and sorted, as browsers send them.
+func preflightHeaders(preflight *PreflightRequest) []string {
+	headers := []string{
+		"Origin: " + preflight.Origin,
+		"Access-Control-Request-Method: " + preflight.RequestMethod,
+	}
+	if len(preflight.RequestHeaders) > 0 {
+		names := make([]string, len(preflight.RequestHeaders))
+		for i, name := range preflight.RequestHeaders {
+			names[i] = strings.ToLower(strings.TrimSpace(name))
+		}
+		sort.Strings(names)
+		headers = append(headers, "Access-Control-Request-Headers: "+strings.Join(names, ","))
+	}
+	return headers
+}
+
+// evaluatePreflight checks the upstream's answer the way a browser checks a preflight response
+// Credentialed requests aren't modeled, so a wildcard origin, method or header list counts as allowing
+// everything, except that a wildcard header list never covers Authorization.
+func evaluatePreflight(preflight *PreflightRequest, response *ProxyResponse) *PreflightResponse {
+	result := &PreflightResponse{
+		Success:        response.Success,
+		ResponseStatus: response.ResponseStatus,
+		ResponseTime:   response.ResponseTime,
+		ErrorType:      response.ErrorType,
+		ErrorTitle:     response.ErrorTitle,
+		ErrorMessage:   response.ErrorMessage,
+	}
+	if !response.Success {
+		return result
+	}
+
+	result.CORSHeaders = make(map[string]string)
+	for name, value := range response.ResponseHeaders {
+		if strings.HasPrefix(name, "access-control-") || name == "vary" {
+			result.CORSHeaders[name] = value
+		}
+	}
+
+	allowOrigin := strings.TrimSpace(response.ResponseHeaders["access-control-allow-origin"])
+	result.OriginAllowed = allowOrigin == "*" || allowOrigin == preflight.Origin
+
+	allowMethods := corsList(response.ResponseHeaders["access-control-allow-methods"])
+	result.MethodAllowed = corsSafelistedMethods[preflight.RequestMethod] || allowMethods["*"] || allowMethods[strings.ToLower(preflight.RequestMethod)]
+
+	allowHeaders := corsList(response.ResponseHeaders["access-control-allow-headers"])
+	result.HeadersAllowed = true
+	for _, name := range preflight.RequestHeaders {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !allowHeaders[name] && (!allowHeaders["*"] || name == "authorization") {
+			result.HeadersAllowed = false
+		}
+	}
+
+	okStatus := response.ResponseStatus >= 200 && response.ResponseStatus < 300
+	result.Allowed = okStatus && result.OriginAllowed && result.MethodAllowed && result.HeadersAllowed
+	return result
+}
+
+// corsList parses a comma-separated CORS header value into a set of lowercased entries
+func corsList(value string) map[string]bool {
+	entries := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			entries[entry] = true
+		}
+	}
+	return entries
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// prettyResponseWriter marks a response whose JSON body is indented, as asked for with ?pretty=true
+// Only whole JSON documents written through jsonEncoder are indented; streamed data and the
+// single-line streaming metadata are written unchanged.
+type prettyResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Flush implements http.Flusher interface for streaming support
+func (w *prettyResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// withPrettyJSON wraps w so JSON responses are indented if the request has ?pretty=true
+func withPrettyJSON(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+		return &prettyResponseWriter{ResponseWriter: w}
+	}
+	return w
+}
+
+// jsonEncoder returns an encoder for a JSON response body, indenting it for pretty responses
+func jsonEncoder(w io.Writer) *json.Encoder {
+	encoder := json.NewEncoder(w)
+	if _, ok := w.(*prettyResponseWriter); ok {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder
+}
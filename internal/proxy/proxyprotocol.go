@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyProtocolHeaderTimeout bounds how long a new connection may take to send its PROXY header
+const proxyProtocolHeaderTimeout = 10 * time.Second
+
+// proxyProtocolV1MaxLength is the longest valid v1 header ("PROXY TCP6 ...\r\n"), per the spec
+const proxyProtocolV1MaxLength = 107
+
+// proxyProtocolV2Signature starts every v2 header
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtocolListener accepts connections that start with a PROXY protocol v1 or v2 header (--proxy-protocol)
+// The header is read by the connection's own goroutine, so a slow client can't stall Accept.
+// Connections without a valid header are dropped: behind a load balancer they can't be genuine.
+// So are connections from peers outside trusted, whose header could claim any client address.
+type proxyProtocolListener struct {
+	net.Listener
+	trusted []*net.IPNet // Peers allowed to send a PROXY header (empty = any peer)
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if !l.isTrusted(conn.RemoteAddr()) {
+			conn.Close()
+			continue
+		}
+		return &proxyProtocolConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+	}
+}
+
+// isTrusted reports whether a connection's own peer address may send a PROXY header
+func (l *proxyProtocolListener) isTrusted(addr net.Addr) bool {
+	if len(l.trusted) == 0 {
+		return true
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxyProtocolTrusted parses --proxy-protocol-trusted entries, CIDRs or single IP addresses
+func parseProxyProtocolTrusted(entries []string) ([]*net.IPNet, error) {
+	var trusted []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR", entry)
+		}
+		trusted = append(trusted, network)
+	}
+	return trusted, nil
+}
+
+// proxyProtocolConn reports the client address from its PROXY header as RemoteAddr
+type proxyProtocolConn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	once       sync.Once
+	remoteAddr net.Addr // Client address from the header (nil = the connection's own, e.g. for LOCAL)
+	headerErr  error
+}
+
+// readHeader consumes the PROXY header once, before anything else is read from the connection
+func (c *proxyProtocolConn) readHeader() {
+	c.once.Do(func() {
+		c.Conn.SetReadDeadline(time.Now().Add(proxyProtocolHeaderTimeout))
+		c.remoteAddr, c.headerErr = readProxyProtocolHeader(c.reader)
+		c.Conn.SetReadDeadline(time.Time{})
+	})
+}
+
+func (c *proxyProtocolConn) Read(p []byte) (int, error) {
+	c.readHeader()
+	if c.headerErr != nil {
+		return 0, c.headerErr
+	}
+	return c.reader.Read(p)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readProxyProtocolHeader reads a v1 or v2 header and returns the client address it carries
+// A nil address with a nil error means the header is valid but carries no usable address
+// (v1 UNKNOWN, v2 LOCAL or a non-TCP/IP family).
+func readProxyProtocolHeader(r *bufio.Reader) (net.Addr, error) {
+	start, err := r.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("missing PROXY protocol header: %v", err)
+	}
+	if bytes.Equal(start, proxyProtocolV2Signature) {
+		return readProxyProtocolV2(r)
+	}
+	if bytes.HasPrefix(start, []byte("PROXY ")) {
+		return readProxyProtocolV1(r)
+	}
+	return nil, errors.New("missing PROXY protocol header")
+}
+
+// readProxyProtocolV1 parses "PROXY TCP4|TCP6 <src> <dst> <sport> <dport>\r\n" or "PROXY UNKNOWN ...\r\n"
+func readProxyProtocolV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("incomplete PROXY protocol v1 header: %v", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= proxyProtocolV1MaxLength {
+			return nil, errors.New("PROXY protocol v1 header too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("PROXY protocol v1 header must end with CRLF")
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 source address %s:%s", fields[2], fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyProtocolV2 parses the binary v2 header, skipping any TLVs after the addresses
+func readProxyProtocolV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("incomplete PROXY protocol v2 header: %v", err)
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", header[12]>>4)
+	}
+	command, family := header[12]&0x0f, header[13]
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("incomplete PROXY protocol v2 addresses: %v", err)
+	}
+
+	switch command {
+	case 0x0: // LOCAL: a health check of the load balancer itself
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("unsupported PROXY protocol v2 command %d", command)
+	}
+
+	switch family {
+	case 0x11, 0x12: // TCP or UDP over IPv4: src, dst, sport, dport
+		if len(payload) < 12 {
+			return nil, errors.New("truncated PROXY protocol v2 IPv4 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x21, 0x22: // TCP or UDP over IPv6
+		if len(payload) < 36 {
+			return nil, errors.New("truncated PROXY protocol v2 IPv6 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	return nil, nil
+}
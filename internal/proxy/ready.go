@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Readiness states reported by /ready
+const (
+	ReadyStatusReady    = "ready"
+	ReadyStatusNotReady = "not_ready"
+)
+
+// readyCheckStatusError is the error type of a check target that answered with a 5xx status
+const readyCheckStatusError = "upstream_unavailable"
+
+// readyChecker probes an upstream target for /ready, bounded in time and bytes read
+type readyChecker struct {
+	url      string
+	timeout  time.Duration // Bound of the whole check, body included
+	maxBytes int64         // Maximum response body bytes read (0 = unlimited)
+	client   *http.Client
+}
+
+// newReadyChecker creates a checker for target; the check never follows redirects,
+// so a redirecting target counts as reachable without a second round trip
+func newReadyChecker(target string, timeout time.Duration, maxBytes int64) *readyChecker {
+	return &readyChecker{
+		url:      target,
+		timeout:  timeout,
+		maxBytes: maxBytes,
+		client: &http.Client{
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DisableKeepAlives: true},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// check requests the target and reports the outcome; any response below 500 counts as ready
+func (c *readyChecker) check(ctx context.Context, userAgent string) *ReadyCheck {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	result := &ReadyCheck{URL: c.url}
+	metrics := &RequestMetrics{StartTime: time.Now()}
+	fail := func(errType, message string) *ReadyCheck {
+		metrics.EndTime = time.Now()
+		result.Duration = metrics.FormatDuration()
+		result.ErrorType, result.ErrorMessage = errType, message
+		return result
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fail(URLValidationError.Type, err.Error())
+	}
+	httpReq.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(httpReq)
+	if err == nil {
+		defer resp.Body.Close()
+		body := io.Reader(resp.Body)
+		if c.maxBytes > 0 {
+			body = io.LimitReader(body, c.maxBytes)
+		}
+		_, err = io.Copy(io.Discard, body)
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fail(TimeoutError.Type, fmt.Sprintf("The readiness check did not complete within %s.", c.timeout))
+		}
+		errType, message := classifyConnectionError(err)
+		return fail(errType.Type, message)
+	}
+
+	result.ResponseStatus = resp.StatusCode
+	if resp.StatusCode >= 500 {
+		return fail(readyCheckStatusError, fmt.Sprintf("The readiness check target answered with status %d.", resp.StatusCode))
+	}
+	metrics.EndTime = time.Now()
+	result.Duration = metrics.FormatDuration()
+	return result
+}
+
+// handleReady reports whether the proxy is ready to serve traffic
+// Without --ready-check-url it is ready whenever it is running, like /health; otherwise
+// the check target must be reachable within --ready-check-timeout.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := &ReadyResponse{Status: ReadyStatusReady}
+	if s.readyCheck != nil {
+		response.Check = s.readyCheck.check(r.Context(), fmt.Sprintf("rb-slingshot/%s (https://requestbite.com/slingshot)", s.version))
+		if response.Check.ErrorType != "" {
+			response.Status = ReadyStatusNotReady
+			s.logger.Printf("Readiness check failed: %s", response.Check.ErrorMessage)
+		}
+	}
+
+	if response.Status == ReadyStatusReady {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// wireSizeTransport does the transparent gzip decoding of http.Transport itself, so the compressed
+// size of a decoded response body can be reported in metrics.CompressedBytes
+// Like http.Transport, it only asks for gzip when the caller set neither Accept-Encoding nor Range;
+// responses the caller asked to be encoded are returned as they are.
+type wireSizeTransport struct {
+	base    http.RoundTripper
+	metrics *RequestMetrics
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *wireSizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodHead || req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request; the clone shares its body
+	gzipReq := req.Clone(req.Context())
+	gzipReq.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := t.base.RoundTrip(gzipReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each redirect hop starts over, so the size is the final response's
+	t.metrics.CompressedBytes = 0
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") || resp.ContentLength == 0 {
+		return resp, nil
+	}
+
+	resp.Body = &gunzipBody{raw: resp.Body, counter: &wireCounter{r: resp.Body, n: &t.metrics.CompressedBytes}}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// wireCounter counts the bytes read from the compressed body into n
+type wireCounter struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *wireCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// gunzipBody decompresses the response body, reading the gzip header on the first Read
+// like http.Transport, so an invalid body fails when read rather than when the response arrives
+type gunzipBody struct {
+	raw     io.ReadCloser
+	counter *wireCounter
+	zr      *gzip.Reader
+	err     error
+}
+
+func (b *gunzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil && b.err == nil {
+		b.zr, b.err = gzip.NewReader(b.counter)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gunzipBody) Close() error {
+	return b.raw.Close()
+}
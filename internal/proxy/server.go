@@ -1,23 +1,27 @@
 package proxy
 
 import (
-	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -29,14 +33,85 @@ type Server struct {
 	httpClient       *HTTPClient
 	server           *http.Server
 	logger           *log.Logger
-	blockedHostnames []string // Configurable list of hostnames to block (prevents loops)
-	version          string   // Version for health endpoint
-	enableLocalFiles bool     // Enable local file serving via /file endpoint
-	enableExec       bool     // Enable process execution via /exec endpoint
+	blockedHostnames []string        // Configurable list of hostnames to block (prevents loops)
+	version          string          // Version for health endpoint
+	buildTime        string          // Build timestamp for version endpoint
+	gitCommit        string          // Git commit for version endpoint
+	enableLocalFiles bool            // Enable local file serving via /file endpoint
+	enableExec       bool            // Enable process execution via /exec endpoint
+	maxPathParams    int             // Maximum number of path parameters (0 = unlimited)
+	maxURLLength     int             // Maximum URL length after substitution (0 = unlimited)
+	maxHeaderValue   int             // Maximum length of one outbound header value (0 = unlimited)
+	maxHeaderBytes   int             // Maximum total size of outbound headers (0 = unlimited)
+	maxHeaderCount   int             // Maximum number of headers per request (0 = unlimited)
+	execAllowlist    []execAllowRule // Commands permitted via /exec (nil = no restriction)
+	execInheritEnv   bool            // Pass the proxy's own environment to /exec children
+	execRoot         string          // Base directory /exec working directories are confined to
+	execMaxOutput    int64           // Maximum bytes of output captured per command (0 = unlimited)
+	fileRoot         string          // Base directory bodyFromPath, /file, /file/batch and /dir paths are confined to (empty = any absolute path)
+
+	defaultPassThrough bool            // PassThrough value for /proxy/request when the field is omitted
+	notFoundFormat     string          // NotFoundFormatJSON or NotFoundFormatText
+	trailingSlash      string          // TrailingSlashStrict, TrailingSlashAccept or TrailingSlashRedirect
+	notFoundBody       string          // Custom 404 body (empty = built-in message)
+	blocked            *blockCounters  // Loop, blacklist and private network rejections (shared with httpClient)
+	passThroughTypes   map[string]bool // MIME prefix -> true (allow) / false (deny), consulted before built-in defaults
+
+	mimeTypes map[string]string // Lowercased file extension with dot -> Content-Type served by /file, consulted first
+
+	maxDirEntries int // Maximum entries scanned per /dir listing (0 = unlimited)
+
+	disableLoopDetection bool   // Skip detectLoop: no User-Agent, hostname or blacklist checks
+	basePath             string // Prefix every route is mounted under, e.g. "/slingshot" (empty = root)
+
+	healthCheckHosts []string // Blocked hostnames whose /health and / may be requested (nil = all, empty = none)
+
+	formForwardHeaders []string // Canonical names of client request headers /proxy/form forwards upstream
+
+	readyCheck *readyChecker // Upstream check of /ready (nil = none)
+
+	decompressRequestBodies bool // Decode gzip/deflate client request bodies before handling them
+	enablePprof             bool // Serve net/http/pprof under /debug/pprof/ (localhost only)
+
+	proxyProtocol        bool         // Read the client address from a PROXY protocol header on each connection
+	proxyProtocolTrusted []*net.IPNet // Peers allowed to send the PROXY header (empty = any peer)
+
+	errorStatusDefault  bool // Answer failed upstream requests with 502/504/4xx instead of 200 unless a request says otherwise
+	mirrorStatusDefault bool // Answer with the upstream's status instead of 200 unless a request says otherwise
+
+	noColor bool // Send the welcome message without color unless ?color=true asks for it (NO_COLOR)
+
+	methodPolicy methodPolicy // Upstream methods allowed per endpoint (nil = any method)
+
+	streams *streamLimiter // Streaming requests in progress, capped by --max-streaming-connections
+
+	execs *streamLimiter // /exec processes running, capped by --exec-max-concurrent
+
+	active *activeRequests // Requests with a requestId, cancellable through /proxy/cancel
+
+	targets *targetBalancer // Rotation of requests over their targets
+}
+
+// passThroughDeniedTypes are content types neutralized in pass-through mode by default,
+// since a browser would render them (and run their scripts) in the proxy's origin
+var passThroughDeniedTypes = []string{
+	"text/html",
+	"application/xhtml+xml",
+	"image/svg+xml",
+}
+
+// execDeniedEnvPrefixes are environment variables callers may not set unless the parent
+// environment is trusted, since they change how the child process is loaded
+var execDeniedEnvPrefixes = []string{"LD_", "DYLD_"}
+
+// execAllowRule permits a command, optionally restricting its arguments
+type execAllowRule struct {
+	command string         // Bare command name or absolute path
+	args    *regexp.Regexp // Optional pattern the space-joined arguments must match
 }
 
-// NewServer creates a new proxy server instance
-func NewServer(port int, version string, enableLocalFiles bool, blacklistFile string, enableLogging bool, enableExec bool) (*Server, error) {
+// NewServer creates a new proxy server instance from the given configuration
+func NewServer(cfg Config) (*Server, error) {
 	logger := log.New(log.Writer(), "[PROXY] ", log.LstdFlags)
 
 	// CONFIGURABLE: List of hostnames to block to prevent loops
@@ -47,23 +122,299 @@ func NewServer(port int, version string, enableLocalFiles bool, blacklistFile st
 	}
 
 	// Load additional hostnames from blacklist file if provided
-	if blacklistFile != "" {
-		additionalHosts, err := loadBlacklistFile(blacklistFile)
+	if cfg.BlacklistFile != "" {
+		additionalHosts, err := loadBlacklistFile(cfg.BlacklistFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load blacklist file: %v", err)
 		}
 		blockedHostnames = append(blockedHostnames, additionalHosts...)
-		logger.Printf("Loaded %d hostname(s) from blacklist file: %s", len(additionalHosts), blacklistFile)
+		logger.Printf("Loaded %d hostname(s) from blacklist file: %s", len(additionalHosts), cfg.BlacklistFile)
+	}
+
+	// Keep nil (every blocked hostname exempt) apart from an empty list (none exempt)
+	var healthCheckHosts []string
+	if cfg.HealthCheckHosts != nil {
+		healthCheckHosts = []string{}
+		for _, host := range cfg.HealthCheckHosts {
+			if host = strings.TrimSpace(host); host != "" {
+				healthCheckHosts = append(healthCheckHosts, host)
+			}
+		}
+	}
+
+	formForwardHeaders, err := parseFormForwardHeaders(cfg.FormForwardHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("invalid form forward headers: %v", err)
+	}
+
+	httpClient := NewHTTPClient(cfg.Version, cfg.EnableLogging, logger)
+	httpClient.debug = cfg.Debug
+
+	// Load OAuth2 client-credentials configurations if provided
+	if cfg.OAuth2ConfigFile != "" {
+		oauth2Configs, err := loadOAuth2ConfigFile(cfg.OAuth2ConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OAuth2 config file: %v", err)
+		}
+		for _, oauth2Config := range oauth2Configs {
+			httpClient.oauth2Sources = append(httpClient.oauth2Sources, newOAuth2TokenSource(oauth2Config))
+		}
+		logger.Printf("Loaded %d OAuth2 configuration(s) from: %s", len(oauth2Configs), cfg.OAuth2ConfigFile)
+	}
+
+	// Load content type classification overrides if provided
+	if cfg.ContentTypesFile != "" {
+		overrides, err := loadContentTypesFile(cfg.ContentTypesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load content types file: %v", err)
+		}
+		httpClient.binaryOverrides = overrides
+		logger.Printf("Loaded %d content type override(s) from: %s", len(overrides), cfg.ContentTypesFile)
+	}
+
+	// Load response header rewrite rules if provided
+	if cfg.ResponseHeadersFile != "" {
+		rules, err := loadHeaderRulesFile(cfg.ResponseHeadersFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load response headers file: %v", err)
+		}
+		httpClient.headerRules = rules
+		logger.Printf("Loaded %d response header rule(s) from: %s", len(rules), cfg.ResponseHeadersFile)
+	}
+
+	// Load JSON body redaction rules if provided
+	if cfg.BodyRulesFile != "" {
+		rules, err := loadBodyRulesFile(cfg.BodyRulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load body rules file: %v", err)
+		}
+		httpClient.bodyRules = rules
+		logger.Printf("Loaded %d body rule(s) from: %s", len(rules), cfg.BodyRulesFile)
+	}
+
+	// Load default outbound request headers if provided
+	if cfg.DefaultHeadersFile != "" {
+		headers, err := loadDefaultHeadersFile(cfg.DefaultHeadersFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default headers file: %v", err)
+		}
+		httpClient.defaultHeaders = headers
+		logger.Printf("Loaded %d default request header(s) from: %s", len(headers), cfg.DefaultHeadersFile)
+	}
+
+	// Load exec allowlist if provided
+	var execAllowlist []execAllowRule
+	if cfg.ExecAllowlistFile != "" {
+		rules, err := loadExecAllowlistFile(cfg.ExecAllowlistFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load exec allowlist file: %v", err)
+		}
+		execAllowlist = rules
+		logger.Printf("Loaded %d exec allowlist rule(s) from: %s", len(rules), cfg.ExecAllowlistFile)
+	}
+
+	// Load pass-through content type rules if provided
+	var passThroughTypes map[string]bool
+	if cfg.PassThroughTypesFile != "" {
+		rules, err := loadPassThroughTypesFile(cfg.PassThroughTypesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pass-through types file: %v", err)
+		}
+		passThroughTypes = rules
+		logger.Printf("Loaded %d pass-through content type rule(s) from: %s", len(rules), cfg.PassThroughTypesFile)
+	}
+
+	// Load file MIME type overrides if provided
+	var mimeTypes map[string]string
+	if cfg.MimeTypesFile != "" {
+		types, err := loadMimeTypesFile(cfg.MimeTypesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MIME types file: %v", err)
+		}
+		mimeTypes = types
+		logger.Printf("Loaded %d MIME type override(s) from: %s", len(types), cfg.MimeTypesFile)
+	}
+
+	// Load the allowed upstream methods policy if provided
+	var allowedMethods methodPolicy
+	if cfg.AllowedMethodsFile != "" {
+		policy, err := loadAllowedMethodsFile(cfg.AllowedMethodsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load allowed methods file: %v", err)
+		}
+		allowedMethods = policy
+		logger.Printf("Loaded %d allowed methods rule(s) from: %s", len(policy), cfg.AllowedMethodsFile)
+	}
+
+	notFoundFormat := cfg.NotFoundFormat
+	switch notFoundFormat {
+	case "":
+		notFoundFormat = NotFoundFormatJSON
+	case NotFoundFormatJSON, NotFoundFormatText:
+	default:
+		return nil, fmt.Errorf("invalid not-found format %q (expected %s or %s)", notFoundFormat, NotFoundFormatJSON, NotFoundFormatText)
+	}
+	if notFoundFormat == NotFoundFormatJSON && cfg.NotFoundBody != "" && !json.Valid([]byte(cfg.NotFoundBody)) {
+		return nil, fmt.Errorf("invalid not-found body: must be valid JSON in %s format", NotFoundFormatJSON)
+	}
+
+	trailingSlash := cfg.TrailingSlash
+	switch trailingSlash {
+	case "":
+		trailingSlash = TrailingSlashStrict
+	case TrailingSlashStrict, TrailingSlashAccept, TrailingSlashRedirect:
+	default:
+		return nil, fmt.Errorf("invalid trailing slash mode %q (expected %s, %s or %s)", trailingSlash,
+			TrailingSlashStrict, TrailingSlashAccept, TrailingSlashRedirect)
+	}
+
+	// "/slingshot/" and "slingshot" both mount at "/slingshot"; "/" is the same as no base path
+	basePath := strings.TrimRight(cfg.BasePath, "/")
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	if strings.ContainsAny(basePath, "?#{}") {
+		return nil, fmt.Errorf("invalid base path %q: must be a plain URL path", cfg.BasePath)
+	}
+
+	if cfg.ExecRoot != "" {
+		info, err := os.Stat(cfg.ExecRoot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exec root: %v", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("invalid exec root: %s is not a directory", cfg.ExecRoot)
+		}
+	}
+
+	if cfg.FileRoot != "" {
+		info, err := os.Stat(cfg.FileRoot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file root: %v", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("invalid file root: %s is not a directory", cfg.FileRoot)
+		}
+	}
+
+	if err := httpClient.setAllowedSchemes(cfg.AllowedSchemes); err != nil {
+		return nil, fmt.Errorf("invalid allowed schemes: %v", err)
+	}
+	if err := httpClient.setAllowedPorts(cfg.AllowedPorts); err != nil {
+		return nil, fmt.Errorf("invalid allowed ports: %v", err)
+	}
+
+	proxyProtocolTrusted, err := parseProxyProtocolTrusted(cfg.ProxyProtocolTrusted)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY protocol trusted peers: %v", err)
+	}
+
+	httpClient.streamMaxDuration = cfg.StreamMaxDuration
+	httpClient.maxResponseBytes = cfg.MaxResponseBytes
+	httpClient.setTCPKeepAlive(cfg.TCPKeepAlive)
+	if err := httpClient.setSourceAddress(cfg.SourceAddress); err != nil {
+		return nil, fmt.Errorf("invalid source address: %v", err)
+	}
+	httpClient.setResponseHeaderTimeout(cfg.ResponseHeaderTimeout)
+	if cfg.TCPFastOpen && !fastOpenSupported {
+		logger.Printf("TCP Fast Open is not supported on this platform; upstream connections open normally")
+	}
+	httpClient.setSocketOptions(socketOptions{
+		disableNoDelay: cfg.DisableTCPNoDelay,
+		fastOpen:       cfg.TCPFastOpen && fastOpenSupported,
+	})
+	if cfg.MaxRequestsPerHost > 0 {
+		httpClient.hostLimiter = newHostLimiter(cfg.MaxRequestsPerHost)
+	}
+	if cfg.CoalesceRequests {
+		httpClient.coalescer = newRequestCoalescer()
+	}
+	if cfg.ResponseCacheTTL > 0 {
+		httpClient.cache = newResponseCache(cfg.ResponseCacheTTL)
+	}
+
+	blocked := &blockCounters{}
+	httpClient.blocked = blocked
+	if cfg.BlockPrivateNetworks {
+		httpClient.blockPrivateNetworks()
+	}
+
+	if cfg.EnableBodyCapture {
+		httpClient.bodyCapture = newBodyCapture(cfg.BodyCaptureDir, cfg.BodyCaptureTTL, logger)
+	}
+
+	var readyCheck *readyChecker
+	if cfg.ReadyCheckURL != "" {
+		if err := httpClient.validateURL(cfg.ReadyCheckURL); err != nil {
+			return nil, fmt.Errorf("invalid ready check URL %q: %v", cfg.ReadyCheckURL, err)
+		}
+		timeout := cfg.ReadyCheckTimeout
+		if timeout <= 0 {
+			timeout = DefaultReadyCheckTimeout
+		}
+		readyCheck = newReadyChecker(cfg.ReadyCheckURL, timeout, cfg.ReadyCheckMaxBytes)
 	}
 
 	return &Server{
-		port:             port,
-		httpClient:       NewHTTPClient(version, enableLogging),
+		port:             cfg.Port,
+		httpClient:       httpClient,
 		logger:           logger,
 		blockedHostnames: blockedHostnames,
-		version:          version,
-		enableLocalFiles: enableLocalFiles,
-		enableExec:       enableExec,
+		healthCheckHosts: healthCheckHosts,
+		version:          cfg.Version,
+		buildTime:        cfg.BuildTime,
+		gitCommit:        cfg.GitCommit,
+		enableLocalFiles: cfg.EnableLocalFiles,
+		enableExec:       cfg.EnableExec,
+		maxPathParams:    cfg.MaxPathParams,
+		maxURLLength:     cfg.MaxURLLength,
+		maxHeaderValue:   cfg.MaxHeaderValueLength,
+		maxHeaderBytes:   cfg.MaxHeaderBytes,
+		maxHeaderCount:   cfg.MaxHeaderCount,
+		execAllowlist:    execAllowlist,
+		execInheritEnv:   cfg.ExecInheritEnv,
+		execRoot:         cfg.ExecRoot,
+		execMaxOutput:    cfg.ExecMaxOutput,
+		fileRoot:         cfg.FileRoot,
+
+		defaultPassThrough: cfg.DefaultPassThrough,
+		passThroughTypes:   passThroughTypes,
+		notFoundFormat:     notFoundFormat,
+		trailingSlash:      trailingSlash,
+		notFoundBody:       cfg.NotFoundBody,
+		blocked:            blocked,
+
+		mimeTypes: mimeTypes,
+
+		maxDirEntries: cfg.MaxDirEntries,
+
+		disableLoopDetection: cfg.DisableLoopDetection,
+		basePath:             basePath,
+
+		readyCheck: readyCheck,
+
+		decompressRequestBodies: cfg.DecompressRequestBodies,
+		enablePprof:             cfg.EnablePprof,
+
+		proxyProtocol:        cfg.ProxyProtocol,
+		proxyProtocolTrusted: proxyProtocolTrusted,
+
+		errorStatusDefault:  cfg.ErrorStatusCodes,
+		mirrorStatusDefault: cfg.MirrorStatus,
+
+		noColor: cfg.NoColor,
+
+		methodPolicy: allowedMethods,
+
+		streams: &streamLimiter{limit: int64(cfg.MaxStreamingConnections)},
+
+		execs: &streamLimiter{limit: int64(cfg.ExecMaxConcurrent)},
+
+		active: newActiveRequests(),
+
+		targets: newTargetBalancer(),
+
+		formForwardHeaders: formForwardHeaders,
 	}, nil
 }
 
@@ -109,6 +460,240 @@ func loadBlacklistFile(filename string) ([]string, error) {
 	return hostnames, nil
 }
 
+// loadContentTypesFile reads a content type classification file
+// Format: one MIME type prefix per line followed by a colon and "binary" or "text"
+// Example:
+//
+//	application/wasm: binary
+//	application/x-ndjson: text
+//	# This is a comment
+func loadContentTypesFile(filename string) (map[string]bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.LastIndex(line, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("line %d: expected \"<mime prefix>: binary|text\"", i+1)
+		}
+
+		prefix := strings.ToLower(strings.TrimSpace(line[:idx]))
+		class := strings.ToLower(strings.TrimSpace(line[idx+1:]))
+		if prefix == "" {
+			return nil, fmt.Errorf("line %d: empty MIME prefix", i+1)
+		}
+
+		switch class {
+		case "binary":
+			overrides[prefix] = true
+		case "text":
+			overrides[prefix] = false
+		default:
+			return nil, fmt.Errorf("line %d: classification must be \"binary\" or \"text\", got %q", i+1, class)
+		}
+	}
+
+	return overrides, nil
+}
+
+// loadPassThroughTypesFile reads the content types allowed or denied in pass-through mode
+// Format: one MIME type prefix per line followed by a colon and "allow" or "deny";
+// "*" matches every content type
+// Example:
+//
+//	text/html: allow
+//	application/xml: deny
+//	# This is a comment
+func loadPassThroughTypesFile(filename string) (map[string]bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.LastIndex(line, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("line %d: expected \"<mime prefix>: allow|deny\"", i+1)
+		}
+
+		prefix := strings.ToLower(strings.TrimSpace(line[:idx]))
+		action := strings.ToLower(strings.TrimSpace(line[idx+1:]))
+		if prefix == "" {
+			return nil, fmt.Errorf("line %d: empty MIME prefix", i+1)
+		}
+		if prefix == "*" {
+			prefix = ""
+		}
+
+		switch action {
+		case "allow":
+			rules[prefix] = true
+		case "deny":
+			rules[prefix] = false
+		default:
+			return nil, fmt.Errorf("line %d: action must be \"allow\" or \"deny\", got %q", i+1, action)
+		}
+	}
+
+	return rules, nil
+}
+
+// loadMimeTypesFile reads the Content-Type overrides of /file by file extension
+// Format: one extension per line (leading dot optional) followed by a colon and a MIME type
+// Example:
+//
+//	.md: text/markdown; charset=utf-8
+//	webmanifest: application/manifest+json
+//	# This is a comment
+func loadMimeTypesFile(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The MIME type may contain colons in parameters, the extension can't
+		idx := strings.Index(line, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("line %d: expected \"<extension>: <mime type>\"", i+1)
+		}
+
+		ext := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(line[:idx]), "."))
+		mimeType := strings.TrimSpace(line[idx+1:])
+		if ext == "" || strings.ContainsAny(ext, "./ \t") {
+			return nil, fmt.Errorf("line %d: invalid file extension %q", i+1, strings.TrimSpace(line[:idx]))
+		}
+		if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+			return nil, fmt.Errorf("line %d: invalid MIME type %q: %v", i+1, mimeType, err)
+		}
+		types["."+ext] = mimeType
+	}
+
+	return types, nil
+}
+
+// loadHeaderRulesFile reads a response header rewrite rules file
+// Format: one rule per line, applied in order
+// Example:
+//
+//	remove X-Frame-Options
+//	set X-Proxied-By: rb-slingshot
+//	# This is a comment
+func loadHeaderRulesFile(filename string) ([]HeaderRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []HeaderRule
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		op := strings.ToLower(parts[0])
+		arg := ""
+		if len(parts) == 2 {
+			arg = strings.TrimSpace(parts[1])
+		}
+
+		switch op {
+		case HeaderRuleRemove:
+			if arg == "" {
+				return nil, fmt.Errorf("line %d: remove requires a header name", i+1)
+			}
+			rules = append(rules, HeaderRule{Op: HeaderRuleRemove, Name: arg})
+		case HeaderRuleSet:
+			nameValue := strings.SplitN(arg, ":", 2)
+			if len(nameValue) != 2 || strings.TrimSpace(nameValue[0]) == "" {
+				return nil, fmt.Errorf("line %d: set requires \"Name: value\"", i+1)
+			}
+			rules = append(rules, HeaderRule{
+				Op:    HeaderRuleSet,
+				Name:  strings.TrimSpace(nameValue[0]),
+				Value: strings.TrimSpace(nameValue[1]),
+			})
+		default:
+			return nil, fmt.Errorf("line %d: unknown operation %q (expected remove or set)", i+1, parts[0])
+		}
+	}
+
+	return rules, nil
+}
+
+// loadExecAllowlistFile reads the list of commands permitted via /exec
+// Format: one command per line (bare name or absolute path), optionally followed by
+// whitespace and a regular expression the space-joined arguments must fully match
+// Example:
+//
+//	ls
+//	/usr/bin/git ^(status|log|diff)( .*)?$
+//	# This is a comment
+func loadExecAllowlistFile(filename string) ([]execAllowRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []execAllowRule{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The command ends at the first space or tab; the rest is the argument pattern
+		rule := execAllowRule{command: line}
+		argsPattern := ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			rule.command, argsPattern = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if argsPattern != "" {
+			pattern, err := regexp.Compile("^(?:" + argsPattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid argument pattern: %v", i+1, err)
+			}
+			rule.args = pattern
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	router := mux.NewRouter()
@@ -119,18 +704,52 @@ func (s *Server) Start() error {
 	// Request logging middleware
 	router.Use(s.loggingMiddleware)
 
+	// Decode compressed client request bodies
+	if s.decompressRequestBodies {
+		router.Use(s.decompressRequestMiddleware)
+	}
+
+	// Mount every route under the base path, if any; the bare prefix serves the root endpoint too
+	routes := router
+	if s.basePath != "" {
+		router.HandleFunc(s.basePath, s.handleRoot).Methods("GET", "OPTIONS")
+		routes = router.PathPrefix(s.basePath).Subrouter()
+	}
+
 	// Root endpoint
-	router.HandleFunc("/", s.handleRoot).Methods("GET", "OPTIONS")
+	routes.HandleFunc("/", s.handleRoot).Methods("GET", "OPTIONS")
 
 	// API endpoints
-	router.HandleFunc("/proxy/request", s.handleJSONRequest).Methods("POST", "OPTIONS")
-	router.HandleFunc("/proxy/form", s.handleFormRequest).Methods("POST", "OPTIONS")
-	router.HandleFunc("/file", s.handleFileRequest).Methods("POST", "OPTIONS")
-	router.HandleFunc("/dir", s.handleDirectoryRequest).Methods("POST", "OPTIONS")
-	router.HandleFunc("/exec", s.handleExecRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/proxy/request", s.handleJSONRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/proxy/form", s.handleFormRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/proxy/batch", s.handleBatchRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/proxy/validate", s.handleValidateRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/proxy/preflight", s.handlePreflightRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/proxy/cancel", s.handleCancelRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/file", s.handleFileRequest).Methods("POST", "HEAD", "OPTIONS")
+	routes.HandleFunc("/file/batch", s.handleFileBatchRequest).Methods("POST", "OPTIONS")
+	routes.HandleFunc("/dir", s.handleDirectoryRequest).Methods("POST", "HEAD", "OPTIONS")
+	routes.HandleFunc("/exec", s.handleExecRequest).Methods("POST", "OPTIONS")
 
 	// Health check endpoint
-	router.HandleFunc("/health", s.handleHealthCheck).Methods("GET", "OPTIONS")
+	routes.HandleFunc("/health", s.handleHealthCheck).Methods("GET", "OPTIONS")
+
+	// Readiness endpoint, with an optional upstream connectivity check
+	routes.HandleFunc("/ready", s.handleReady).Methods("GET", "OPTIONS")
+
+	// Build metadata endpoint
+	routes.HandleFunc("/version", s.handleVersion).Methods("GET", "OPTIONS")
+
+	// Runtime metrics endpoint
+	routes.HandleFunc("/metrics", s.handleMetrics).Methods("GET", "OPTIONS")
+
+	// Catalog of the error types responses can report
+	routes.HandleFunc("/errors", s.handleErrorCatalog).Methods("GET", "OPTIONS")
+
+	// Profiling endpoints, only when enabled
+	if s.enablePprof {
+		routes.PathPrefix(pprofPrefix).HandlerFunc(s.handlePprof).Methods("GET", "POST")
+	}
 
 	// Custom 404 handler
 	router.NotFoundHandler = http.HandlerFunc(s.handleNotFound)
@@ -140,10 +759,25 @@ func (s *Server) Start() error {
 
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: router,
+		Handler: s.trailingSlashHandler(router),
+	}
+
+	if !s.proxyProtocol {
+		return s.server.ListenAndServe()
+	}
+
+	// Behind an L4 load balancer, RemoteAddr (and so logging and the localhost checks) must be
+	// the client address from the PROXY header, not the balancer's
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
 	}
+	return s.server.Serve(&proxyProtocolListener{Listener: listener, trusted: s.proxyProtocolTrusted})
+}
 
-	return s.server.ListenAndServe()
+// BasePath returns the normalized prefix all routes are mounted under (empty = root)
+func (s *Server) BasePath() string {
+	return s.basePath
 }
 
 // Stop stops the HTTP server gracefully
@@ -162,18 +796,35 @@ func (s *Server) isLoopbackRequest(targetURL string) bool {
 		return false // Invalid URL, let validation handle it
 	}
 
-	// Allow /health and / endpoints on any hostname (required for proxy health checks and welcome page)
-	if parsedURL.Path == "/health" || parsedURL.Path == "/" {
-		return false
-	}
-
 	// Extract hostname (ignore port)
 	targetHost := parsedURL.Hostname()
 
+	// Allow /health and / endpoints (required for proxy health checks and welcome page), including
+	// under this proxy's base path, on any hostname unless --health-check-hosts restricts them
+	switch parsedURL.Path {
+	case "/health", "/", s.basePath + "/health", s.basePath + "/":
+		if s.isHealthCheckHost(targetHost) {
+			return false
+		}
+	}
+
 	// Check if target hostname is in our blocked list
 	return s.isBlockedHostname(targetHost)
 }
 
+// isHealthCheckHost reports whether hostname's /health and / are exempt from hostname blocking
+func (s *Server) isHealthCheckHost(hostname string) bool {
+	if s.healthCheckHosts == nil {
+		return true
+	}
+	for _, host := range s.healthCheckHosts {
+		if strings.EqualFold(hostname, host) {
+			return true
+		}
+	}
+	return false
+}
+
 // isBlockedHostname checks if a hostname is in the blocked list
 func (s *Server) isBlockedHostname(hostname string) bool {
 	// Check against the configurable blocked hostnames list
@@ -217,20 +868,314 @@ func (s *Server) isProxyUserAgent(r *http.Request) bool {
 // 1. User-Agent detection (prevents any proxy instance from calling another)
 // 2. Hostname blocking (prevents targeting known production domains)
 func (s *Server) detectLoop(r *http.Request, targetURL string) bool {
-	// Strategy 1: Check incoming User-Agent header
-	if s.isProxyUserAgent(r) {
-		s.logger.Printf("BLOCKED loop: rb-slingshot User-Agent detected from %s targeting %s",
+	switch s.loopRule(r, targetURL) {
+	case RuleLoopDetected:
+		atomic.AddInt64(&s.blocked.loopDetected, 1)
+		s.logger.Printf("BLOCKED loop_detected: rb-slingshot User-Agent detected from %s targeting %s",
 			r.RemoteAddr, targetURL)
 		return true
+
+	case RuleHostnameBlocked:
+		atomic.AddInt64(&s.blocked.hostnameBlocked, 1)
+		s.logger.Printf("BLOCKED hostname_blocked: hostname blocking prevented request to: %s", targetURL)
+		return true
+	}
+
+	return false
+}
+
+// loopRule returns the loop detection rule that blocks targetURL, or "" if none does
+// It neither counts nor logs, so /proxy/validate can use it for dry runs.
+func (s *Server) loopRule(r *http.Request, targetURL string) string {
+	if s.disableLoopDetection {
+		return ""
+	}
+
+	// Strategy 1: Check incoming User-Agent header
+	if s.isProxyUserAgent(r) {
+		return RuleLoopDetected
 	}
 
 	// Strategy 2: Check target URL hostname
 	if s.isLoopbackRequest(targetURL) {
-		s.logger.Printf("BLOCKED loop: hostname blocking prevented request to: %s", targetURL)
-		return true
+		return RuleHostnameBlocked
 	}
 
-	return false
+	return ""
+}
+
+// streamedRequestHeader carries the ProxyRequest JSON when the /proxy/request body is the upstream body
+const streamedRequestHeader = "X-Slingshot-Request"
+
+// streamRequestBody makes the client's request body the upstream body of req, streamed without buffering
+// Features that need the whole body up front can't be combined with it.
+func streamRequestBody(r *http.Request, req *ProxyRequest) *requestError {
+	if req.Body != "" {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Body",
+			fmt.Sprintf("body must be empty when the request is described in %s; the request body is sent upstream", streamedRequestHeader)}
+	}
+	if req.Signing != nil || req.CaptureRequestBody {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Streamed Body Not Supported",
+			"signing and captureRequestBody need the whole body and can't be used with a streamed body"}
+	}
+
+	req.bodyStream = r.Body
+	req.bodyLength = r.ContentLength // -1 for a chunked client body, which is then sent upstream chunked
+	return nil
+}
+
+// requestError describes why a proxy request was rejected before execution
+type requestError struct {
+	status  int
+	errType string
+	title   string
+	message string
+}
+
+// validateProxyRequest validates a proxy request and prepares it for execution
+// Defaults are applied and path parameters substituted in place.
+func (s *Server) validateProxyRequest(r *http.Request, req *ProxyRequest) *requestError {
+	// Validate required fields
+	if req.Method == "" {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Missing Method", "HTTP method is required"}
+	}
+
+	if len(req.Targets) > 0 {
+		if reqErr := s.validateTargets(req); reqErr != nil {
+			return reqErr
+		}
+	} else if req.URL == "" {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Missing URL", "URL is required"}
+	}
+
+	if len(req.RequestID) > maxRequestIDLength {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Request ID",
+			fmt.Sprintf("requestId must not be longer than %d characters", maxRequestIDLength)}
+	}
+
+	// Set default timeout if not provided
+	if req.Timeout == 0 {
+		req.Timeout = 60 // default 60 seconds
+	}
+
+	if req.ConnectTimeout < 0 || req.TLSTimeout < 0 || req.ResponseHeaderTimeout < 0 {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Phase Timeout",
+			"connectTimeout, tlsTimeout and responseHeaderTimeout must not be negative"}
+	}
+
+	if req.H2C {
+		switch {
+		case !h2cSupported:
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Protocol Option",
+				"h2c requires the proxy to be built with Go 1.24 or later"}
+		case !allHavePrefix(req.targetURLs(), "http://"):
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Protocol Option",
+				"h2c requires an http URL (use https for HTTP/2 over TLS)"}
+		case req.HTTP10 || req.OrderedHeaders:
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Protocol Option",
+				"h2c can't be combined with http10 or orderedHeaders"}
+		}
+	}
+
+	if req.ServerTiming && req.Streaming {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Server Timing",
+			"serverTiming can't be combined with streaming"}
+	}
+
+	if req.NormalizeText != "" {
+		if req.NormalizeText != TextNormalizeStripBOM && req.NormalizeText != TextNormalizeLineEndings {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Text Normalization",
+				fmt.Sprintf("Unknown normalizeText %q (expected %q or %q)", req.NormalizeText, TextNormalizeStripBOM, TextNormalizeLineEndings)}
+		}
+		if req.Streaming {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Text Normalization",
+				"normalizeText can't be combined with streaming"}
+		}
+	}
+
+	if req.RedirectMode != "" {
+		if req.RedirectMode != RedirectModeReturnLocation {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Redirect Mode",
+				fmt.Sprintf("Unknown redirectMode %q (expected %q)", req.RedirectMode, RedirectModeReturnLocation)}
+		}
+		if req.Streaming || (req.FollowRedirects != nil && *req.FollowRedirects) {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Redirect Options",
+				"redirectMode return-location can't be combined with streaming or followRedirects: true"}
+		}
+	}
+
+	if req.DiscardBody && (req.Streaming || req.CaptureResponseBody) {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Response Options",
+			"discardBody can't be combined with streaming or captureResponseBody"}
+	}
+
+	if req.MultipartResponse && req.Streaming {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Response Options",
+			"multipartResponse can't be combined with streaming"}
+	}
+
+	if req.MaxStreamBytes < 0 {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Stream Limit",
+			"maxStreamBytes must not be negative"}
+	}
+
+	if req.PinnedCertSHA256 != "" {
+		if !allHavePrefix(req.targetURLs(), "https://") {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Certificate Pin",
+				"pinnedCertSHA256 requires an https URL"}
+		}
+		pin, err := parseCertPin(req.PinnedCertSHA256)
+		if err != nil {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Certificate Pin", err.Error()}
+		}
+		req.certPin = pin
+	}
+
+	if !isRequestEncoding(req.RequestEncoding) {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Request Encoding",
+			fmt.Sprintf("Unknown requestEncoding %q (expected \"gzip\" or \"deflate\")", req.RequestEncoding)}
+	}
+
+	// Substitute path parameters if provided
+	if req.PathParams != nil {
+		if s.maxPathParams > 0 && len(req.PathParams) > s.maxPathParams {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Too Many Path Parameters",
+				fmt.Sprintf("Request has %d path parameters, maximum is %d", len(req.PathParams), s.maxPathParams)}
+		}
+
+		if req.PathParamsJoin != "" && req.PathParamsJoin != PathParamJoinComma && req.PathParamsJoin != PathParamJoinSegment {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Path Parameter Join",
+				fmt.Sprintf("path_params_join must be %q or %q", PathParamJoinComma, PathParamJoinSegment)}
+		}
+
+		req.URL = s.httpClient.SubstitutePathParams(req.URL, req.PathParams, req.PathParamsJoin)
+		for i, target := range req.targetOrder {
+			req.targetOrder[i] = s.httpClient.SubstitutePathParams(target, req.PathParams, req.PathParamsJoin)
+		}
+
+		for _, target := range req.targetURLs() {
+			if s.maxURLLength > 0 && len(target) > s.maxURLLength {
+				return &requestError{http.StatusBadRequest, "request_format_error", "URL Too Long",
+					fmt.Sprintf("URL is %d characters after path parameter substitution, maximum is %d", len(target), s.maxURLLength)}
+			}
+		}
+	}
+
+	// Placeholders without a value would otherwise be sent to the upstream verbatim
+	if req.PathParamsStrict {
+		for _, target := range req.targetURLs() {
+			if missing := unresolvedPathParams(target); len(missing) > 0 {
+				return &requestError{http.StatusBadRequest, "request_format_error", "Missing Path Parameters",
+					fmt.Sprintf("URL still contains placeholders with no value in path_params: %s", strings.Join(missing, ", "))}
+			}
+		}
+	}
+
+	// Build a multipart body before anything (encoding, signing, coalescing) looks at it
+	if len(req.Multipart) > 0 {
+		if req.Body != "" || req.bodyStream != nil || req.BodyFromPath != "" || req.BodyParams != nil {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Body",
+				"multipart can't be combined with body, body_params, bodyFromPath or a streamed body"}
+		}
+		if hasHeader(req.Headers, "Content-Type") {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Body",
+				"multipart sets the Content-Type header itself, including the boundary"}
+		}
+		body, contentType, err := buildMultipartBody(req.Multipart)
+		if err != nil {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Multipart Part", err.Error()}
+		}
+		req.Body = body
+		req.Headers = append(req.Headers, "Content-Type: "+contentType)
+	}
+
+	// Template the body before anything (encoding, signing, coalescing) looks at it
+	if req.BodyParams != nil {
+		if !isBodyParamsEscape(req.BodyParamsEscape) {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Body Parameter Escaping",
+				fmt.Sprintf("body_params_escape must be %q, %q or %q", BodyParamsEscapeJSON, BodyParamsEscapeURL, BodyParamsEscapeRaw)}
+		}
+		if req.bodyStream != nil || req.BodyFromPath != "" {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Body",
+				"body_params can only be substituted into body, not into a streamed or bodyFromPath body"}
+		}
+		req.Body = substituteBodyParams(req.Body, req.BodyParams, req.BodyParamsEscape)
+	}
+
+	if reqErr := s.validateHeaders(req.Headers); reqErr != nil {
+		return reqErr
+	}
+
+	// Body capture touches disk, so it must be explicitly enabled and requested from localhost
+	if req.CaptureRequestBody || req.CaptureResponseBody {
+		if s.httpClient.bodyCapture == nil {
+			return &requestError{http.StatusForbidden, FeatureDisabledError.Type, FeatureDisabledError.Title,
+				"Body capture is disabled. Enable with --enable-body-capture flag."}
+		}
+		if !s.isLocalhostRequest(r) {
+			return &requestError{http.StatusForbidden, LocalhostOnlyError.Type, LocalhostOnlyError.Title,
+				"Body capture is only accepted from localhost (127.0.0.1)"}
+		}
+		if req.CaptureResponseBody && (req.PassThrough || req.Streaming) {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Capture Options",
+				"captureResponseBody cannot be combined with passThrough or streaming"}
+		}
+	}
+
+	// Local files are only read once the feature, the caller and the path have been checked
+	if req.BodyFromPath != "" {
+		if reqErr := s.prepareBodyFile(r, req); reqErr != nil {
+			return reqErr
+		}
+	}
+
+	// Validate signing configuration before doing any work
+	if req.Signing != nil {
+		if err := req.Signing.Validate(); err != nil {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Signing Config", err.Error()}
+		}
+	}
+
+	// Check for self-loop AFTER path parameter substitution
+	for _, target := range req.targetURLs() {
+		if s.detectLoop(r, target) {
+			return &requestError{http.StatusLoopDetected, LoopDetectedError.Type, LoopDetectedError.Title,
+				"Request could create an infinite loop to this proxy server"}
+		}
+	}
+
+	return nil
+}
+
+// validateHeaders enforces the outbound header count, value and total size limits
+// Sizes are counted as the headers are sent: "Name: value\r\n" per header.
+func (s *Server) validateHeaders(headers []string) *requestError {
+	// Checked up front so an oversized array isn't walked at all
+	if s.maxHeaderCount > 0 && len(headers) > s.maxHeaderCount {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Too Many Headers",
+			fmt.Sprintf("Request has %d headers, maximum is %d", len(headers), s.maxHeaderCount)}
+	}
+
+	total := 0
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		if s.maxHeaderValue > 0 && len(value) > s.maxHeaderValue {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Header Too Long",
+				fmt.Sprintf("Value of header %s is %d bytes, maximum is %d", name, len(value), s.maxHeaderValue)}
+		}
+		total += len(name) + len(value) + 4
+	}
+
+	if s.maxHeaderBytes > 0 && total > s.maxHeaderBytes {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Headers Too Large",
+			fmt.Sprintf("Request headers are %d bytes, maximum is %d", total, s.maxHeaderBytes)}
+	}
+	return nil
 }
 
 // handleJSONRequest handles /proxy/request endpoint
@@ -241,45 +1186,61 @@ func (s *Server) handleJSONRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w = withPrettyJSON(w, r)
 	w.Header().Set("Content-Type", "application/json")
 
-	// Parse request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
-		return
+	// With the request description in a header, the body is the upstream body itself and is
+	// streamed through as it arrives rather than read here
+	descriptor := r.Header.Get(streamedRequestHeader)
+	var body []byte
+	if descriptor != "" {
+		body = []byte(descriptor)
+	} else {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
+			return
+		}
 	}
 
-	var req ProxyRequest
+	// Fields omitted from the JSON keep their preset value, so this only applies the
+	// server default when passThrough isn't specified
+	req := ProxyRequest{PassThrough: s.defaultPassThrough}
 	if err := json.Unmarshal(body, &req); err != nil {
 		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
 		return
 	}
 
-	// Validate required fields
-	if req.Method == "" {
-		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing Method", "HTTP method is required")
-		return
+	// The multipart body part replaces the raw pass-through body, also over --default-pass-through;
+	// a discarded body leaves nothing to pass through
+	if req.MultipartResponse || req.DiscardBody {
+		req.PassThrough = false
 	}
 
-	if req.URL == "" {
-		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing URL", "URL is required")
-		return
+	if descriptor != "" {
+		if reqErr := streamRequestBody(r, &req); reqErr != nil {
+			s.writeErrorResponse(w, reqErr.status, reqErr.errType, reqErr.title, reqErr.message)
+			return
+		}
 	}
 
-	// Set default timeout if not provided
-	if req.Timeout == 0 {
-		req.Timeout = 60 // default 60 seconds
+	// Forward an SSE reconnection's Last-Event-ID so the upstream can resume the stream; added
+	// before validation so the header limits count it
+	if req.Streaming {
+		if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" && !hasHeader(req.Headers, "Last-Event-ID") {
+			req.Headers = append(req.Headers, "Last-Event-ID: "+lastEventID)
+		}
 	}
 
-	// Substitute path parameters if provided
-	if req.PathParams != nil {
-		req.URL = s.httpClient.SubstitutePathParams(req.URL, req.PathParams)
+	// Validate the request, applying defaults and path parameters
+	if reqErr := s.validateProxyRequest(r, &req); reqErr != nil {
+		s.writeErrorResponse(w, reqErr.status, reqErr.errType, reqErr.title, reqErr.message)
+		return
 	}
 
-	// Check for self-loop AFTER path parameter substitution
-	if s.detectLoop(r, req.URL) {
-		s.writeLoopErrorResponse(w, "Request could create an infinite loop to this proxy server")
+	if reqErr := s.checkMethodPolicy("/proxy/request", req.Method); reqErr != nil {
+		s.writeErrorResponse(w, reqErr.status, reqErr.errType, reqErr.title, reqErr.message)
 		return
 	}
 
@@ -287,12 +1248,31 @@ func (s *Server) handleJSONRequest(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.Timeout)*time.Second)
 	defer cancel()
 
+	// Let /proxy/cancel abort the request by its requestId
+	untrack, reqErr := s.trackRequest(&req, cancel)
+	if reqErr != nil {
+		s.writeErrorResponse(w, reqErr.status, reqErr.errType, reqErr.title, reqErr.message)
+		return
+	}
+	defer untrack()
+
+	req.errorStatus = s.errorStatusCodes(req.ErrorStatusCodes)
+
 	// Log the request
 	s.logger.Printf("%s %s", req.Method, req.URL)
 
 	// Check if streaming is requested
 	if req.Streaming {
+		if !s.streams.acquire() {
+			s.logger.Printf("Streaming request rejected: %d streams already active", s.streams.Active())
+			s.writeErrorResponse(w, http.StatusServiceUnavailable, StreamingLimitError.Type, StreamingLimitError.Title,
+				fmt.Sprintf("The proxy is already serving the maximum of %d concurrent streaming requests. Try again later.", s.streams.limit))
+			return
+		}
+		defer s.streams.release()
+
 		s.logger.Printf("Streaming mode enabled for request")
+
 		// Execute the streaming request
 		if err := s.httpClient.ExecuteStreamingRequest(ctx, &req, w); err != nil {
 			s.logger.Printf("Streaming request failed: %v", err)
@@ -308,22 +1288,47 @@ func (s *Server) handleJSONRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Execute the standard request
-	response, err := s.httpClient.ExecuteRequest(ctx, &req)
+	if req.ServerTiming {
+		req.timings = newPhaseTimings()
+	}
+	response, err := s.executeTargets(ctx, &req)
 	if err != nil {
 		s.logger.Printf("Request failed: %v", err)
 		s.writeErrorResponse(w, http.StatusInternalServerError, "unknown_error", "Request Failed", err.Error())
 		return
 	}
 
+	// Expose the measured phases to browser dev tools, cross-origin too
+	if req.timings != nil {
+		w.Header().Set("Server-Timing", req.timings.header())
+		w.Header().Set("Timing-Allow-Origin", "*")
+	}
+
+	// Report cache use in a header too, since pass-through responses have no JSON to carry it
+	if s.httpClient.cache != nil && canCoalesce(&req) {
+		if response.FromCache {
+			w.Header().Set("X-Cache", "HIT")
+		} else {
+			w.Header().Set("X-Cache", "MISS")
+		}
+	}
+
 	// Handle pass-through mode
 	if req.PassThrough && response.Success {
 		// Remove the application/json content-type that was set earlier
 		w.Header().Del("Content-Type")
 
-		// Set content-type header to match the proxied response
+		// Set content-type header to match the proxied response, unless it could render as
+		// active content; then it is served as plain text with the original type kept aside
 		if response.ContentType != "" {
-			w.Header().Set("Content-Type", response.ContentType)
+			if s.passThroughAllowed(response.ContentType) {
+				w.Header().Set("Content-Type", response.ContentType)
+			} else {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.Header().Set("X-Slingshot-Original-Content-Type", response.ContentType)
+			}
 		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
 
 		// Write raw response body directly
 		if _, err := w.Write(response.RawResponseBody); err != nil {
@@ -332,12 +1337,56 @@ func (s *Server) handleJSONRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Multipart mode - metadata and raw body as separate parts; responses without a body stay JSON
+	if req.MultipartResponse && hasInlineBody(response) {
+		s.writeMultipartResponse(w, response)
+		return
+	}
+
 	// Normal mode - write JSON response (Content-Type already set to application/json)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if !response.Success && req.errorStatus {
+		w.WriteHeader(upstreamErrorStatus(response.ErrorType))
+	} else if response.Success && s.mirrorStatus(req.MirrorStatus) {
+		w.WriteHeader(mirroredStatus(response.ResponseStatus))
+	}
+	if err := jsonEncoder(w).Encode(response); err != nil {
 		s.logger.Printf("Failed to encode response: %v", err)
 	}
 }
 
+// passThroughAllowed reports whether a content type may be forwarded as-is in pass-through mode
+// Configured rules win over the built-in deny list; the longest matching prefix is used
+func (s *Server) passThroughAllowed(contentType string) bool {
+	contentTypeLower := strings.ToLower(strings.TrimSpace(contentType))
+
+	matched, found := "", false
+	for prefix := range s.passThroughTypes {
+		if strings.HasPrefix(contentTypeLower, prefix) && (!found || len(prefix) > len(matched)) {
+			matched, found = prefix, true
+		}
+	}
+	if found {
+		return s.passThroughTypes[matched]
+	}
+
+	for _, denied := range passThroughDeniedTypes {
+		if strings.HasPrefix(contentTypeLower, denied) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasHeader reports whether a "Key: Value" header list contains the named header
+func hasHeader(headers []string, name string) bool {
+	for _, header := range headers {
+		if parts := strings.SplitN(header, ":", 2); len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), name) {
+			return true
+		}
+	}
+	return false
+}
+
 // handleFormRequest handles /proxy/form endpoint
 func (s *Server) handleFormRequest(w http.ResponseWriter, r *http.Request) {
 	// Handle OPTIONS for CORS preflight
@@ -372,23 +1421,62 @@ func (s *Server) handleFormRequest(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse errorStatusCodes
+	if errorStatusStr := query.Get("errorStatusCodes"); errorStatusStr != "" {
+		if errorStatus, err := strconv.ParseBool(errorStatusStr); err == nil {
+			formReq.ErrorStatusCodes = &errorStatus
+		}
+	}
+
+	// Parse mirrorStatus
+	if mirrorStatusStr := query.Get("mirrorStatus"); mirrorStatusStr != "" {
+		if mirrorStatus, err := strconv.ParseBool(mirrorStatusStr); err == nil {
+			formReq.MirrorStatus = &mirrorStatus
+		}
+	}
+
 	// Validate required fields
 	if formReq.URL == "" {
 		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing URL", "URL is required")
 		return
 	}
 
+	// Headers are passed as a comma-separated "Name: value" list; allowlisted client headers
+	// are added unless the list sets them
+	explicitHeaders := strings.Split(formReq.Headers, ",")
+	formReq.ForwardedHeaders = s.forwardedFormHeaders(r, explicitHeaders)
+	if reqErr := s.validateHeaders(append(explicitHeaders, formReq.ForwardedHeaders...)); reqErr != nil {
+		s.writeErrorResponse(w, reqErr.status, reqErr.errType, reqErr.title, reqErr.message)
+		return
+	}
+
 	// Check for self-loop before processing
 	if s.detectLoop(r, formReq.URL) {
 		s.writeLoopErrorResponse(w, "Request could create an infinite loop to this proxy server")
 		return
 	}
 
+	// An override wins over the method param: header first, then the _method query param
+	if override := methodOverride(r); override != "" {
+		method := strings.ToUpper(override)
+		if !overridableMethods[method] {
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid Method Override",
+				fmt.Sprintf("Method override %q is not a supported HTTP method", override))
+			return
+		}
+		formReq.Method = method
+	}
+
 	// Default method to POST
 	if formReq.Method == "" {
 		formReq.Method = "POST"
 	}
 
+	if reqErr := s.checkMethodPolicy("/proxy/form", formReq.Method); reqErr != nil {
+		s.writeErrorResponse(w, reqErr.status, reqErr.errType, reqErr.title, reqErr.message)
+		return
+	}
+
 	// Set default timeout
 	if formReq.Timeout == 0 {
 		formReq.Timeout = 60
@@ -441,11 +1529,41 @@ func (s *Server) handleFormRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Write response
+	if !response.Success && s.errorStatusCodes(formReq.ErrorStatusCodes) {
+		w.WriteHeader(upstreamErrorStatus(response.ErrorType))
+	} else if response.Success && s.mirrorStatus(formReq.MirrorStatus) {
+		w.WriteHeader(mirroredStatus(response.ResponseStatus))
+	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		s.logger.Printf("Failed to encode response: %v", err)
 	}
 }
 
+// Method override for clients that can only send GET/POST to /proxy/form
+const (
+	methodOverrideHeader = "X-HTTP-Method-Override"
+	methodOverrideParam  = "_method"
+)
+
+// overridableMethods are the methods a method override may select
+var overridableMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"PATCH":   true,
+	"DELETE":  true,
+	"OPTIONS": true,
+}
+
+// methodOverride returns the requested override method, or "" if none was given
+func methodOverride(r *http.Request) string {
+	if override := strings.TrimSpace(r.Header.Get(methodOverrideHeader)); override != "" {
+		return override
+	}
+	return strings.TrimSpace(r.URL.Query().Get(methodOverrideParam))
+}
+
 // handleRoot handles the root endpoint with ASCII art
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 	// Handle OPTIONS for CORS preflight
@@ -454,15 +1572,25 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userAgent := r.Header.Get("User-Agent")
-	useColors := strings.Contains(userAgent, "rb-slingshot")
-
-	welcomeMsg := s.generateWelcomeMessage(useColors)
+	welcomeMsg := s.generateWelcomeMessage(s.welcomeColors(r))
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, welcomeMsg)
 }
 
+// welcomeColors reports whether the welcome message is sent with color codes
+// A valid ?color=true/false wins; otherwise NO_COLOR turns colors off, and without it only
+// rb-slingshot clients get them.
+func (s *Server) welcomeColors(r *http.Request) bool {
+	if color, err := strconv.ParseBool(r.URL.Query().Get("color")); err == nil {
+		return color
+	}
+	if s.noColor {
+		return false
+	}
+	return strings.Contains(r.Header.Get("User-Agent"), "rb-slingshot")
+}
+
 // generateWelcomeMessage creates the welcome ASCII art with optional color codes
 func (s *Server) generateWelcomeMessage(useColors bool) string {
 	var asciiArt string
@@ -487,17 +1615,30 @@ func (s *Server) generateWelcomeMessage(useColors bool) string {
 		"Learn more about the project at:\n" +
 		" - https://github.com/requestbite/proxy\n\n" +
 		"Endpoints:\n" +
-		" - POST /proxy/request - Make HTTP requests via JSON\n" +
-		" - POST /proxy/form    - Make HTTP requests via form data\n" +
-		" - GET  /health        - Health check endpoint"
+		" - POST " + s.basePath + "/proxy/request - Make HTTP requests via JSON\n" +
+		" - POST " + s.basePath + "/proxy/form    - Make HTTP requests via form data\n" +
+		" - POST " + s.basePath + "/proxy/batch   - Make several HTTP requests in one call\n" +
+		" - POST " + s.basePath + "/proxy/validate - Check a URL against the proxy's policies\n" +
+		" - POST " + s.basePath + "/proxy/preflight - Send a CORS preflight to the upstream and check its answer\n" +
+		" - POST " + s.basePath + "/proxy/cancel  - Cancel an in-flight request by its requestId\n" +
+		" - GET  " + s.basePath + "/health        - Health check endpoint\n" +
+		" - GET  " + s.basePath + "/ready         - Readiness, including the upstream check if configured\n" +
+		" - GET  " + s.basePath + "/version       - Build metadata\n" +
+		" - GET  " + s.basePath + "/metrics       - Runtime metrics\n" +
+		" - GET  " + s.basePath + "/errors        - Catalog of error types"
 
 	if s.enableLocalFiles {
-		desc += "\n - POST /file          - Serve local files (localhost only)\n" +
-			" - POST /dir           - List directory contents (localhost only)"
+		desc += "\n - POST " + s.basePath + "/file          - Serve local files (localhost only, HEAD ?path= for metadata)\n" +
+			" - POST " + s.basePath + "/file/batch    - Read several local files within a size budget (localhost only)\n" +
+			" - POST " + s.basePath + "/dir           - List directory contents (localhost only, HEAD ?path= to probe)"
 	}
 
 	if s.enableExec {
-		desc += "\n - POST /exec          - Execute processes (localhost only)"
+		desc += "\n - POST " + s.basePath + "/exec          - Execute processes (localhost only)"
+	}
+
+	if s.enablePprof {
+		desc += "\n - GET  " + s.basePath + "/debug/pprof/  - Go profiling endpoints (localhost only)"
 	}
 
 	return fmt.Sprintf("Welcome to version %s of:\n\n%s\n\n%s\n", s.version, asciiArt, desc)
@@ -530,13 +1671,90 @@ func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 		healthResponse["enableExec"] = true
 	}
 
+	healthResponse["activeStreams"] = s.streams.Active()
+
 	json.NewEncoder(w).Encode(healthResponse)
 }
 
+// handleVersion returns the build metadata of the running binary
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	versionResponse := map[string]interface{}{
+		"version":   s.version,
+		"buildTime": s.buildTime,
+		"gitCommit": s.gitCommit,
+		"goVersion": runtime.Version(),
+	}
+
+	json.NewEncoder(w).Encode(versionResponse)
+}
+
+// handleMetrics returns runtime metrics as JSON
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// In-flight upstream requests per host (only tracked when a per-host limit is set)
+	inFlight := map[string]int{}
+	if s.httpClient.hostLimiter != nil {
+		inFlight = s.httpClient.hostLimiter.InFlight()
+	}
+
+	// Requests that shared another request's upstream call (only when coalescing is enabled)
+	var coalesced int64
+	if s.httpClient.coalescer != nil {
+		coalesced = s.httpClient.coalescer.Coalesced()
+	}
+
+	metricsResponse := map[string]interface{}{
+		"upstreamInFlight":  inFlight,
+		"coalescedRequests": coalesced,
+		"blockedRequests":   s.blocked.Snapshot(),
+		"activeStreams":     s.streams.Active(),
+		"maxStreams":        s.streams.limit,
+		"activeExecs":       s.execs.Active(),
+		"maxExecs":          s.execs.limit,
+	}
+
+	json.NewEncoder(w).Encode(metricsResponse)
+}
+
 // handleNotFound handles requests to undefined endpoints
 func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	if s.notFoundFormat == NotFoundFormatText {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		body := s.notFoundBody
+		if body == "" {
+			body = fmt.Sprintf("Endpoint not found: %s\n", r.URL.Path)
+		}
+		io.WriteString(w, body)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
+	// Custom JSON body, validated at startup
+	if s.notFoundBody != "" {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, s.notFoundBody)
+		return
+	}
+
 	response := &ProxyResponse{
 		Success:      false,
 		ErrorType:    EndpointNotFoundError.Type,
@@ -557,8 +1775,8 @@ func (s *Server) handleMethodNotAllowed(w http.ResponseWriter, r *http.Request)
 
 	response := &ProxyResponse{
 		Success:      false,
-		ErrorType:    "method_not_allowed",
-		ErrorTitle:   "Method Not Allowed",
+		ErrorType:    MethodNotAllowedError.Type,
+		ErrorTitle:   MethodNotAllowedError.Title,
 		ErrorMessage: fmt.Sprintf("Method %s is not allowed for endpoint %s", r.Method, r.URL.Path),
 		Cancelled:    false,
 	}
@@ -574,8 +1792,8 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		w.Header().Set("Access-Control-Expose-Headers", "X-Slingshot-Streaming")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Last-Event-ID, X-HTTP-Method-Override, Range, If-Range, X-Slingshot-Request, Content-Encoding")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Slingshot-Streaming, X-Slingshot-Original-Content-Type, ETag, Content-Range, Accept-Ranges, X-Cache, Server-Timing")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		next.ServeHTTP(w, r)
@@ -626,7 +1844,7 @@ func (s *Server) writeErrorResponse(w http.ResponseWriter, statusCode int, error
 	}
 
 	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := jsonEncoder(w).Encode(response); err != nil {
 		s.logger.Printf("Failed to encode error response: %v", err)
 	}
 }
@@ -642,7 +1860,7 @@ func (s *Server) writeLoopErrorResponse(w http.ResponseWriter, errorMessage stri
 	}
 
 	w.WriteHeader(http.StatusLoopDetected) // HTTP 508 status for loops
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := jsonEncoder(w).Encode(response); err != nil {
 		s.logger.Printf("Failed to encode loop error response: %v", err)
 	}
 }
@@ -673,19 +1891,24 @@ func (s *Server) handleFileRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
-		return
-	}
-
+	// HEAD has no body, so the path comes from the query string
 	var req FileRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
-		return
+	if r.Method == "HEAD" {
+		req.Path = r.URL.Query().Get("path")
+	} else {
+		// Parse request body
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
+			return
+		}
+
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
+			return
+		}
 	}
 
 	// Validate required fields
@@ -694,12 +1917,31 @@ func (s *Server) handleFileRequest(w http.ResponseWriter, r *http.Request) {
 		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing path", "File path is required")
 		return
 	}
+	if req.FollowSeconds < 0 {
+		w.Header().Set("Content-Type", "application/json")
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid Follow Duration", "followSeconds must not be negative")
+		return
+	}
 
 	// Clean and validate the path
 	cleanPath := filepath.Clean(req.Path)
 
-	// Security check: Ensure path is absolute
-	if !filepath.IsAbs(cleanPath) {
+	// Security check: with --file-root the path must stay inside it (relative paths resolve
+	// there), otherwise it must be absolute. This also confines follow mode.
+	if s.fileRoot != "" {
+		confined, err := confinePath(s.fileRoot, cleanPath)
+		if os.IsNotExist(err) {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusNotFound, FileNotFoundError.Type, FileNotFoundError.Title, fmt.Sprintf("File not found: %s", cleanPath))
+			return
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusForbidden, FileAccessError.Type, FileAccessError.Title, err.Error())
+			return
+		}
+		cleanPath = confined
+	} else if !filepath.IsAbs(cleanPath) {
 		w.Header().Set("Content-Type", "application/json")
 		s.writeErrorResponse(w, http.StatusBadRequest, FileAccessError.Type, FileAccessError.Title, "Path must be absolute")
 		return
@@ -727,32 +1969,86 @@ func (s *Server) handleFileRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Read the file
-	fileData, err := os.ReadFile(cleanPath)
+	// Open the file
+	file, err := os.Open(cleanPath)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		s.writeErrorResponse(w, http.StatusInternalServerError, FileAccessError.Type, FileAccessError.Title, fmt.Sprintf("Failed to read file: %v", err))
 		return
 	}
+	defer file.Close()
+
+	// Stat the open file so the validators describe exactly what is served
+	if openInfo, err := file.Stat(); err == nil {
+		fileInfo = openInfo
+	}
+
+	// Detect MIME type from the extension, or from the leading bytes
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(file, sniff)
+	mimeType := s.detectMimeType(cleanPath, sniff[:n])
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		s.writeErrorResponse(w, http.StatusInternalServerError, FileAccessError.Type, FileAccessError.Title, fmt.Sprintf("Failed to read file: %v", err))
+		return
+	}
 
-	// Detect MIME type
-	mimeType := s.detectMimeType(cleanPath, fileData)
+	// Follow mode streams the file as it grows instead of serving a snapshot
+	if req.Follow && r.Method != "HEAD" {
+		s.serveFollowedFile(w, r, file, cleanPath, mimeType, req.FollowSeconds)
+		return
+	}
 
 	// Set the appropriate Content-Type header
 	w.Header().Set("Content-Type", mimeType)
 
-	// Write the file content directly (pass-through mode)
-	if _, err := w.Write(fileData); err != nil {
-		s.logger.Printf("Failed to write file response: %v", err)
+	// A strong validator lets clients resume downloads with Range and If-Range
+	w.Header().Set("ETag", fileETag(fileInfo))
+
+	// ServeContent only evaluates If-Range for GET and HEAD, so it is resolved here for this
+	// POST endpoint: a stale validator means the whole file must be sent instead of the range
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" {
+		r = r.Clone(r.Context())
+		if !ifRangeMatches(ifRange, fileETag(fileInfo), fileInfo.ModTime()) {
+			r.Header.Del("Range")
+		}
+		r.Header.Del("If-Range")
+	}
+
+	// Write the file content directly (pass-through mode); ServeContent handles Range
+	// and the other conditional request headers
+	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
+
+	s.logger.Printf("Served file: %s (%d bytes, %s)", cleanPath, fileInfo.Size(), mimeType)
+}
+
+// ifRangeMatches reports whether an If-Range validator (an ETag or an HTTP date) still
+// describes the file. ETags use strong comparison, so weak ETags never match.
+func ifRangeMatches(ifRange, etag string, modTime time.Time) bool {
+	if strings.HasPrefix(ifRange, "\"") {
+		return ifRange == etag
 	}
+	if strings.HasPrefix(ifRange, "W/") {
+		return false
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && t.Unix() == modTime.Unix()
+}
 
-	s.logger.Printf("Served file: %s (%d bytes, %s)", cleanPath, len(fileData), mimeType)
+// fileETag returns a strong ETag for a file, derived from its modification time and size
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf("\"%x-%x\"", info.ModTime().UnixNano(), info.Size())
 }
 
 // detectMimeType detects the MIME type of a file based on extension and content
 func (s *Server) detectMimeType(filePath string, data []byte) string {
-	// First try to detect by file extension
+	// Configured overrides win over everything else
 	ext := strings.ToLower(filepath.Ext(filePath))
+	if mimeType, ok := s.mimeTypes[ext]; ok {
+		return mimeType
+	}
+
+	// Then try to detect by file extension
 	mimeType := mime.TypeByExtension(ext)
 
 	if mimeType != "" {
@@ -815,6 +2111,15 @@ func sortDirectoryEntries(entries []DirectoryEntry) {
 	})
 }
 
+// pathErrorReason returns the cause of a file system error without the path it carries
+func pathErrorReason(err error) string {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
 // handleDirectoryRequest handles /dir endpoint for directory listing
 func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request) {
 	// Handle OPTIONS for CORS preflight
@@ -823,6 +2128,8 @@ func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	w = withPrettyJSON(w, r)
+
 	// Check if feature is enabled
 	if !s.enableLocalFiles {
 		w.Header().Set("Content-Type", "application/json")
@@ -841,19 +2148,26 @@ func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Parse request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
-		return
-	}
-
+	// HEAD has no body, so the path comes from the query string
 	var req DirectoryRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
-		return
+	if r.Method == "HEAD" {
+		if path := r.URL.Query().Get("path"); path != "" {
+			req.Path = &path
+		}
+	} else {
+		// Parse request body
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
+			return
+		}
+
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
+			return
+		}
 	}
 
 	// Determine whether to show hidden files (defaults to false)
@@ -864,18 +2178,35 @@ func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request)
 
 	// Determine target path
 	var targetPath string
-	if req.Path == nil {
+	if req.Path != nil {
+		targetPath = *req.Path
+	} else if s.fileRoot != "" {
+		targetPath = s.fileRoot
+	} else {
 		// Use platform-specific root
 		targetPath = s.getDefaultRoot()
-	} else {
-		targetPath = *req.Path
 	}
 
 	// Clean the path
 	cleanPath := filepath.Clean(targetPath)
 
-	// Security check: Ensure path is absolute (unless it's the root)
-	if !filepath.IsAbs(cleanPath) {
+	// Security check: with --file-root the path must stay inside it, as for /file (relative
+	// paths resolve there), otherwise it must be absolute. Listings, HEAD probes and
+	// directory size walks all start from the confined path.
+	if s.fileRoot != "" {
+		confined, err := confinePath(s.fileRoot, cleanPath)
+		if os.IsNotExist(err) {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusNotFound, FileNotFoundError.Type, FileNotFoundError.Title, fmt.Sprintf("Directory not found: %s", cleanPath))
+			return
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			s.writeErrorResponse(w, http.StatusForbidden, FileAccessError.Type, FileAccessError.Title, err.Error())
+			return
+		}
+		cleanPath = confined
+	} else if !filepath.IsAbs(cleanPath) {
 		w.Header().Set("Content-Type", "application/json")
 		s.writeErrorResponse(w, http.StatusBadRequest, FileAccessError.Type, FileAccessError.Title, "Path must be absolute")
 		return
@@ -903,16 +2234,31 @@ func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// HEAD only probes that the directory exists; skip reading it
+	if r.Method == "HEAD" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Last-Modified", fileInfo.ModTime().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
 	// Read directory contents
-	entries, err := os.ReadDir(cleanPath)
+	entries, truncated, err := readDirEntries(cleanPath, s.maxDirEntries)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		s.writeErrorResponse(w, http.StatusInternalServerError, FileAccessError.Type, FileAccessError.Title, fmt.Sprintf("Failed to read directory: %v", err))
 		return
 	}
 
-	// Build response array
+	// Directory sizes are opt-in: each one is a recursive walk, bounded per request
+	var sizeWalker *dirSizeWalker
+	if req.IncludeDirSizes {
+		sizeWalker = newDirSizeWalker()
+	}
+
+	// Build response array; unreadable entries are reported apart so the listing stays clean
 	var dirEntries []DirectoryEntry
+	var entryErrors []DirectoryEntryError
 	for _, entry := range entries {
 		entryName := entry.Name()
 
@@ -927,8 +2273,9 @@ func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request)
 		// Use Lstat to detect symlinks (doesn't follow them)
 		lstatInfo, err := os.Lstat(entryPath)
 		if err != nil {
-			// Log but skip entries we can't access
+			// Log and report, but skip entries we can't access
 			s.logger.Printf("Warning: Cannot lstat entry %s: %v", entryPath, err)
+			entryErrors = append(entryErrors, DirectoryEntryError{Name: entryName, Path: entryPath, Error: pathErrorReason(err)})
 			continue
 		}
 
@@ -965,6 +2312,13 @@ func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request)
 			// Not a symlink - use standard type detection
 			if lstatInfo.IsDir() {
 				dirEntry.Type = "directory"
+				if sizeWalker != nil {
+					dirSize, complete := sizeWalker.size(entryPath, 1)
+					dirEntry.SizeBytes = &dirSize
+					humanSize := FormatFileSize(dirSize)
+					dirEntry.SizeHuman = &humanSize
+					dirEntry.SizeIncomplete = !complete
+				}
 			} else {
 				dirEntry.Type = "file"
 				// Add size information for files
@@ -990,11 +2344,13 @@ func (s *Server) handleDirectoryRequest(w http.ResponseWriter, r *http.Request)
 		ParentDir:  parentDir,
 		CurrentDir: cleanPath,
 		Dir:        dirEntries,
+		Truncated:  truncated,
+		Errors:     entryErrors,
 	}
 
 	// Return JSON response
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := jsonEncoder(w).Encode(response); err != nil {
 		s.logger.Printf("Failed to encode directory response: %v", err)
 	}
 
@@ -1054,8 +2410,50 @@ func (s *Server) handleExecRequest(w http.ResponseWriter, r *http.Request) {
 		req.Timeout = 20
 	}
 
+	// Decode stdin up front so malformed input is reported before anything runs
+	if req.Stdin != "" {
+		switch req.StdinEncoding {
+		case "", "text":
+			req.stdin = []byte(req.Stdin)
+		case "base64":
+			decoded, err := base64.StdEncoding.DecodeString(req.Stdin)
+			if err != nil {
+				s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid stdin", fmt.Sprintf("Failed to decode base64 stdin: %v", err))
+				return
+			}
+			req.stdin = decoded
+		default:
+			s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid stdin", fmt.Sprintf("Unknown stdinEncoding %q (expected \"text\" or \"base64\")", req.StdinEncoding))
+			return
+		}
+	}
+
+	// Enforce the exec allowlist before spawning anything
+	if !s.isExecAllowed(&req) {
+		s.logger.Printf("BLOCKED exec: command not in allowlist: %s %v", req.Command, req.Args)
+		s.writeErrorResponse(w, http.StatusForbidden, ExecFailedError.Type, ExecFailedError.Title,
+			fmt.Sprintf("Command is not allowed: %s", req.Command))
+		return
+	}
+
+	// Cap the processes running at once; the slot is held until the process has exited,
+	// including when it is killed for its timeout or output
+	if !s.execs.acquire() {
+		s.logger.Printf("Exec request rejected: %d processes already running", s.execs.Active())
+		s.writeErrorResponse(w, http.StatusServiceUnavailable, ExecFailedError.Type, ExecFailedError.Title,
+			fmt.Sprintf("The proxy is already running the maximum of %d concurrent exec processes. Try again later.", s.execs.limit))
+		return
+	}
+	defer s.execs.release()
+
 	s.logger.Printf("Exec request: %s %v (timeout: %ds)", req.Command, req.Args, req.Timeout)
 
+	// Stream output as it is produced if requested
+	if req.Streaming {
+		s.executeCommandStreaming(w, r, &req)
+		return
+	}
+
 	// Execute the command
 	response := s.executeCommand(&req)
 
@@ -1065,6 +2463,119 @@ func (s *Server) handleExecRequest(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// isExecAllowed checks a command and its arguments against the exec allowlist
+// Bare names only match the same bare name (resolved through PATH at execution time);
+// absolute paths match the path exec will actually run, see execPath
+func (s *Server) isExecAllowed(req *ExecRequest) bool {
+	if s.execAllowlist == nil {
+		return true
+	}
+
+	resolved := s.execPath(req)
+	joinedArgs := strings.Join(req.Args, " ")
+	for _, rule := range s.execAllowlist {
+		matches := false
+		if filepath.IsAbs(rule.command) {
+			matches = resolved != "" && filepath.Clean(rule.command) == resolved
+		} else {
+			matches = req.Command == rule.command
+		}
+
+		if matches && (rule.args == nil || rule.args.MatchString(joinedArgs)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// execPath returns the absolute path of the binary exec will run for req, or "" if it can't tell
+// A command with a slash is used as given, so a relative one runs from the command's working
+// directory rather than the proxy's; bare names are looked up in the proxy's PATH.
+func (s *Server) execPath(req *ExecRequest) string {
+	if !strings.ContainsAny(req.Command, "/"+string(filepath.Separator)) {
+		path, err := exec.LookPath(req.Command)
+		if err != nil || !filepath.IsAbs(path) {
+			return ""
+		}
+		return filepath.Clean(path)
+	}
+
+	path := req.Command
+	if !filepath.IsAbs(path) {
+		workingDir, err := s.workingDir(req)
+		if err != nil {
+			return ""
+		}
+		if workingDir == "" {
+			if workingDir, err = os.Getwd(); err != nil {
+				return ""
+			}
+		}
+		path = filepath.Join(workingDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// workingDir resolves the working directory of req, confining it to the exec root when configured
+// An empty result means the command runs in the proxy's own working directory.
+func (s *Server) workingDir(req *ExecRequest) (string, error) {
+	workingDir := req.WorkingDir
+	if s.execRoot != "" {
+		if workingDir == "" {
+			workingDir = s.execRoot
+		}
+		confined, err := confinePath(s.execRoot, workingDir)
+		if err != nil {
+			return "", fmt.Errorf("Working directory not allowed: %v", err)
+		}
+		workingDir = confined
+	}
+	if workingDir != "" {
+		info, err := os.Stat(workingDir)
+		if err != nil {
+			return "", fmt.Errorf("Invalid working directory: %v", err)
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("Working directory is not a directory: %s", workingDir)
+		}
+	}
+	return workingDir, nil
+}
+
+// prepareCommand applies the working directory and environment from the request to cmd
+func (s *Server) prepareCommand(cmd *exec.Cmd, req *ExecRequest) error {
+	workingDir, err := s.workingDir(req)
+	if err != nil {
+		return err
+	}
+	cmd.Dir = workingDir
+
+	// By default the child only sees the variables in the request; the proxy's own
+	// environment is passed on only when explicitly trusted
+	if s.execInheritEnv {
+		cmd.Env = os.Environ()
+	} else {
+		cmd.Env = []string{}
+	}
+
+	for key, value := range req.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return fmt.Errorf("Invalid environment variable name: %q", key)
+		}
+		if !s.execInheritEnv {
+			for _, prefix := range execDeniedEnvPrefixes {
+				if strings.HasPrefix(strings.ToUpper(key), prefix) {
+					return fmt.Errorf("Environment variable %s is not allowed. Enable with --exec-inherit-env flag.", key)
+				}
+			}
+		}
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return nil
+}
+
 // executeCommand executes a command and returns the response
 func (s *Server) executeCommand(req *ExecRequest) *ExecResponse {
 	startTime := time.Now()
@@ -1075,31 +2586,26 @@ func (s *Server) executeCommand(req *ExecRequest) *ExecResponse {
 
 	// Create command
 	cmd := exec.CommandContext(ctx, req.Command, req.Args...)
-
-	// Set working directory if provided
-	if req.WorkingDir != "" {
-		cmd.Dir = req.WorkingDir
-	}
-
-	// Set environment variables if provided
-	if req.Env != nil {
-		cmd.Env = os.Environ() // Start with current environment
-		for key, value := range req.Env {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	if err := s.prepareCommand(cmd, req); err != nil {
+		return &ExecResponse{
+			Success:       false,
+			ErrorType:     ExecFailedError.Type,
+			ErrorTitle:    ExecFailedError.Title,
+			ErrorMessage:  err.Error(),
+			ExecutionTime: fmt.Sprintf("%.2f ms", float64(time.Since(startTime).Nanoseconds())/1000000),
 		}
 	}
 
-	// Execute based on output mode
-	var stdout, stderr bytes.Buffer
-	var combinedOutput []byte
-	var err error
+	// Execute based on output mode, capping the captured output
+	limiter := newOutputLimiter(s.execMaxOutput, cancel)
+	stdout := &cappedBuffer{limiter: limiter}
+	stderr := &cappedBuffer{limiter: limiter}
 
+	var err error
 	if req.CombineOutput {
-		combinedOutput, err = cmd.CombinedOutput()
+		err = runCommand(ctx, cmd, req.stdin, stdout, stdout)
 	} else {
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-		err = cmd.Run()
+		err = runCommand(ctx, cmd, req.stdin, stdout, stderr)
 	}
 
 	executionTime := time.Since(startTime)
@@ -1107,9 +2613,25 @@ func (s *Server) executeCommand(req *ExecRequest) *ExecResponse {
 	// Build response
 	response := &ExecResponse{
 		ExecutionTime: fmt.Sprintf("%.2f ms", float64(executionTime.Nanoseconds())/1000000),
+		Truncated:     limiter.Truncated(),
 	}
 
 	// Check for errors
+	if limiter.Killed() {
+		response.Success = false
+		response.ErrorType = ExecFailedError.Type
+		response.ErrorTitle = ExecFailedError.Title
+		response.ErrorMessage = fmt.Sprintf("Command killed after producing more than %d bytes of output", s.execMaxOutput*execOutputKillFactor)
+		if req.CombineOutput {
+			response.CombinedOutput = stdout.String()
+		} else {
+			response.Stdout = stdout.String()
+			response.Stderr = stderr.String()
+		}
+		s.logger.Printf("Command killed for excessive output: %s", req.Command)
+		return response
+	}
+
 	if err != nil {
 		// Check if it was a timeout
 		if ctx.Err() == context.DeadlineExceeded {
@@ -1128,7 +2650,7 @@ func (s *Server) executeCommand(req *ExecRequest) *ExecResponse {
 
 			// Include output even on failure
 			if req.CombineOutput {
-				response.CombinedOutput = string(combinedOutput)
+				response.CombinedOutput = stdout.String()
 			} else {
 				response.Stdout = stdout.String()
 				response.Stderr = stderr.String()
@@ -1152,7 +2674,7 @@ func (s *Server) executeCommand(req *ExecRequest) *ExecResponse {
 	response.ExitCode = 0
 
 	if req.CombineOutput {
-		response.CombinedOutput = string(combinedOutput)
+		response.CombinedOutput = stdout.String()
 	} else {
 		response.Stdout = stdout.String()
 		response.Stderr = stderr.String()
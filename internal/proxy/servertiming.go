@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server-Timing metrics for responses that didn't come from the request's own upstream call
+const (
+	servedFromCache     = "cache;desc=hit" // Answered by the response cache
+	servedFromCoalesced = "coalesced"      // Answered by an identical request's upstream call
+)
+
+// phaseTimings records how long the phases of an upstream request took, for serverTiming
+// Each connection attempt starts over, so after redirects or failover the phases are those of
+// the last hop; a reused connection has no dns, connect or tls phase. The transport reports
+// events from its own goroutines, hence the lock.
+type phaseTimings struct {
+	start time.Time // When the proxy started handling the request; ttfb and total count from here
+
+	mu           sync.Mutex
+	servedFrom   string // servedFromCache or servedFromCoalesced; such responses have no phases of their own
+	dnsStart     time.Time
+	dns          time.Duration
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	tls          time.Duration
+	ttfb         time.Duration
+}
+
+// newPhaseTimings returns a recorder whose ttfb and total count from now
+func newPhaseTimings() *phaseTimings {
+	return &phaseTimings{start: time.Now()}
+}
+
+// trace returns a context that records the phases of the request in t
+func (t *phaseTimings) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.dns, t.connect, t.tls = 0, 0, 0
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.dns = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			t.connectStart = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			t.connect = time.Since(t.connectStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.tls = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.ttfb = time.Since(t.start)
+			t.mu.Unlock()
+		},
+	})
+}
+
+// served records that the response came from the cache or another request's upstream call
+func (t *phaseTimings) served(from string) {
+	t.mu.Lock()
+	t.servedFrom = from
+	t.mu.Unlock()
+}
+
+// copyPhases sets the phases of t to those recorded by from, keeping t's start and servedFrom
+func (t *phaseTimings) copyPhases(from *phaseTimings) {
+	from.mu.Lock()
+	dns, connect, tls, ttfb := from.dns, from.connect, from.tls, from.ttfb
+	from.mu.Unlock()
+
+	t.mu.Lock()
+	t.dns, t.connect, t.tls, t.ttfb = dns, connect, tls, ttfb
+	t.mu.Unlock()
+}
+
+// header returns the Server-Timing header value, e.g. "dns;dur=1.20, connect;dur=0.35, total;dur=12.50"
+// Phases that didn't happen are left out; total runs until now. A cached or coalesced response
+// leads with a metric saying so, e.g. "cache;desc=hit, total;dur=0.20".
+func (t *phaseTimings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phases := []struct {
+		name string
+		dur  time.Duration
+	}{
+		{"dns", t.dns},
+		{"connect", t.connect},
+		{"tls", t.tls},
+		{"ttfb", t.ttfb},
+		{"total", time.Since(t.start)},
+	}
+	metrics := make([]string, 0, len(phases)+1)
+	if t.servedFrom != "" {
+		metrics = append(metrics, t.servedFrom)
+	}
+	for _, phase := range phases {
+		if phase.dur > 0 {
+			metrics = append(metrics, fmt.Sprintf("%s;dur=%.2f", phase.name, float64(phase.dur.Nanoseconds())/1000000))
+		}
+	}
+	return strings.Join(metrics, ", ")
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Signing schemes supported for outbound HMAC request signing
+const (
+	SigningSchemeCanonical = "canonical" // HMAC over METHOD\nPATH?QUERY\nTIMESTAMP\nBODY, timestamp sent in its own header
+	SigningSchemeBody      = "body"      // HMAC over the raw body, sent as "sha256=<sig>" (webhook style)
+	SigningSchemeTimestamp = "timestamp" // HMAC over TIMESTAMP.BODY, sent as "t=<ts>,v1=<sig>" (Stripe style)
+)
+
+// Defaults for SigningConfig fields that are not provided
+const (
+	DefaultSignatureHeader = "X-Signature"
+	DefaultTimestampHeader = "X-Timestamp"
+)
+
+// SigningConfig describes how an outbound request should be HMAC-signed
+type SigningConfig struct {
+	Secret          string `json:"secret"`                    // Required HMAC-SHA256 key
+	Scheme          string `json:"scheme,omitempty"`          // "canonical" (default), "body" or "timestamp"
+	Header          string `json:"header,omitempty"`          // Header that receives the signature, default X-Signature
+	TimestampHeader string `json:"timestampHeader,omitempty"` // Header that receives the timestamp (canonical scheme only), default X-Timestamp
+	Encoding        string `json:"encoding,omitempty"`        // "hex" (default) or "base64"
+}
+
+// signingInput holds the parts of the final outbound request that may be signed
+type signingInput struct {
+	Method     string
+	RequestURI string // Path plus query of the final URL
+	Body       string
+	Timestamp  string // Unix seconds
+}
+
+// signingScheme computes the header values for a request given a MAC function
+// It returns the signature header value and, if the scheme needs it, a separate timestamp header value
+type signingScheme func(in signingInput, mac func(message string) string) (signature string, timestamp string)
+
+// signingSchemes is the registry of available schemes; add an entry here to support a new style
+var signingSchemes = map[string]signingScheme{
+	SigningSchemeCanonical: func(in signingInput, mac func(string) string) (string, string) {
+		return mac(in.Method + "\n" + in.RequestURI + "\n" + in.Timestamp + "\n" + in.Body), in.Timestamp
+	},
+	SigningSchemeBody: func(in signingInput, mac func(string) string) (string, string) {
+		return "sha256=" + mac(in.Body), ""
+	},
+	SigningSchemeTimestamp: func(in signingInput, mac func(string) string) (string, string) {
+		return fmt.Sprintf("t=%s,v1=%s", in.Timestamp, mac(in.Timestamp+"."+in.Body)), ""
+	},
+}
+
+// Validate checks that the signing configuration is usable
+func (cfg *SigningConfig) Validate() error {
+	if cfg.Secret == "" {
+		return fmt.Errorf("signing secret is required")
+	}
+	if _, ok := signingSchemes[cfg.scheme()]; !ok {
+		return fmt.Errorf("unknown signing scheme: %s", cfg.Scheme)
+	}
+	if cfg.Encoding != "" && cfg.Encoding != "hex" && cfg.Encoding != "base64" {
+		return fmt.Errorf("unknown signature encoding: %s", cfg.Encoding)
+	}
+	return nil
+}
+
+// scheme returns the configured scheme or the default
+func (cfg *SigningConfig) scheme() string {
+	if cfg.Scheme == "" {
+		return SigningSchemeCanonical
+	}
+	return cfg.Scheme
+}
+
+// signRequest computes the HMAC signature for the final request and injects the signature headers
+// The request URL and body must already be final (after path parameter substitution)
+func signRequest(httpReq *http.Request, body string, cfg *SigningConfig, now time.Time) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	mac := func(message string) string {
+		h := hmac.New(sha256.New, []byte(cfg.Secret))
+		h.Write([]byte(message))
+		sum := h.Sum(nil)
+		if cfg.Encoding == "base64" {
+			return base64.StdEncoding.EncodeToString(sum)
+		}
+		return hex.EncodeToString(sum)
+	}
+
+	in := signingInput{
+		Method:     httpReq.Method,
+		RequestURI: httpReq.URL.RequestURI(),
+		Body:       body,
+		Timestamp:  strconv.FormatInt(now.Unix(), 10),
+	}
+
+	signature, timestamp := signingSchemes[cfg.scheme()](in, mac)
+
+	header := cfg.Header
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+	httpReq.Header.Set(header, signature)
+
+	if timestamp != "" {
+		timestampHeader := cfg.TimestampHeader
+		if timestampHeader == "" {
+			timestampHeader = DefaultTimestampHeader
+		}
+		httpReq.Header.Set(timestampHeader, timestamp)
+	}
+
+	return nil
+}
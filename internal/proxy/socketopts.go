@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// socketOptions are the low-latency options of upstream TCP connections (--tcp-nodelay, --tcp-fast-open)
+// The zero value keeps Go's defaults: Nagle's algorithm disabled and no TCP Fast Open.
+type socketOptions struct {
+	disableNoDelay bool // Turn Nagle's algorithm back on, coalescing small writes
+	fastOpen       bool // Send the first request bytes with the SYN where the kernel supports it
+}
+
+// isSet reports whether any option differs from Go's defaults
+func (o socketOptions) isSet() bool {
+	return o.disableNoDelay || o.fastOpen
+}
+
+// dialContext returns a dial function of dialer that applies the options to each connection
+// The dialer is read on every dial, so settings changed on it later still apply. Options the
+// platform or kernel rejects are skipped and the connection is used as Go set it up.
+func (o socketOptions) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if !o.isSet() {
+		return dialer.DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		d := *dialer
+		if o.fastOpen {
+			d.Control = chainControl(dialer.Control, fastOpenControl)
+		}
+		conn, err := d.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok && o.disableNoDelay {
+			tcpConn.SetNoDelay(false)
+		}
+		return conn, nil
+	}
+}
+
+// chainControl returns a dialer Control function running first (if any) and then second
+func chainControl(first, second func(network, address string, conn syscall.RawConn) error) func(network, address string, conn syscall.RawConn) error {
+	if first == nil {
+		return second
+	}
+	return func(network, address string, conn syscall.RawConn) error {
+		if err := first(network, address, conn); err != nil {
+			return err
+		}
+		return second(network, address, conn)
+	}
+}
@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"bytes"
+	"strings"
+)
+
+// sseEventIDTracker follows an SSE stream and remembers the id of the last dispatched event
+// It is written to with the raw stream (e.g. via io.TeeReader), so lines may be split across writes.
+type sseEventIDTracker struct {
+	partial     []byte // Incomplete line carried over from the previous write
+	pendingID   string // id field of the event currently being received
+	hasPending  bool
+	lastEventID string // id of the last event dispatched (terminated by a blank line)
+}
+
+// Write consumes stream data; it never fails
+func (t *sseEventIDTracker) Write(p []byte) (int, error) {
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			t.partial = append(t.partial, data...)
+			return len(p), nil
+		}
+
+		line := data[:i]
+		if len(t.partial) > 0 {
+			line = append(t.partial, line...)
+			t.partial = t.partial[:0]
+		}
+		t.processLine(strings.TrimSuffix(string(line), "\r"))
+		data = data[i+1:]
+	}
+}
+
+// processLine handles one complete line of the event stream
+func (t *sseEventIDTracker) processLine(line string) {
+	if line == "" {
+		// Blank line dispatches the event
+		if t.hasPending {
+			t.lastEventID = t.pendingID
+			t.hasPending = false
+		}
+		return
+	}
+
+	field, value := line, ""
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+	}
+
+	// Per the SSE spec, ids containing NULL are ignored
+	if field == "id" && !strings.ContainsRune(value, 0) {
+		t.pendingID = value
+		t.hasPending = true
+	}
+}
+
+// LastEventID returns the id of the last dispatched event, if any
+func (t *sseEventIDTracker) LastEventID() string {
+	return t.lastEventID
+}
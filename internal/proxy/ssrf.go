@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"syscall"
+)
+
+// blockedAddressError reports an upstream address rejected by the private network guard
+type blockedAddressError struct {
+	ip net.IP
+}
+
+func (e *blockedAddressError) Error() string {
+	return fmt.Sprintf("connections to %s are not allowed", e.ip)
+}
+
+// isPrivateAddress reports whether ip is a loopback, private, link-local or unspecified address
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// privateNetworkControl is a net.Dialer Control function that refuses private addresses
+// It runs on the resolved address of every dial, so hostnames that resolve (or re-resolve,
+// as in DNS rebinding) to internal addresses are caught as well as literal IPs.
+func privateNetworkControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateAddress(ip) {
+		return &blockedAddressError{ip: ip}
+	}
+	return nil
+}
+
+// blockCounters counts requests rejected by loop detection, the hostname blacklist
+// and the private network guard; fields are updated atomically
+type blockCounters struct {
+	loopDetected    int64
+	hostnameBlocked int64
+	ssrfBlocked     int64
+}
+
+// Snapshot returns the current counts keyed by event name
+func (c *blockCounters) Snapshot() map[string]int64 {
+	return map[string]int64{
+		"loop_detected":    atomic.LoadInt64(&c.loopDetected),
+		"hostname_blocked": atomic.LoadInt64(&c.hostnameBlocked),
+		"ssrf_blocked":     atomic.LoadInt64(&c.ssrfBlocked),
+	}
+}
@@ -0,0 +1,30 @@
+package proxy
+
+import "sync/atomic"
+
+// streamLimiter counts the streaming requests in progress and caps them (--max-streaming-connections)
+// Unlike the per-host limit, excess streams are rejected instead of queued, since a
+// waiting stream would hold its client connection just as long.
+type streamLimiter struct {
+	limit  int64 // Maximum concurrent streams (0 = unlimited, only counted)
+	active int64 // Streams currently holding a slot, updated atomically
+}
+
+// acquire takes a slot, reporting false without one if the limit is reached
+func (l *streamLimiter) acquire() bool {
+	if atomic.AddInt64(&l.active, 1) > l.limit && l.limit > 0 {
+		atomic.AddInt64(&l.active, -1)
+		return false
+	}
+	return true
+}
+
+// release gives back a slot taken by acquire
+func (l *streamLimiter) release() {
+	atomic.AddInt64(&l.active, -1)
+}
+
+// Active returns the number of streaming requests in progress
+func (l *streamLimiter) Active() int64 {
+	return atomic.LoadInt64(&l.active)
+}
@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxTargets bounds the targets of a single request
+const maxTargets = 32
+
+// maxTargetSets bounds the target lists whose rotation is remembered; beyond it all rotations restart
+const maxTargetSets = 1024
+
+// RequestTarget is one of several equivalent upstream URLs of a request (ProxyRequest.Targets)
+type RequestTarget struct {
+	URL    string `json:"url"`
+	Weight *int   `json:"weight,omitempty"` // Share of the requests sent to this target (default 1, 0 = failover only)
+}
+
+// weight returns the target's weight, defaulting to 1
+func (t RequestTarget) weight() int {
+	if t.Weight == nil {
+		return 1
+	}
+	return *t.Weight
+}
+
+// targetBalancer rotates requests over target lists by weight
+// Each distinct list (URLs and weights) keeps its own position, so requests naming the
+// same targets share a rotation.
+type targetBalancer struct {
+	mu        sync.Mutex
+	positions map[string]uint64
+}
+
+func newTargetBalancer() *targetBalancer {
+	return &targetBalancer{positions: make(map[string]uint64)}
+}
+
+// order returns the target URLs in the order to try them: the one whose turn it is, then the
+// others in list order after it. Over total weight consecutive requests, each target comes first
+// as many times as its weight.
+func (b *targetBalancer) order(targets []RequestTarget) []string {
+	var key strings.Builder
+	total := 0
+	for _, target := range targets {
+		key.WriteString(strconv.Itoa(target.weight()))
+		key.WriteByte(' ')
+		key.WriteString(target.URL)
+		key.WriteByte('\n')
+		total += target.weight()
+	}
+
+	b.mu.Lock()
+	if _, ok := b.positions[key.String()]; !ok && len(b.positions) >= maxTargetSets {
+		b.positions = make(map[string]uint64)
+	}
+	position := b.positions[key.String()]
+	b.positions[key.String()] = position + 1
+	b.mu.Unlock()
+
+	// Find the target owning this slot of the weight cycle
+	slot := int(position % uint64(total))
+	first := 0
+	for i, target := range targets {
+		if slot < target.weight() {
+			first = i
+			break
+		}
+		slot -= target.weight()
+	}
+
+	order := make([]string, 0, len(targets))
+	for i := range targets {
+		order = append(order, targets[(first+i)%len(targets)].URL)
+	}
+	return order
+}
+
+// targetURLs returns every URL req may be sent to: all its targets, or just its URL
+func (req *ProxyRequest) targetURLs() []string {
+	if len(req.targetOrder) > 0 {
+		return req.targetOrder
+	}
+	return []string{req.URL}
+}
+
+// allHavePrefix reports whether every URL starts with prefix, ignoring case
+func allHavePrefix(urls []string, prefix string) bool {
+	for _, u := range urls {
+		if !strings.HasPrefix(strings.ToLower(u), prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateTargets checks the targets of req and picks the URL it is sent to
+// With failover the remaining targets follow it in req.targetOrder.
+func (s *Server) validateTargets(req *ProxyRequest) *requestError {
+	if req.URL != "" {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Target Options",
+			"url and targets can't be combined"}
+	}
+	if len(req.Targets) > maxTargets {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Too Many Targets",
+			fmt.Sprintf("Request has %d targets, maximum is %d", len(req.Targets), maxTargets)}
+	}
+
+	total := 0
+	for i, target := range req.Targets {
+		if target.URL == "" {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Target",
+				fmt.Sprintf("targets[%d] has no url", i)}
+		}
+		if target.weight() < 0 {
+			return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Target",
+				fmt.Sprintf("targets[%d] has a negative weight", i)}
+		}
+		total += target.weight()
+	}
+	if total == 0 {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Invalid Target",
+			"At least one target needs a weight above 0"}
+	}
+
+	// The body of a streamed request can only be sent once
+	if req.Failover && (req.Streaming || req.bodyStream != nil) {
+		return &requestError{http.StatusBadRequest, "request_format_error", "Conflicting Target Options",
+			"failover can't be combined with streaming or a streamed request body"}
+	}
+
+	req.targetOrder = s.targets.order(req.Targets)
+	req.URL = req.targetOrder[0]
+	if !req.Failover {
+		req.targetOrder = req.targetOrder[:1]
+	}
+	return nil
+}
+
+// failoverErrors are the failures after which failover tries the next target: ones where the
+// target couldn't be reached, so the request most likely never got there
+var failoverErrors = map[string]bool{
+	ConnectionError.Type:        true,
+	DNSError.Type:               true,
+	ConnectionRefusedError.Type: true,
+	ConnectionResetError.Type:   true,
+	TLSError.Type:               true,
+	ConnectTimeoutError.Type:    true,
+	TLSTimeoutError.Type:        true,
+}
+
+// executeTargets executes req, trying the next of its targets after a connection failure when
+// failover is set. The response names the target that answered and, on failover, the ones that failed.
+// All attempts share the request's timeout.
+func (s *Server) executeTargets(ctx context.Context, req *ProxyRequest) (*ProxyResponse, error) {
+	if len(req.targetOrder) == 0 {
+		return s.httpClient.ExecuteRequest(ctx, req)
+	}
+
+	var failed []string
+	for i, target := range req.targetOrder {
+		req.URL = target
+		response, err := s.httpClient.ExecuteRequest(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		last := i == len(req.targetOrder)-1
+		if response.Success || !req.Failover || last || !failoverErrors[response.ErrorType] || ctx.Err() != nil {
+			response.Target = target
+			response.FailedTargets = failed
+			return response, nil
+		}
+
+		s.logger.Printf("Target %s failed (%s), trying the next one", target, response.ErrorType)
+		failed = append(failed, target)
+	}
+	return nil, fmt.Errorf("no targets")
+}
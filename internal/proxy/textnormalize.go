@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// utf8BOM is the byte order mark some servers put in front of UTF-8 text
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// normalizeResponseText applies the normalizeText mode of a request to a text response body,
+// keeping Content-Length in step. Binary bodies are returned unchanged.
+func (c *HTTPClient) normalizeResponseText(resp *http.Response, body []byte, mode string) []byte {
+	if mode == "" || c.isBinaryContent(responseContentType(resp.Header, body)) {
+		return body
+	}
+	normalized := bytes.TrimPrefix(body, utf8BOM)
+	if mode == TextNormalizeLineEndings && bytes.Contains(normalized, []byte("\r\n")) {
+		normalized = bytes.ReplaceAll(normalized, []byte("\r\n"), []byte("\n"))
+	}
+	if len(normalized) != len(body) && resp.Header.Get("Content-Length") != "" {
+		resp.Header.Set("Content-Length", fmt.Sprintf("%d", len(normalized)))
+	}
+	return normalized
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// trailingSlashHandler applies s.trailingSlash to requests whose path only matches a route without
+// its trailing slashes. Paths that match as they are, like / or the pprof index, are left alone.
+// It wraps the router rather than being router middleware, since middleware only runs on matches.
+func (s *Server) trailingSlashHandler(router *mux.Router) http.Handler {
+	if s.trailingSlash == TrailingSlashStrict {
+		return router
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimRight(r.URL.Path, "/")
+		if path == "" || path == r.URL.Path || routeMatches(router, r) {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		trimmed := r.Clone(r.Context())
+		trimmed.URL.Path = path
+		trimmed.URL.RawPath = ""
+		if !routeMatches(router, trimmed) {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		if s.trailingSlash == TrailingSlashRedirect {
+			// 308 keeps the method and body, unlike the 301 of mux's StrictSlash
+			http.Redirect(w, r, trimmed.URL.RequestURI(), http.StatusPermanentRedirect)
+			return
+		}
+		router.ServeHTTP(w, trimmed)
+	})
+}
+
+// routeMatches reports whether r reaches a route of router, even with a method it doesn't allow
+func routeMatches(router *mux.Router, r *http.Request) bool {
+	var match mux.RouteMatch
+	return router.Match(r, &match) && match.MatchErr != mux.ErrNotFound
+}
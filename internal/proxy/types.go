@@ -1,21 +1,130 @@
 package proxy
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 )
 
 // ProxyRequest represents the JSON request structure matching the Lua API
 type ProxyRequest struct {
-	Method          string            `json:"method"`
-	URL             string            `json:"url"`
-	Headers         []string          `json:"headers"`
-	Body            string            `json:"body,omitempty"`
-	Timeout         int               `json:"timeout,omitempty"`
-	FollowRedirects *bool             `json:"followRedirects,omitempty"`
-	PathParams      map[string]string `json:"path_params,omitempty"`
-	PassThrough     bool              `json:"passThrough,omitempty"`
-	Streaming       bool              `json:"streaming,omitempty"`
+	Method          string                    `json:"method"`
+	URL             string                    `json:"url"`
+	Headers         []string                  `json:"headers"`
+	Body            string                    `json:"body,omitempty"`
+	Timeout         int                       `json:"timeout,omitempty"`
+	FollowRedirects *bool                     `json:"followRedirects,omitempty"`
+	RedirectMode    string                    `json:"redirectMode,omitempty"` // RedirectModeReturnLocation to return a 3xx as a successful response
+	PathParams      map[string]PathParamValue `json:"path_params,omitempty"`
+	PathParamsJoin  string                    `json:"path_params_join,omitempty"` // "comma" (default) or "segment" for list values
+	PassThrough     bool                      `json:"passThrough,omitempty"`
+	Streaming       bool                      `json:"streaming,omitempty"`
+	Signing         *SigningConfig            `json:"signing,omitempty"`         // Optional HMAC signing of the outbound request
+	HTTP10          bool                      `json:"http10,omitempty"`          // Send the request using HTTP/1.0
+	H2C             bool                      `json:"h2c,omitempty"`             // Send the request using cleartext HTTP/2 (http:// URLs only)
+	CloseConnection bool                      `json:"closeConnection,omitempty"` // Send Connection: close and don't reuse the connection
+
+	PartialOnTimeout   bool `json:"partialOnTimeout,omitempty"`   // On timeout, return the response bytes read so far instead of an error
+	ParseJSON          bool `json:"parseJSON,omitempty"`          // Return a JSON body as a nested value in response_json
+	PreserveHeaderCase bool `json:"preserveHeaderCase,omitempty"` // Send header names exactly as given (HTTP/1.x only)
+	IncludeResolvedIP  bool `json:"includeResolvedIP,omitempty"`  // Report the IP address the proxy connected to in resolved_ip
+	OmitUserAgent      bool `json:"omitUserAgent,omitempty"`      // Send no User-Agent at all, not even the default
+
+	IncludeInformational bool `json:"includeInformational,omitempty"` // Report 1xx responses (e.g. 103 Early Hints) received before the final one
+	OrderedHeaders       bool `json:"orderedHeaders,omitempty"`       // Also report the response headers in wire order in response_header_list
+
+	MultipartResponse bool `json:"multipartResponse,omitempty"` // Return the metadata JSON and the raw body as two multipart/mixed parts
+
+	IncludeHeaderDiff bool `json:"includeHeaderDiff,omitempty"` // Report headers added, removed or changed by the response in header_diff
+
+	DiscardBody bool `json:"discardBody,omitempty"` // Close the response body unread and report only the status and headers
+
+	NormalizeLength bool `json:"normalizeLength,omitempty"` // Report a Content-Length matching the decoded body instead of the upstream's framing
+
+	NormalizeText string `json:"normalizeText,omitempty"` // TextNormalizeStripBOM or TextNormalizeLineEndings to clean up text responses
+
+	ServerTiming bool `json:"serverTiming,omitempty"` // Report the upstream request's phases in a Server-Timing header of the proxy response
+
+	RequestID string `json:"requestId,omitempty"` // Client-chosen id that /proxy/cancel can cancel the request by while it runs
+
+	Targets  []RequestTarget `json:"targets,omitempty"`  // Equivalent upstream URLs to rotate over by weight, instead of url
+	Failover bool            `json:"failover,omitempty"` // Try the next target when one can't be reached
+
+	ErrorStatusCodes *bool `json:"errorStatusCodes,omitempty"` // Answer upstream failures with 502/504/4xx instead of 200 (default: --error-status-codes)
+	MirrorStatus     *bool `json:"mirrorStatus,omitempty"`     // Answer with the upstream's status instead of 200, keeping the JSON body (default: --mirror-status)
+
+	RequestEncoding string `json:"requestEncoding,omitempty"` // Compress the outbound body: "gzip" or "deflate" (sets Content-Encoding)
+
+	PathParamsStrict bool `json:"path_params_strict,omitempty"` // Reject URLs still containing :placeholders after substitution
+
+	BodyParams       map[string]string `json:"body_params,omitempty"`        // Values substituted for {{name}} placeholders in body
+	BodyParamsEscape string            `json:"body_params_escape,omitempty"` // "json" (default), "url" or "raw" escaping of body_params values
+
+	Multipart []MultipartPart `json:"multipart,omitempty"` // Parts to send as a multipart/form-data body instead of body
+
+	MaxStreamBytes int64 `json:"maxStreamBytes,omitempty"` // End a streaming response cleanly after this many bytes (0 = unlimited)
+
+	BodyFromPath string `json:"bodyFromPath,omitempty"` // Stream this local file as the body (requires --enable-local-files)
+
+	PinnedCertSHA256 string `json:"pinnedCertSHA256,omitempty"` // Abort unless the server's leaf certificate has this SHA-256 (hex)
+
+	// Per-phase timeouts in seconds, each within the overall timeout (0 = no separate limit)
+	ConnectTimeout        int `json:"connectTimeout,omitempty"`        // Establishing the TCP connection
+	TLSTimeout            int `json:"tlsTimeout,omitempty"`            // TLS handshake
+	ResponseHeaderTimeout int `json:"responseHeaderTimeout,omitempty"` // Waiting for the response headers once the request is sent
+
+	// Body capture (requires --enable-body-capture)
+	CaptureRequestBody  bool `json:"captureRequestBody,omitempty"`  // Write the outbound body to a temp file
+	CaptureResponseBody bool `json:"captureResponseBody,omitempty"` // Write the response body to a temp file instead of response_data
+
+	bodyStream io.Reader // Client body forwarded as the upstream body without buffering (nil = use Body)
+	bodyLength int64     // Length of bodyStream, or -1 if unknown (sent chunked)
+	bodyFile   string    // Resolved bodyFromPath file, opened as bodyStream when the request is executed
+	certPin    []byte    // Decoded PinnedCertSHA256
+
+	targetOrder []string // Target URLs in the order to try them, first one picked by weight (nil = just URL)
+
+	timings *phaseTimings // Phases of the upstream request for serverTiming (nil = not recorded)
+
+	errorStatus bool // Resolved ErrorStatusCodes, for streaming errors written by the client
+}
+
+// Join styles for list-valued path parameters
+const (
+	PathParamJoinComma   = "comma"   // ["1","2"] -> "1,2"
+	PathParamJoinSegment = "segment" // ["1","2"] -> "1/2" (one path segment per value)
+)
+
+// Redirect handling modes (ProxyRequest.RedirectMode); empty follows followRedirects
+const (
+	RedirectModeReturnLocation = "return-location" // Stop at the first 3xx and return it with its Location in redirect_location
+)
+
+// Text response normalizations (ProxyRequest.NormalizeText); binary responses are never changed
+const (
+	TextNormalizeStripBOM    = "strip-bom"    // Remove a leading UTF-8 byte order mark
+	TextNormalizeLineEndings = "line-endings" // Remove the byte order mark and turn CRLF line endings into LF
+)
+
+// PathParamValue holds a path parameter value
+// Accepts either a JSON string or an array of strings; a string is stored as a single element
+type PathParamValue []string
+
+// UnmarshalJSON accepts both "value" and ["value1", "value2"]
+func (v *PathParamValue) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*v = PathParamValue{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("path parameter must be a string or an array of strings")
+	}
+	*v = PathParamValue(list)
+	return nil
 }
 
 // FormProxyRequest represents form data request parameters
@@ -28,17 +137,56 @@ type FormProxyRequest struct {
 	Headers         string `json:"headers,omitempty"`
 	PathParams      string `json:"path_params,omitempty"`
 	RawBody         []byte `json:"-"` // For multipart data, exclude from JSON
+
+	ForwardedHeaders []string `json:"-"` // Allowlisted headers of the client's request as "Name: value" (--form-forward-headers)
+
+	ErrorStatusCodes *bool `json:"errorStatusCodes,omitempty"` // Answer upstream failures with 502/504/4xx instead of 200
+	MirrorStatus     *bool `json:"mirrorStatus,omitempty"`     // Answer with the upstream's status instead of 200
 }
 
 // FileRequest represents a local file request
 type FileRequest struct {
 	Path string `json:"path"`
+
+	Follow        bool `json:"follow,omitempty"`        // Keep streaming bytes appended to the file, like tail -f
+	FollowSeconds int  `json:"followSeconds,omitempty"` // Stop following after this many seconds (0 = DefaultFileFollowDuration)
+}
+
+// FileBatchRequest represents a /file/batch request
+type FileBatchRequest struct {
+	Files    []FileRequest `json:"files"`
+	MaxBytes int64         `json:"maxBytes,omitempty"` // Total content returned across files (0 = DefaultFileBatchBytes)
+}
+
+// FileBatchResult is the outcome of one file of a batch
+// Index refers to the position of the file in FileBatchRequest.Files
+type FileBatchResult struct {
+	Index     int    `json:"index"`
+	Path      string `json:"path"` // Resolved path, or the requested one if it couldn't be resolved
+	Success   bool   `json:"success"`
+	Content   string `json:"content,omitempty"`
+	Encoding  string `json:"encoding,omitempty"` // "text" or "base64" (binary content)
+	MimeType  string `json:"mimeType,omitempty"`
+	SizeBytes int64  `json:"sizeBytes"`           // Size of the file
+	ReadBytes int64  `json:"readBytes"`           // Bytes returned in content, before encoding
+	Truncated bool   `json:"truncated,omitempty"` // Content stops short of the file because the budget ran out
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// FileBatchResponse is the response of /file/batch, with one result per requested file
+type FileBatchResponse struct {
+	Success    bool              `json:"success"` // Every file could be read (possibly truncated)
+	Results    []FileBatchResult `json:"results"`
+	TotalBytes int64             `json:"totalBytes"`          // Sum of readBytes, at most maxBytes
+	Truncated  bool              `json:"truncated,omitempty"` // At least one file was truncated
 }
 
 // DirectoryRequest represents a directory listing request
 type DirectoryRequest struct {
 	Path            *string `json:"path"`            // Pointer to allow null detection
 	ShowHiddenFiles *bool   `json:"showHiddenFiles"` // Defaults to false if not provided
+	IncludeDirSizes bool    `json:"includeDirSizes"` // Compute recursive sizes for directories (off by default due to cost)
 }
 
 // DirectoryEntry represents a file or directory entry
@@ -46,8 +194,10 @@ type DirectoryEntry struct {
 	Name      string `json:"name"`
 	Type      string `json:"type"`                // "file" or "directory"
 	IsSymlink *bool  `json:"isSymlink,omitempty"` // Only present if entry is a symlink
-	SizeBytes *int64 `json:"sizeBytes,omitempty"` // File size in bytes (only for files)
-	SizeHuman *string `json:"sizeHuman,omitempty"` // Human-readable size (only for files)
+	SizeBytes *int64 `json:"sizeBytes,omitempty"` // Size in bytes (files, and directories with includeDirSizes)
+	SizeHuman *string `json:"sizeHuman,omitempty"` // Human-readable size (files, and directories with includeDirSizes)
+
+	SizeIncomplete bool `json:"sizeIncomplete,omitempty"` // Directory size is a lower bound (limits hit or unreadable entries)
 }
 
 // DirectoryResponse represents the response for directory listing
@@ -55,6 +205,17 @@ type DirectoryResponse struct {
 	ParentDir  *string          `json:"parentDir"`  // Absolute path to parent directory, or null if at root
 	CurrentDir string           `json:"currentDir"` // Absolute path to the currently listed directory
 	Dir        []DirectoryEntry `json:"dir"`        // Array of directory entries
+
+	Truncated bool `json:"truncated,omitempty"` // Listing stopped at --max-dir-entries; more entries exist
+
+	Errors []DirectoryEntryError `json:"errors,omitempty"` // Entries left out of Dir because they couldn't be read
+}
+
+// DirectoryEntryError describes a directory entry that couldn't be read and is missing from the listing
+type DirectoryEntryError struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`  // Absolute path of the entry
+	Error string `json:"error"` // Why it couldn't be read, e.g. "permission denied"
 }
 
 // ExecRequest represents a process execution request
@@ -65,6 +226,11 @@ type ExecRequest struct {
 	WorkingDir    string            `json:"workingDir,omitempty"` // Optional
 	Env           map[string]string `json:"env,omitempty"`        // Optional
 	CombineOutput bool              `json:"combineOutput,omitempty"` // Optional, default false
+	Streaming     bool              `json:"streaming,omitempty"`     // Optional, stream output as NDJSON events
+	Stdin         string            `json:"stdin,omitempty"`         // Optional, written to the command's stdin
+	StdinEncoding string            `json:"stdinEncoding,omitempty"` // Optional, "text" (default) or "base64"
+
+	stdin []byte // Decoded Stdin
 }
 
 // ExecResponse represents the response from process execution
@@ -74,6 +240,7 @@ type ExecResponse struct {
 	Stdout         string `json:"stdout,omitempty"`        // Only if not combined
 	Stderr         string `json:"stderr,omitempty"`        // Only if not combined
 	CombinedOutput string `json:"combinedOutput,omitempty"` // Only if combined
+	Truncated      bool   `json:"truncated,omitempty"`      // Output exceeded the cap and was cut off
 	ExecutionTime  string `json:"executionTime,omitempty"`
 
 	// Error fields (when success = false)
@@ -88,12 +255,45 @@ type ProxyResponse struct {
 	ResponseStatus  int               `json:"response_status,omitempty"`
 	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
 	ResponseData    string            `json:"response_data,omitempty"`
+	ResponseJSON    json.RawMessage   `json:"response_json,omitempty"` // Parsed body when parseJSON was set and the body is JSON
 	ResponseSize    string            `json:"response_size,omitempty"`
 	ResponseTime    string            `json:"response_time,omitempty"`
 	ContentType     string            `json:"content_type,omitempty"`
 	IsBinary        bool              `json:"is_binary,omitempty"`
 	Cancelled       bool              `json:"cancelled,omitempty"`
 
+	EffectiveTimeout int `json:"effective_timeout,omitempty"` // Seconds the request was allowed to run, after defaulting
+
+	CompressedBytes int64 `json:"compressed_bytes,omitempty"` // Wire size of a gzip body the proxy decompressed; response_size is decompressed
+
+	ResolvedIP string `json:"resolved_ip,omitempty"` // Address of the final hop's connection (includeResolvedIP)
+
+	InformationalResponses []InformationalResponse `json:"informational_responses,omitempty"` // 1xx responses (includeInformational)
+
+	ResponseHeaderList []HeaderField `json:"response_header_list,omitempty"` // Headers in the order received, names as sent (orderedHeaders)
+
+	HeaderDiff *HeaderDiff `json:"header_diff,omitempty"` // Request vs response headers (includeHeaderDiff)
+
+	BodyDiscarded bool `json:"body_discarded,omitempty"` // The body was closed unread (discardBody)
+
+	RedirectLocation string `json:"redirect_location,omitempty"` // Location of a 3xx, resolved against the request URL (redirectMode return-location)
+
+	// Set when the request named targets
+	Target        string   `json:"target,omitempty"`         // URL of the target the response came from
+	FailedTargets []string `json:"failed_targets,omitempty"` // Targets tried before it that couldn't be reached (failover)
+
+	// Set when the response cache answered the request (--response-cache-ttl)
+	FromCache bool `json:"from_cache,omitempty"`
+	CacheAge  int  `json:"cache_age,omitempty"` // Seconds since the response was stored
+
+	// Set when the body is incomplete (partialOnTimeout)
+	Partial       bool   `json:"partial,omitempty"`
+	PartialReason string `json:"partial_reason,omitempty"`
+
+	// Captured body files (when body capture was requested)
+	RequestBodyFile  string `json:"request_body_file,omitempty"`
+	ResponseBodyFile string `json:"response_body_file,omitempty"`
+
 	// Error fields (when success = false)
 	ErrorType    string `json:"error_type,omitempty"`
 	ErrorTitle   string `json:"error_title,omitempty"`
@@ -102,6 +302,107 @@ type ProxyResponse struct {
 	// Internal fields for pass-through mode
 	RawResponseBody []byte `json:"-"`
 	PassThrough     bool   `json:"-"`
+
+	metrics RequestMetrics // Raw timing and sizes behind response_time and response_size, for batch summaries
+}
+
+// Reasons reported in ProxyResponse.PartialReason
+const (
+	PartialReasonTimeout = "timeout" // The request timeout expired while the body was being read
+)
+
+// BatchRequest executes several proxy requests in one call
+type BatchRequest struct {
+	Requests []ProxyRequest `json:"requests"`
+	Stream   bool           `json:"stream,omitempty"` // Emit results as NDJSON as they complete instead of one JSON document
+
+	Multipart bool `json:"multipart,omitempty"` // Return a multipart/mixed response with each body as its own part
+}
+
+// BatchResult is the outcome of one sub-request of a batch
+// Index refers to the position of the sub-request in BatchRequest.Requests
+type BatchResult struct {
+	Index int `json:"index"`
+	*ProxyResponse
+}
+
+// BatchResponse is the aggregate response of a non-streaming batch
+type BatchResponse struct {
+	Success bool          `json:"success"`
+	Results []BatchResult `json:"results"`
+	Summary *BatchSummary `json:"summary"`
+}
+
+// BatchSummary aggregates the results of a batch
+// Sub-requests rejected before being sent count as failures with no bytes or time.
+type BatchSummary struct {
+	Total         int    `json:"total"`
+	Succeeded     int    `json:"succeeded"`
+	Failed        int    `json:"failed"`
+	RequestBytes  int64  `json:"request_bytes"`  // Outbound bodies as sent, after requestEncoding
+	ResponseBytes int64  `json:"response_bytes"` // Response bodies, as counted in response_size
+	TotalTime     string `json:"total_time"`     // Sum of the sub-requests' response_time
+	Duration      string `json:"duration"`       // Wall-clock time of the whole batch; less than total_time when sub-requests overlap
+}
+
+// ReadyResponse is the body of /ready
+type ReadyResponse struct {
+	Status string      `json:"status"`          // ReadyStatusReady or ReadyStatusNotReady
+	Check  *ReadyCheck `json:"check,omitempty"` // Upstream check, when --ready-check-url is set
+}
+
+// ReadyCheck is the outcome of the /ready upstream connectivity check
+type ReadyCheck struct {
+	URL            string `json:"url"`
+	ResponseStatus int    `json:"response_status,omitempty"`
+	Duration       string `json:"duration"`
+
+	// Set when the check failed
+	ErrorType    string `json:"error_type,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// ValidateRequest is the body of a /proxy/validate request
+type ValidateRequest struct {
+	URL string `json:"url"`
+}
+
+// ValidateResponse is the policy verdict for a URL
+// Rule and Details say which check blocked it; Details may also note a check that couldn't be applied.
+type ValidateResponse struct {
+	Success bool   `json:"success"`
+	URL     string `json:"url"`
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// PreflightRequest is the body of a /proxy/preflight request, describing the CORS preflight to send
+type PreflightRequest struct {
+	URL            string   `json:"url"`
+	Origin         string   `json:"origin"`                   // Origin of the page making the request
+	RequestMethod  string   `json:"requestMethod"`            // Method of the actual request (Access-Control-Request-Method)
+	RequestHeaders []string `json:"requestHeaders,omitempty"` // Header names of the actual request (Access-Control-Request-Headers)
+	Headers        []string `json:"headers,omitempty"`        // Additional "Name: value" headers sent with the preflight
+	Timeout        int      `json:"timeout,omitempty"`
+}
+
+// PreflightResponse reports the upstream's answer to a preflight and whether a browser would accept it
+type PreflightResponse struct {
+	Success        bool              `json:"success"`
+	ResponseStatus int               `json:"response_status,omitempty"`
+	ResponseTime   string            `json:"response_time,omitempty"`
+	CORSHeaders    map[string]string `json:"cors_headers,omitempty"` // Access-Control-* and Vary response headers, lowercased names
+
+	OriginAllowed  bool `json:"origin_allowed"`
+	MethodAllowed  bool `json:"method_allowed"`
+	HeadersAllowed bool `json:"headers_allowed"`
+	Allowed        bool `json:"allowed"` // All of the above and a 2xx status: the browser would send the actual request
+
+	// Error fields (when success = false)
+	ErrorType    string `json:"error_type,omitempty"`
+	ErrorTitle   string `json:"error_title,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
 }
 
 // StreamingResponse represents the initial metadata response for streaming requests
@@ -114,17 +415,52 @@ type StreamingResponse struct {
 	IsBinary        bool              `json:"is_binary,omitempty"`
 	Cancelled       bool              `json:"cancelled,omitempty"`
 
+	InformationalResponses []InformationalResponse `json:"informational_responses,omitempty"` // 1xx responses (includeInformational)
+
+	ResponseHeaderList []HeaderField `json:"response_header_list,omitempty"` // Headers in the order received, names as sent (orderedHeaders)
+
+	HeaderDiff *HeaderDiff `json:"header_diff,omitempty"` // Request vs response headers (includeHeaderDiff)
+
 	// Error fields (when success = false)
 	ErrorType    string `json:"error_type,omitempty"`
 	ErrorTitle   string `json:"error_title,omitempty"`
 	ErrorMessage string `json:"error_message,omitempty"`
 }
 
+// HeaderField is one response header line, in the order and name case the upstream sent it
+type HeaderField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// InformationalResponse is a 1xx response the upstream sent before its final response
+type InformationalResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"` // Lowercased names; repeated headers (e.g. Link) joined with ", "
+}
+
+// StreamRecordSeparator prefixes the completion record in streaming responses
+// (ASCII record separator, as used by JSON text sequences in RFC 7464)
+const StreamRecordSeparator = '\x1e'
+
+// StreamCompletion is the final record written after a streaming response ends normally
+type StreamCompletion struct {
+	StreamComplete     bool   `json:"stream_complete"`
+	BytesStreamed      int64  `json:"bytes_streamed"`
+	ResponseTime       string `json:"response_time"`
+	LastEventID        string `json:"last_event_id,omitempty"`        // id of the last SSE event, for resuming with Last-Event-ID
+	MaxDurationReached bool   `json:"max_duration_reached,omitempty"` // Stream was closed by the proxy's maximum streaming duration
+	MaxBytesReached    bool   `json:"max_bytes_reached,omitempty"`    // Stream was closed after the request's maxStreamBytes
+	Cancelled          bool   `json:"cancelled,omitempty"`            // Stream was cancelled through /proxy/cancel before it ended
+}
+
 // ProxyError represents different types of proxy errors
 type ProxyError struct {
 	Type    string
 	Title   string
 	Message string
+
+	Description string // What the error means, listed by /errors
 }
 
 func (e *ProxyError) Error() string {
@@ -133,57 +469,155 @@ func (e *ProxyError) Error() string {
 
 // Predefined error types matching Lua implementation
 var (
+	RequestFormatError = &ProxyError{
+		Type:        "request_format_error",
+		Title:       "Invalid Request",
+		Description: "The request to the proxy is malformed or combines options that can't be used together; the title names the problem.",
+	}
+	UnknownError = &ProxyError{
+		Type:        "unknown_error",
+		Title:       "Request Failed",
+		Description: "The request failed for an unexpected reason.",
+	}
 	URLValidationError = &ProxyError{
-		Type:  "url_validation_error",
-		Title: "Invalid URL",
+		Type:        "url_validation_error",
+		Title:       "Invalid URL",
+		Description: "The target URL is missing, malformed or uses a scheme that is not allowed.",
 	}
 	TimeoutError = &ProxyError{
-		Type:  "timeout",
-		Title: "Request Timed Out",
+		Type:        "timeout",
+		Title:       "Request Timed Out",
+		Description: "The upstream did not respond within the request timeout.",
 	}
 	ConnectionError = &ProxyError{
-		Type:  "connection_error",
-		Title: "Connection Failed",
+		Type:        "connection_error",
+		Title:       "Connection Failed",
+		Description: "The connection to the upstream failed for a reason not covered by a more specific type.",
+	}
+	DNSError = &ProxyError{
+		Type:        "dns_error",
+		Title:       "Host Not Found",
+		Description: "The target hostname could not be resolved.",
+	}
+	ConnectionRefusedError = &ProxyError{
+		Type:        "connection_refused",
+		Title:       "Connection Refused",
+		Description: "The upstream host refused the connection.",
+	}
+	ConnectionResetError = &ProxyError{
+		Type:        "connection_reset",
+		Title:       "Connection Reset",
+		Description: "The upstream closed the connection unexpectedly.",
+	}
+	TLSError = &ProxyError{
+		Type:        "tls_error",
+		Title:       "TLS Handshake Failed",
+		Description: "The TLS handshake with the upstream failed, e.g. because of an invalid certificate.",
 	}
 	RedirectNotFollowedError = &ProxyError{
-		Type:  "redirect_not_followed",
-		Title: "Redirect Not Followed",
+		Type:        "redirect_not_followed",
+		Title:       "Redirect Not Followed",
+		Description: "The upstream answered with a redirect that the request did not allow following.",
 	}
 	LoopDetectedError = &ProxyError{
-		Type:  "loop_detected",
-		Title: "Loop Detected",
+		Type:        "loop_detected",
+		Title:       "Loop Detected",
+		Description: "The request would make the proxy call itself or a blocked hostname.",
+	}
+	ConnectTimeoutError = &ProxyError{
+		Type:        "connect_timeout",
+		Title:       "Connect Timeout",
+		Description: "Establishing the TCP connection took longer than connectTimeout.",
+	}
+	TLSTimeoutError = &ProxyError{
+		Type:        "tls_timeout",
+		Title:       "TLS Handshake Timeout",
+		Description: "The TLS handshake took longer than tlsTimeout.",
+	}
+	ResponseHeaderTimeoutError = &ProxyError{
+		Type:        "response_header_timeout",
+		Title:       "Response Header Timeout",
+		Description: "The upstream did not send response headers within responseHeaderTimeout or --response-header-timeout.",
+	}
+	ResponseTooLargeError = &ProxyError{
+		Type:        "response_too_large",
+		Title:       "Response Too Large",
+		Description: "The response body exceeded --max-response-bytes.",
+	}
+	SSRFBlockedError = &ProxyError{
+		Type:        "ssrf_blocked",
+		Title:       "Private Address Blocked",
+		Description: "The target resolves to a private network address and --block-private-networks is set.",
 	}
 	StreamingTimeoutError = &ProxyError{
-		Type:  "request_timeout",
-		Title: "Streaming Request Timeout",
+		Type:        "request_timeout",
+		Title:       "Streaming Request Timeout",
+		Description: "A streaming request exceeded its timeout before the response started.",
 	}
 	FileNotFoundError = &ProxyError{
-		Type:  "file_not_found",
-		Title: "File Not Found",
+		Type:        "file_not_found",
+		Title:       "File Not Found",
+		Description: "The requested local file or directory does not exist.",
 	}
 	FileAccessError = &ProxyError{
-		Type:  "file_access_error",
-		Title: "File Access Error",
+		Type:        "file_access_error",
+		Title:       "File Access Error",
+		Description: "The local file or directory could not be read.",
 	}
 	FeatureDisabledError = &ProxyError{
-		Type:  "feature_disabled",
-		Title: "Feature Disabled",
+		Type:        "feature_disabled",
+		Title:       "Feature Disabled",
+		Description: "The endpoint or option requires a feature that is not enabled on this proxy.",
 	}
 	EndpointNotFoundError = &ProxyError{
-		Type:  "endpoint_not_found",
-		Title: "Endpoint Not Found",
+		Type:        "endpoint_not_found",
+		Title:       "Endpoint Not Found",
+		Description: "No endpoint exists at the requested path.",
 	}
 	ExecTimeoutError = &ProxyError{
-		Type:  "exec_timeout",
-		Title: "Execution Timeout",
+		Type:        "exec_timeout",
+		Title:       "Execution Timeout",
+		Description: "The executed process did not finish within its timeout.",
 	}
 	ExecFailedError = &ProxyError{
-		Type:  "exec_failed",
-		Title: "Execution Failed",
+		Type:        "exec_failed",
+		Title:       "Execution Failed",
+		Description: "The process could not be started, was not allowed or too many were already running.",
 	}
 	LocalhostOnlyError = &ProxyError{
-		Type:  "localhost_only",
-		Title: "Localhost Only",
+		Type:        "localhost_only",
+		Title:       "Localhost Only",
+		Description: "The endpoint only accepts requests from localhost.",
+	}
+	OAuth2TokenError = &ProxyError{
+		Type:        "oauth2_token_error",
+		Title:       "OAuth2 Token Error",
+		Description: "An OAuth2 access token could not be obtained for the target host.",
+	}
+	MethodNotAllowedError = &ProxyError{
+		Type:        "method_not_allowed",
+		Title:       "Method Not Allowed",
+		Description: "The HTTP method is not allowed for the endpoint by --allowed-methods.",
+	}
+	StreamingLimitError = &ProxyError{
+		Type:        "streaming_limit_reached",
+		Title:       "Too Many Streaming Requests",
+		Description: "The maximum number of concurrent streaming requests was reached.",
+	}
+	RequestCancelledError = &ProxyError{
+		Type:        "request_cancelled",
+		Title:       "Request Cancelled",
+		Description: "The request was cancelled through /proxy/cancel or because the client went away.",
+	}
+	CertPinMismatchError = &ProxyError{
+		Type:        "cert_pin_mismatch",
+		Title:       "Certificate Pin Mismatch",
+		Description: "The upstream certificate did not match pinnedCertSHA256.",
+	}
+	BodyCaptureError = &ProxyError{
+		Type:        "body_capture_error",
+		Title:       "Body Capture Failed",
+		Description: "The request body could not be written to a capture file in --body-capture-dir.",
 	}
 )
 
@@ -191,7 +625,10 @@ var (
 type RequestMetrics struct {
 	StartTime    time.Time
 	EndTime      time.Time
+	RequestSize  int64 // Outbound body bytes (-1 = streamed with unknown length)
 	ResponseSize int64
+
+	CompressedBytes int64 // Wire bytes of a response body the proxy decompressed (0 = not decompressed)
 }
 
 // GetDuration returns the total request duration in milliseconds
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Policy rules reported by /proxy/validate, named like the error types and blockedRequests counters
+const (
+	RuleURLValidation   = "url_validation_error" // Not an absolute URL with an allowed scheme
+	RuleLoopDetected    = "loop_detected"        // Caller sent the rb-slingshot User-Agent
+	RuleHostnameBlocked = "hostname_blocked"     // Built-in blocked hostname or --enable-blacklist entry
+	RuleSSRFBlocked     = "ssrf_blocked"         // Resolves to a private address under --block-private-networks
+)
+
+// validateResolveTimeout bounds the DNS lookup of the private network check
+const validateResolveTimeout = 5 * time.Second
+
+// handleValidateRequest handles /proxy/validate endpoint
+// It runs the URL through the same checks as /proxy/request without sending anything
+// upstream; the checks don't count towards the blockedRequests metrics.
+func (s *Server) handleValidateRequest(w http.ResponseWriter, r *http.Request) {
+	// Handle OPTIONS for CORS preflight
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Failed to read request body", err.Error())
+		return
+	}
+
+	var req ValidateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Invalid JSON", fmt.Sprintf("Failed to parse JSON request: %v", err))
+		return
+	}
+
+	if req.URL == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "request_format_error", "Missing URL", "URL is required")
+		return
+	}
+
+	verdict := s.validateURLPolicy(r, req.URL)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(verdict)
+}
+
+// validateURLPolicy checks targetURL in the order /proxy/request applies the same rules
+func (s *Server) validateURLPolicy(r *http.Request, targetURL string) *ValidateResponse {
+	verdict := &ValidateResponse{Success: true, URL: targetURL, Allowed: true}
+	block := func(rule, details string) *ValidateResponse {
+		verdict.Allowed, verdict.Rule, verdict.Details = false, rule, details
+		return verdict
+	}
+
+	if err := s.httpClient.validateURL(targetURL); err != nil {
+		return block(RuleURLValidation, err.Error())
+	}
+
+	switch s.loopRule(r, targetURL) {
+	case RuleLoopDetected:
+		return block(RuleLoopDetected, "The request carries the rb-slingshot User-Agent, so it could loop back through a proxy")
+	case RuleHostnameBlocked:
+		return block(RuleHostnameBlocked, "The hostname is blocked to prevent requests looping back to this proxy")
+	}
+
+	if !s.httpClient.blocksPrivateNetworks() {
+		return verdict
+	}
+
+	// The verdict is advisory here: the real request checks every dialed address, which also catches DNS rebinding
+	parsedURL, _ := url.Parse(targetURL) // Already checked by validateURL
+	host := parsedURL.Hostname()
+	ips, err := lookupHostIPs(r.Context(), host)
+	if err != nil {
+		verdict.Details = fmt.Sprintf("%s could not be resolved, so the private network check was not applied: %v", host, err)
+		return verdict
+	}
+	for _, ip := range ips {
+		if isPrivateAddress(ip) {
+			return block(RuleSSRFBlocked, fmt.Sprintf("The target resolves to %s, which is a private network address. Requests to private networks are blocked by --block-private-networks.", ip))
+		}
+	}
+
+	return verdict
+}
+
+// lookupHostIPs returns the addresses host resolves to; IP literals are returned as is
+func lookupHostIPs(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, validateResolveTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
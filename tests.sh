@@ -54,7 +54,7 @@ echo "Hello from local file!" > "$TEST_FILE"
 make build > /dev/null 2>&1
 
 # Start proxy with local files enabled using make dev in background
-ARGS="--port $PORT --enable-local-files" make dev > /tmp/proxy.log 2>&1 &
+ARGS="--port $PORT --enable-local-files --enable-exec --exec-root $TEST_DIR --exec-max-output 1024 --max-requests-per-host 2" make dev > /tmp/proxy.log 2>&1 &
 PROXY_PID=$!
 
 # Wait for server to start
@@ -104,6 +104,83 @@ VERSION=$(echo "$RESPONSE" | jq -r '.version')
 ENABLE_LOCAL=$(echo "$RESPONSE" | jq -r '.enableLocalFiles')
 check_result "Health endpoint shows enableLocalFiles=true" "true" "$ENABLE_LOCAL"
 
+RESPONSE=$(curl -s "$PROXY_URL/version")
+BUILD_VERSION=$(echo "$RESPONSE" | jq -r '.version')
+check_result "Version endpoint returns configured version" "$VERSION" "$BUILD_VERSION"
+
+GIT_COMMIT=$(echo "$RESPONSE" | jq -r '.gitCommit')
+[ -n "$GIT_COMMIT" ] && [ "$GIT_COMMIT" != "null" ] && check_result "Version endpoint includes git commit" "true" "true"
+
+# Test the error catalog lists every predefined error type, each with a title and description
+RESPONSE=$(curl -s "$PROXY_URL/errors")
+DEFINED_TYPES=$(sed -n '/= &ProxyError{/{n;p;}' internal/proxy/types.go | sed -E 's/.*"([a-z0-9_]+)".*/\1/' | sort | tr '\n' ' ')
+CATALOG_TYPES=$(echo "$RESPONSE" | jq -r '.errors[].type' | sort | tr '\n' ' ')
+check_result "Error catalog lists every predefined error type" "$DEFINED_TYPES" "$CATALOG_TYPES"
+check_result "Error catalog entries have a title and description" "0" "$(echo "$RESPONSE" | jq '[.errors[] | select(.title == "" or .description == "")] | length')"
+
+RESPONSE=$(curl -s "$PROXY_URL/ready")
+STATUS=$(echo "$RESPONSE" | jq -r '.status')
+check_result "Ready endpoint without upstream check returns ready" "ready" "$STATUS"
+
+# Instance whose readiness check target never answers in time reports not ready within the timeout
+if command -v python3 > /dev/null 2>&1; then
+    SLOW_READY_PORT=$((PORT + 16))
+    READY_PORT=$((PORT + 17))
+    python3 -c '
+import socket, sys, time
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+time.sleep(10)
+' $SLOW_READY_PORT &
+    SLOW_READY_PID=$!
+    ./build/rbite-proxy --port $READY_PORT --ready-check-url "http://127.0.0.1:$SLOW_READY_PORT/" --ready-check-timeout 1s --no-upgrade-check > /tmp/proxy-ready.log 2>&1 &
+    READY_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -m 5 -w '\n%{http_code} %{time_total}' "http://localhost:$READY_PORT/ready")
+    READY_STATUS=$(echo "$RESPONSE" | head -1 | jq -r '.status')
+    READY_ERROR=$(echo "$RESPONSE" | head -1 | jq -r '.check.error_type')
+    HTTP_STATUS=$(echo "$RESPONSE" | tail -1 | awk '{print $1}')
+    BOUNDED=$(echo "$RESPONSE" | tail -1 | awk '{print ($2 < 3) ? "true" : "false"}')
+    check_result "Slow readiness check returns not_ready" "not_ready" "$READY_STATUS"
+    check_result "Slow readiness check reports a timeout" "timeout" "$READY_ERROR"
+    check_result "Slow readiness check returns 503" "503" "$HTTP_STATUS"
+    check_result "Slow readiness check is bounded by --ready-check-timeout" "true" "$BOUNDED"
+
+    kill $READY_PID $SLOW_READY_PID 2>/dev/null || true
+    wait $READY_PID $SLOW_READY_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping readiness check timeout test (python3 not available)"
+fi
+
+# Welcome message colors follow the User-Agent, ?color and NO_COLOR
+ESC=$(printf '\033')
+COLORED=$(curl -s "$PROXY_URL/" | grep -c "$ESC")
+check_result "Welcome message is plain for other clients" "0" "$COLORED"
+
+COLORED=$(curl -s "$PROXY_URL/?color=true" | grep -c "$ESC")
+check_result "?color=true forces a colored welcome message" "3" "$COLORED"
+
+COLORED=$(curl -s -A "rb-slingshot/test" "$PROXY_URL/?color=false" | grep -c "$ESC")
+check_result "?color=false forces a plain welcome message" "0" "$COLORED"
+
+NO_COLOR_PORT=$((PORT + 39))
+NO_COLOR=1 ./build/rbite-proxy --port $NO_COLOR_PORT --no-upgrade-check > /tmp/proxy-nocolor.log 2>&1 &
+NO_COLOR_PID=$!
+sleep 1
+
+COLORED=$(curl -s -A "rb-slingshot/test" "http://localhost:$NO_COLOR_PORT/" | grep -c "$ESC")
+check_result "NO_COLOR turns off colors for rb-slingshot clients" "0" "$COLORED"
+
+COLORED=$(curl -s "http://localhost:$NO_COLOR_PORT/?color=true" | grep -c "$ESC")
+check_result "?color=true overrides NO_COLOR" "3" "$COLORED"
+
+kill $NO_COLOR_PID 2>/dev/null || true
+wait $NO_COLOR_PID 2>/dev/null || true
+
 echo ""
 
 # ========================================
@@ -124,6 +201,29 @@ RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
 SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
 check_result "Normal GET request succeeds" "true" "$SUCCESS"
 
+# Test 1b: Default-valued fields are left out, except success; failures keep their error fields
+KEYS=$(echo "$RESPONSE" | jq -c 'keys')
+check_result "Successful response carries only set fields" '["content_type","effective_timeout","response_data","response_headers","response_size","response_status","response_time","success"]' "$KEYS"
+
+KEYS=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/", "timeout": 10}' | jq -c 'keys')
+check_result "Failed response carries success and its error fields" '["effective_timeout","error_message","error_title","error_type","response_time","success"]' "$KEYS"
+
+# Test 1c: effective_timeout reports the timeout the request ran under
+EFFECTIVE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/"}' | jq -r '.effective_timeout')
+check_result "effective_timeout reports the default timeout" "60" "$EFFECTIVE"
+
+EFFECTIVE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/", "timeout": 7}' | jq -r '.effective_timeout')
+check_result "effective_timeout reports the requested timeout" "7" "$EFFECTIVE"
+
+EFFECTIVE=$(curl -s -X POST "$PROXY_URL/proxy/form?url=http://127.0.0.1:1/" -d "field=value" | jq -r '.effective_timeout')
+check_result "effective_timeout is reported by /proxy/form" "60" "$EFFECTIVE"
+
 # Test 2: Timeout request
 RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
     -H "Content-Type: application/json" \
@@ -139,6 +239,913 @@ ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
 check_result "Timeout request fails" "false" "$SUCCESS"
 check_result "Timeout error type is 'timeout'" "timeout" "$ERROR_TYPE"
 
+# Test 2b: A slow-header upstream trips responseHeaderTimeout well within the overall timeout
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/delay/5",
+        "headers": [],
+        "timeout": 20,
+        "responseHeaderTimeout": 2
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Slow headers trip responseHeaderTimeout, not the overall timeout" "response_header_timeout" "$ERROR_TYPE"
+
+# Test 2c: --response-header-timeout fails fast on an upstream that accepts but never answers
+if command -v python3 > /dev/null 2>&1; then
+    HEADER_TIMEOUT_PORT=$((PORT + 34))
+    SILENT_PORT=$((PORT + 35))
+    python3 -c '
+import socket, sys, time
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+time.sleep(30)
+' $SILENT_PORT &
+    SILENT_PID=$!
+    ./build/rbite-proxy --port $HEADER_TIMEOUT_PORT --response-header-timeout 2s --no-upgrade-check > /tmp/proxy-headertimeout.log 2>&1 &
+    HEADER_TIMEOUT_PID=$!
+    sleep 1
+
+    START_TIME=$(date +%s)
+    RESPONSE=$(curl -s -X POST "http://localhost:$HEADER_TIMEOUT_PORT/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$SILENT_PORT/\",
+            \"timeout\": 20
+        }")
+    ELAPSED=$(( $(date +%s) - START_TIME ))
+    ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+    FAST=$([ "$ELAPSED" -lt 10 ] && echo "true" || echo "false")
+    check_result "Withheld headers trip --response-header-timeout" "response_header_timeout" "$ERROR_TYPE"
+    check_result "Withheld headers fail well before the overall timeout" "true" "$FAST"
+
+    kill $HEADER_TIMEOUT_PID $SILENT_PID 2>/dev/null || true
+    wait $HEADER_TIMEOUT_PID $SILENT_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping --response-header-timeout test (python3 not available)"
+fi
+
+# Test 2c2: Upstream failures are reported with 200 by default, with their own status on request
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/", "timeout": 10}')
+check_result "Failed upstream request returns 200 by default" "200" "$STATUS"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/", "timeout": 10, "errorStatusCodes": true}')
+check_result "errorStatusCodes answers a refused connection with 502" "502" "$STATUS"
+
+ERROR_STATUS_PORT=$((PORT + 36))
+./build/rbite-proxy --port $ERROR_STATUS_PORT --error-status-codes --no-upgrade-check > /tmp/proxy-errorstatus.log 2>&1 &
+ERROR_STATUS_PID=$!
+sleep 1
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "http://localhost:$ERROR_STATUS_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "https://httpbin.org/delay/5", "timeout": 2}')
+check_result "--error-status-codes answers a timeout with 504" "504" "$STATUS"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "http://localhost:$ERROR_STATUS_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "ftp://example.com/", "timeout": 10}')
+check_result "--error-status-codes answers an invalid URL with 400" "400" "$STATUS"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "http://localhost:$ERROR_STATUS_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/", "timeout": 10, "errorStatusCodes": false}')
+check_result "errorStatusCodes: false keeps 200 under --error-status-codes" "200" "$STATUS"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "http://localhost:$ERROR_STATUS_PORT/proxy/form?url=http://127.0.0.1:1/" \
+    -d "field=value")
+check_result "--error-status-codes applies to /proxy/form" "502" "$STATUS"
+
+kill $ERROR_STATUS_PID 2>/dev/null || true
+wait $ERROR_STATUS_PID 2>/dev/null || true
+
+# Test 2c3: mirrorStatus answers with the upstream's status, keeping the JSON body
+RESPONSE=$(curl -s -w "\n%{http_code}" -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "https://httpbin.org/status/404", "timeout": 10}')
+STATUS=$(echo "$RESPONSE" | tail -n 1)
+check_result "Upstream 404 is answered with 200 by default" "200" "$STATUS"
+
+RESPONSE=$(curl -s -w "\n%{http_code}" -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "https://httpbin.org/status/404", "timeout": 10, "mirrorStatus": true}')
+STATUS=$(echo "$RESPONSE" | tail -n 1)
+UPSTREAM_STATUS=$(echo "$RESPONSE" | sed '$d' | jq -r '.response_status')
+check_result "mirrorStatus answers an upstream 404 with 404" "404" "$STATUS"
+check_result "mirrorStatus keeps response_status in the JSON body" "404" "$UPSTREAM_STATUS"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/form?url=https://httpbin.org/status/404&mirrorStatus=true" \
+    -d "field=value")
+check_result "mirrorStatus applies to /proxy/form" "404" "$STATUS"
+
+# Test 2c4: h2c sends cleartext HTTP/2 to an upstream that accepts it without TLS
+if command -v go > /dev/null 2>&1; then
+    H2C_PORT=$((PORT + 38))
+    H2C_DIR=$(mktemp -d)
+    cat > "$H2C_DIR/main.go" << 'GOEOF'
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Answers every request with the protocol it arrived over
+func main() {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{
+		Addr:      "127.0.0.1:" + os.Args[1],
+		Protocols: protocols,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, r.Proto)
+		}),
+	}
+	server.ListenAndServe()
+}
+GOEOF
+    go build -o "$H2C_DIR/h2c-server" "$H2C_DIR/main.go"
+    "$H2C_DIR/h2c-server" $H2C_PORT > /dev/null 2>&1 &
+    H2C_PID=$!
+    sleep 1
+
+    PROTO=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$H2C_PORT/\", \"timeout\": 10}" | jq -r '.response_data')
+    check_result "Cleartext requests use HTTP/1.1 by default" "HTTP/1.1" "$PROTO"
+
+    PROTO=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$H2C_PORT/\", \"timeout\": 10, \"h2c\": true}" | jq -r '.response_data')
+    check_result "h2c sends the request over cleartext HTTP/2" "HTTP/2.0" "$PROTO"
+
+    ERROR_TITLE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d '{"method": "GET", "url": "https://httpbin.org/get", "timeout": 10, "h2c": true}' | jq -r '.error_title')
+    check_result "h2c rejects https URLs" "Invalid Protocol Option" "$ERROR_TITLE"
+
+    kill $H2C_PID 2>/dev/null || true
+    wait $H2C_PID 2>/dev/null || true
+    rm -rf "$H2C_DIR"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping h2c test (go not available)"
+fi
+
+# Test 2c5: discardBody returns the status and headers without waiting for a slow body
+if command -v python3 > /dev/null 2>&1; then
+    DISCARD_PORT=$((PORT + 41))
+    DISCARD_LOG=$(mktemp)
+    python3 -c '
+import http.server, sys, time
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_POST(self):
+        self.rfile.read(int(self.headers.get("Content-Length", 0)))
+        self.send_response(202)
+        self.send_header("Content-Length", str(1024 * 1024))
+        self.send_header("X-Webhook", "accepted")
+        self.end_headers()
+        try:
+            for _ in range(100):
+                self.wfile.write(b"x" * 1024)
+                self.wfile.flush()
+                time.sleep(0.1)
+        except (BrokenPipeError, ConnectionResetError):
+            with open(sys.argv[2], "w") as log:
+                log.write("closed")
+    def log_message(self, *args):
+        pass
+http.server.HTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $DISCARD_PORT "$DISCARD_LOG" &
+    DISCARD_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -w '\n%{time_total}' -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"POST\", \"url\": \"http://127.0.0.1:$DISCARD_PORT/hook\", \"body\": \"{}\", \"timeout\": 30, \"discardBody\": true}")
+    BODY=$(echo "$RESPONSE" | sed '$d')
+    FAST=$(echo "$RESPONSE" | tail -n 1 | awk '{print ($1 < 3) ? "true" : "false"}')
+    check_result "discardBody reports the upstream status" "202" "$(echo "$BODY" | jq -r '.response_status')"
+    check_result "discardBody reports the response headers" "accepted" "$(echo "$BODY" | jq -r '.response_headers["x-webhook"]')"
+    check_result "discardBody returns no body" "false" "$(echo "$BODY" | jq -r 'has("response_data")')"
+    check_result "discardBody marks the body as discarded" "true" "$(echo "$BODY" | jq -r '.body_discarded')"
+    check_result "discardBody returns without reading the 10s body" "true" "$FAST"
+    sleep 1
+    check_result "discardBody closes the upstream connection" "closed" "$(cat "$DISCARD_LOG")"
+
+    kill $DISCARD_PID 2>/dev/null || true
+    wait $DISCARD_PID 2>/dev/null || true
+    rm -f "$DISCARD_LOG"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping discardBody test (python3 not available)"
+fi
+
+# Test 2c6: A gzip response the proxy decompresses reports its wire size next to response_size
+if command -v python3 > /dev/null 2>&1; then
+    GZIP_PORT=$((PORT + 45))
+    python3 -c '
+import gzip, http.server, sys
+BODY = b"{\"items\": \"" + b"a" * 10000 + b"\"}"
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        data = BODY
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        if "gzip" in self.headers.get("Accept-Encoding", ""):
+            data = gzip.compress(BODY)
+            self.send_header("Content-Encoding", "gzip")
+        self.send_header("Content-Length", str(len(data)))
+        self.end_headers()
+        self.wfile.write(data)
+    def log_message(self, *args):
+        pass
+http.server.HTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $GZIP_PORT &
+    GZIP_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$GZIP_PORT/\", \"timeout\": 10}")
+    check_result "Decompressed response reports its decompressed size" "9.78 KB" "$(echo "$RESPONSE" | jq -r '.response_size')"
+    check_result "Decompressed response reports a smaller wire size" "true" "$(echo "$RESPONSE" | jq -r '.compressed_bytes > 0 and .compressed_bytes < 10000')"
+    check_result "Decompressed response has no Content-Encoding" "false" "$(echo "$RESPONSE" | jq -r '.response_headers | has("content-encoding")')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$GZIP_PORT/\", \"headers\": [\"Accept-Encoding: gzip\"], \"timeout\": 10}")
+    check_result "A caller's own Accept-Encoding gets the body as sent" "gzip" "$(echo "$RESPONSE" | jq -r '.response_headers["content-encoding"]')"
+    check_result "A body that wasn't decompressed has no compressed_bytes" "false" "$(echo "$RESPONSE" | jq -r 'has("compressed_bytes")')"
+
+    kill $GZIP_PID 2>/dev/null || true
+    wait $GZIP_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping compressed size test (python3 not available)"
+fi
+
+# Test 2c7: /proxy/cancel aborts an in-flight request by its requestId
+if command -v python3 > /dev/null 2>&1; then
+    CANCEL_PORT=$((PORT + 46))
+    python3 -c '
+import http.server, sys, time
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        time.sleep(10)
+        self.send_response(200)
+        self.send_header("Content-Length", "2")
+        self.end_headers()
+        self.wfile.write(b"ok")
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $CANCEL_PORT &
+    CANCEL_MOCK_PID=$!
+    sleep 1
+
+    CANCEL_OUT=$(mktemp)
+    curl -s -w '\n%{time_total}' -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$CANCEL_PORT/slow\", \"timeout\": 30, \"requestId\": \"tests-cancel-1\"}" > "$CANCEL_OUT" &
+    CANCEL_CURL_PID=$!
+    sleep 1
+
+    STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$CANCEL_PORT/slow\", \"timeout\": 30, \"requestId\": \"tests-cancel-1\"}")
+    check_result "A requestId already in use is rejected" "409" "$STATUS"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/cancel" -H "Content-Type: application/json" -d '{"requestId": "tests-cancel-1"}')
+    check_result "Cancel finds the active request" "true" "$(echo "$RESPONSE" | jq -r '.found')"
+
+    wait $CANCEL_CURL_PID 2>/dev/null || true
+    BODY=$(sed '$d' "$CANCEL_OUT")
+    FAST=$(tail -n 1 "$CANCEL_OUT" | awk '{print ($1 < 5) ? "true" : "false"}')
+    check_result "Cancelled request reports request_cancelled" "request_cancelled" "$(echo "$BODY" | jq -r '.error_type')"
+    check_result "Cancelled request is marked cancelled" "true" "$(echo "$BODY" | jq -r '.cancelled')"
+    check_result "Cancelled request returns without waiting for the upstream" "true" "$FAST"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/cancel" -H "Content-Type: application/json" -d '{"requestId": "tests-cancel-1"}')
+    check_result "Cancel reports a finished request as not found" "false" "$(echo "$RESPONSE" | jq -r '.found')"
+
+    kill $CANCEL_MOCK_PID 2>/dev/null || true
+    wait $CANCEL_MOCK_PID 2>/dev/null || true
+    rm -f "$CANCEL_OUT"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping request cancellation test (python3 not available)"
+fi
+
+# Test 2c8: targets are rotated by weight, and failover skips targets that can't be reached
+if command -v python3 > /dev/null 2>&1; then
+    TARGET_A_PORT=$((PORT + 47))
+    TARGET_B_PORT=$((PORT + 48))
+    TARGET_MOCK='
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        body = sys.argv[2].encode()
+        self.send_response(200)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+'
+    python3 -c "$TARGET_MOCK" $TARGET_A_PORT a &
+    TARGET_A_PID=$!
+    python3 -c "$TARGET_MOCK" $TARGET_B_PORT b &
+    TARGET_B_PID=$!
+    sleep 1
+
+    TARGETS="[{\"url\": \"http://127.0.0.1:$TARGET_A_PORT/\", \"weight\": 3}, {\"url\": \"http://127.0.0.1:$TARGET_B_PORT/\"}]"
+    COUNT_A=0
+    COUNT_B=0
+    for i in $(seq 1 40); do
+        case $(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"targets\": $TARGETS}" | jq -r '.response_data') in
+            a) COUNT_A=$((COUNT_A + 1)) ;;
+            b) COUNT_B=$((COUNT_B + 1)) ;;
+        esac
+    done
+    check_result "Targets are used by weight (3:1 over 40 requests)" "30/10" "$COUNT_A/$COUNT_B"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"targets\": [{\"url\": \"http://127.0.0.1:$TARGET_B_PORT/\"}]}")
+    check_result "Response names the target used" "http://127.0.0.1:$TARGET_B_PORT/" "$(echo "$RESPONSE" | jq -r '.target')"
+
+    DEAD_TARGETS="[{\"url\": \"http://127.0.0.1:1/\"}, {\"url\": \"http://127.0.0.1:$TARGET_A_PORT/\", \"weight\": 0}]"
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"targets\": $DEAD_TARGETS, \"failover\": true}")
+    check_result "Failover reaches the next target" "a" "$(echo "$RESPONSE" | jq -r '.response_data')"
+    check_result "Failover reports the unreachable target" "http://127.0.0.1:1/" "$(echo "$RESPONSE" | jq -r '.failed_targets[0]')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"targets\": $DEAD_TARGETS}")
+    check_result "Without failover the unreachable target's error is returned" "connection_refused" "$(echo "$RESPONSE" | jq -r '.error_type')"
+
+    STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$TARGET_A_PORT/\", \"targets\": $TARGETS}")
+    check_result "url and targets can't be combined" "400" "$STATUS"
+
+    kill $TARGET_A_PID $TARGET_B_PID 2>/dev/null || true
+    wait $TARGET_A_PID $TARGET_B_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping target rotation test (python3 not available)"
+fi
+
+# Test 2c9: ?pretty=true indents the JSON response, streaming metadata stays on one line
+if command -v python3 > /dev/null 2>&1; then
+    PRETTY_PORT=$((PORT + 49))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(200)
+        if self.path == "/sse":
+            self.send_header("Content-Type", "text/event-stream")
+            self.end_headers()
+            self.wfile.write(b"data: hello\n\n")
+            return
+        self.send_header("Content-Type", "text/plain")
+        self.send_header("Content-Length", "2")
+        self.end_headers()
+        self.wfile.write(b"ok")
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $PRETTY_PORT &
+    PRETTY_MOCK_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request?pretty=true" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$PRETTY_PORT/\"}")
+    check_result "pretty=true indents the response" '  "success": true,' "$(echo "$RESPONSE" | sed -n 2p)"
+    check_result "Pretty response is the same JSON" "ok" "$(echo "$RESPONSE" | jq -r '.response_data')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$PRETTY_PORT/\"}")
+    check_result "Responses are compact by default" "1" "$(echo "$RESPONSE" | wc -l | tr -d ' ')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request?pretty=true" -H "Content-Type: application/json" -d '{"method": "GET"}')
+    check_result "pretty=true indents error responses" '  "success": false,' "$(echo "$RESPONSE" | sed -n 2p)"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request?pretty=true" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$PRETTY_PORT/sse\", \"streaming\": true}")
+    check_result "Streaming metadata stays on one line with pretty=true" "true" "$(echo "$RESPONSE" | head -n 1 | jq -r '.success')"
+
+    kill $PRETTY_MOCK_PID 2>/dev/null || true
+    wait $PRETTY_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping pretty-printing test (python3 not available)"
+fi
+
+# Test 2c10: multipart builds a multipart/form-data body from a JSON description of its parts
+if command -v python3 > /dev/null 2>&1; then
+    MULTIPART_PORT=$((PORT + 50))
+    python3 -c '
+import email.parser, email.policy, http.server, json, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers["Content-Length"]))
+        content_type = self.headers["Content-Type"]
+        message = email.parser.BytesParser(policy=email.policy.HTTP).parsebytes(
+            b"Content-Type: " + content_type.encode() + b"\r\n\r\n" + body)
+        boundary = message.get_boundary().encode()
+        framed = (body.startswith(b"--" + boundary + b"\r\n")
+                  and body.endswith(b"\r\n--" + boundary + b"--\r\n")
+                  and body.count(b"--" + boundary) == len(message.get_payload()) + 1)
+        parts = [{"name": part.get_param("name", header="content-disposition"),
+                  "filename": part.get_filename(),
+                  "type": part.get("Content-Type"),
+                  "content": part.get_payload(decode=True).decode("latin-1")} for part in message.get_payload()]
+        out = json.dumps({"type": content_type.split(";")[0], "framed": framed, "parts": parts}).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(out)))
+        self.end_headers()
+        self.wfile.write(out)
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $MULTIPART_PORT &
+    MULTIPART_MOCK_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" -d "{
+        \"method\": \"POST\",
+        \"url\": \"http://127.0.0.1:$MULTIPART_PORT/upload\",
+        \"multipart\": [
+            {\"name\": \"note\", \"content\": \"two files\"},
+            {\"name\": \"report\", \"filename\": \"report.csv\", \"contentType\": \"text/csv\", \"content\": \"id,total\\n1,42\\n\"},
+            {\"name\": \"blob\", \"filename\": \"blob.bin\", \"encoding\": \"base64\", \"content\": \"AAEC/w==\"}
+        ]
+    }")
+    UPLOAD=$(echo "$RESPONSE" | jq -r '.response_data')
+    check_result "multipart sends multipart/form-data" "multipart/form-data" "$(echo "$UPLOAD" | jq -r '.type')"
+    check_result "Multipart body is framed by its boundary" "true" "$(echo "$UPLOAD" | jq -r '.framed')"
+    check_result "Multipart body has all parts in order" "note,report,blob" "$(echo "$UPLOAD" | jq -r '[.parts[].name] | join(",")')"
+    check_result "Field part has no filename" "null" "$(echo "$UPLOAD" | jq -r '.parts[0].filename')"
+    check_result "File part has its filename and type" "report.csv text/csv" "$(echo "$UPLOAD" | jq -r '.parts[1] | "\(.filename) \(.type)"')"
+    check_result "File part has its content" "$(printf 'id,total\n1,42')" "$(echo "$UPLOAD" | jq -r '.parts[1].content')"
+    check_result "base64 part is decoded and defaults to octet-stream" "blob.bin application/octet-stream 4" "$(echo "$UPLOAD" | jq -r '.parts[2] | "\(.filename) \(.type) \(.content | length)"')"
+
+    STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"POST\", \"url\": \"http://127.0.0.1:$MULTIPART_PORT/upload\", \"body\": \"x\", \"multipart\": [{\"name\": \"a\"}]}")
+    check_result "multipart can't be combined with body" "400" "$STATUS"
+
+    kill $MULTIPART_MOCK_PID 2>/dev/null || true
+    wait $MULTIPART_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping multipart builder test (python3 not available)"
+fi
+
+# Test 2c11: normalizeLength reports a Content-Length matching the dechunked body
+if command -v python3 > /dev/null 2>&1; then
+    CHUNKED_PORT=$((PORT + 53))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    protocol_version = "HTTP/1.1"
+    def do_GET(self):
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain")
+        self.send_header("Transfer-Encoding", "chunked")
+        self.end_headers()
+        self.wfile.write(b"5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n")
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $CHUNKED_PORT &
+    CHUNKED_MOCK_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$CHUNKED_PORT/\", \"normalizeLength\": true}")
+    check_result "Chunked body is returned dechunked" "hello world" "$(echo "$RESPONSE" | jq -r '.response_data')"
+    check_result "normalizeLength reports the body's Content-Length" "11" "$(echo "$RESPONSE" | jq -r '.response_headers["content-length"]')"
+    check_result "normalizeLength reports no Transfer-Encoding" "false" "$(echo "$RESPONSE" | jq -r '.response_headers | has("transfer-encoding")')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$CHUNKED_PORT/\"}")
+    check_result "Chunked response has no Content-Length by default" "false" "$(echo "$RESPONSE" | jq -r '.response_headers | has("content-length")')"
+
+    kill $CHUNKED_MOCK_PID 2>/dev/null || true
+    wait $CHUNKED_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping normalizeLength test (python3 not available)"
+fi
+
+# Test 2c12: redirectMode return-location returns a redirect's Location instead of following it
+if command -v python3 > /dev/null 2>&1; then
+    REDIRECT_PORT=$((PORT + 54))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(302)
+        if self.path == "/app":
+            self.send_header("Location", "myapp://callback?code=abc123")
+        else:
+            self.send_header("Location", "/callback?code=abc123&state=xyz")
+        self.send_header("Content-Length", "0")
+        self.end_headers()
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $REDIRECT_PORT &
+    REDIRECT_MOCK_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$REDIRECT_PORT/authorize\", \"redirectMode\": \"return-location\"}")
+    check_result "return-location answers the redirect successfully" "true 302" "$(echo "$RESPONSE" | jq -r '"\(.success) \(.response_status)"')"
+    check_result "return-location reports the resolved Location" "http://127.0.0.1:$REDIRECT_PORT/callback?code=abc123&state=xyz" "$(echo "$RESPONSE" | jq -r '.redirect_location')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$REDIRECT_PORT/app\", \"redirectMode\": \"return-location\"}")
+    check_result "return-location keeps a custom-scheme Location" "myapp://callback?code=abc123" "$(echo "$RESPONSE" | jq -r '.redirect_location')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$REDIRECT_PORT/authorize\", \"followRedirects\": false}")
+    check_result "followRedirects false still reports redirect_not_followed" "redirect_not_followed" "$(echo "$RESPONSE" | jq -r '.error_type')"
+
+    STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$REDIRECT_PORT/authorize\", \"redirectMode\": \"follow-once\"}")
+    check_result "Unknown redirectMode is rejected" "400" "$STATUS"
+
+    kill $REDIRECT_MOCK_PID 2>/dev/null || true
+    wait $REDIRECT_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping redirectMode test (python3 not available)"
+fi
+
+# Test 2c13: normalizeText strips a UTF-8 BOM and CRLF line endings from text responses only
+if command -v python3 > /dev/null 2>&1; then
+    TEXT_PORT=$((PORT + 58))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        if self.path == "/binary":
+            body, content_type = b"\xef\xbb\xbf\x00\x01\r\n", "application/octet-stream"
+        elif self.path == "/json":
+            body, content_type = b"\xef\xbb\xbf{\"ok\": true}", "application/json"
+        else:
+            body, content_type = b"\xef\xbb\xbfline one\r\nline two\r\n", "text/plain"
+        self.send_response(200)
+        self.send_header("Content-Type", content_type)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $TEXT_PORT &
+    TEXT_MOCK_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$TEXT_PORT/text\", \"normalizeText\": \"strip-bom\"}")
+    check_result "normalizeText strip-bom removes the BOM and keeps CRLF" '"line one\r\nline two\r\n"' "$(echo "$RESPONSE" | jq '.response_data')"
+    check_result "normalizeText updates content-length" "20" "$(echo "$RESPONSE" | jq -r '.response_headers["content-length"]')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$TEXT_PORT/text\", \"normalizeText\": \"line-endings\"}")
+    check_result "normalizeText line-endings turns CRLF into LF" '"line one\nline two\n"' "$(echo "$RESPONSE" | jq '.response_data')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$TEXT_PORT/text\"}")
+    check_result "Text responses keep their BOM by default" "efbbbf" "$(echo "$RESPONSE" | jq -j '.response_data' | head -c 3 | od -An -tx1 | tr -d ' \n')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$TEXT_PORT/json\", \"normalizeText\": \"strip-bom\", \"parseJSON\": true}")
+    check_result "normalizeText lets parseJSON embed a BOM-prefixed JSON body" "true" "$(echo "$RESPONSE" | jq -r '.response_json.ok')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$TEXT_PORT/binary\", \"normalizeText\": \"line-endings\"}")
+    check_result "normalizeText leaves binary responses untouched" "77u/AAENCg==" "$(echo "$RESPONSE" | jq -r '.response_data')"
+
+    STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$TEXT_PORT/text\", \"normalizeText\": \"unix\"}")
+    check_result "Unknown normalizeText is rejected" "400" "$STATUS"
+
+    kill $TEXT_MOCK_PID 2>/dev/null || true
+    wait $TEXT_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping normalizeText test (python3 not available)"
+fi
+
+# Test 2c14: serverTiming reports the upstream request's phases in a Server-Timing header
+if command -v python3 > /dev/null 2>&1; then
+    TIMING_PORT=$((PORT + 60))
+    python3 -c '
+import http.server, sys, time
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        time.sleep(1 if "slow" in self.path else 0.2)
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain")
+        self.send_header("Content-Length", "2")
+        self.end_headers()
+        self.wfile.write(b"ok")
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $TIMING_PORT &
+    TIMING_MOCK_PID=$!
+    sleep 1
+
+    SERVER_TIMING=$(curl -s -D - -o /dev/null -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://localhost:$TIMING_PORT/\", \"serverTiming\": true}" | grep -i '^server-timing:' | cut -d' ' -f2- | tr -d '\r')
+    if echo "$SERVER_TIMING" | grep -Eq '^dns;dur=[0-9]+\.[0-9]{2}, connect;dur=[0-9]+\.[0-9]{2}, ttfb;dur=[0-9]+\.[0-9]{2}, total;dur=[0-9]+\.[0-9]{2}$'; then
+        FORMAT_OK="true"
+    else
+        FORMAT_OK="false: $SERVER_TIMING"
+    fi
+    check_result "Server-Timing lists dns, connect, ttfb and total (no tls over http)" "true" "$FORMAT_OK"
+    TTFB=$(echo "$SERVER_TIMING" | sed -n 's/.*ttfb;dur=\([0-9.]*\).*/\1/p')
+    TOTAL=$(echo "$SERVER_TIMING" | sed -n 's/.*total;dur=\([0-9.]*\).*/\1/p')
+    check_result "Server-Timing ttfb covers the upstream's delay and is within total" "true" "$(awk -v t="$TTFB" -v total="$TOTAL" 'BEGIN { print (t >= 200 && t <= total) ? "true" : "false" }')"
+
+    HEADERS=$(curl -s -D - -o /dev/null -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://localhost:$TIMING_PORT/\"}")
+    check_result "No Server-Timing header without serverTiming" "0" "$(echo "$HEADERS" | grep -ci '^server-timing:')"
+
+    # Cached and coalesced responses say so instead of reporting upstream phases
+    TIMING_CACHE_PORT=$((PORT + 79))
+    ./build/rbite-proxy --port $TIMING_CACHE_PORT --response-cache-ttl 60s --coalesce-requests --no-upgrade-check > /tmp/proxy-timingcache.log 2>&1 &
+    TIMING_CACHE_PID=$!
+    sleep 1
+
+    # Prints the Server-Timing header of a serverTiming request to the given mock path
+    timing_header() {
+        curl -s -D - -o /dev/null -X POST "http://localhost:$TIMING_CACHE_PORT/proxy/request" -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"url\": \"http://localhost:$TIMING_PORT/$1\", \"serverTiming\": true}" | grep -i '^server-timing:' | cut -d' ' -f2- | tr -d '\r'
+    }
+    MISS_TIMING=$(timing_header cached)
+    HIT_TIMING=$(timing_header cached)
+    check_result "Server-Timing of a cache miss reports the upstream phases" "true" "$(echo "$MISS_TIMING" | grep -q 'ttfb;dur=' && echo true || echo false)"
+    check_result "Server-Timing of a cache hit is cache;desc=hit and total" "true" "$(echo "$HIT_TIMING" | grep -Eq '^cache;desc=hit, total;dur=[0-9]+\.[0-9]{2}$' && echo true || echo "false: $HIT_TIMING")"
+
+    timing_header slow > /tmp/timing-leader.txt &
+    TIMING_LEADER_PID=$!
+    sleep 0.3
+    FOLLOWER_TIMING=$(timing_header slow)
+    wait $TIMING_LEADER_PID
+    check_result "Server-Timing of the coalesced call's first request reports the upstream phases" "true" "$(grep -q 'ttfb;dur=' /tmp/timing-leader.txt && echo true || echo false)"
+    check_result "Server-Timing of a coalesced request is coalesced and total" "true" "$(echo "$FOLLOWER_TIMING" | grep -Eq '^coalesced, total;dur=[0-9]+\.[0-9]{2}$' && echo true || echo "false: $FOLLOWER_TIMING")"
+
+    kill $TIMING_CACHE_PID $TIMING_MOCK_PID 2>/dev/null || true
+    wait $TIMING_CACHE_PID $TIMING_MOCK_PID 2>/dev/null || true
+    rm -f /tmp/timing-leader.txt
+else
+    echo -e "${YELLOW}⚠${NC} Skipping serverTiming test (python3 not available)"
+fi
+
+# Test 2c15: captured bodies are written to files in --body-capture-dir and removed after the TTL
+if command -v python3 > /dev/null 2>&1; then
+    CAPTURE_MOCK_PORT=$((PORT + 66))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers["Content-Length"]))
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain")
+        self.send_header("Content-Length", str(len(body) + 7))
+        self.end_headers()
+        self.wfile.write(b"echoed " + body)
+    def log_message(self, *args):
+        pass
+http.server.HTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $CAPTURE_MOCK_PORT &
+    CAPTURE_MOCK_PID=$!
+    CAPTURE_DIR=$(mktemp -d)
+    CAPTURE_PORT=$((PORT + 67))
+    ./build/rbite-proxy --port $CAPTURE_PORT --enable-body-capture --body-capture-dir "$CAPTURE_DIR" --body-capture-ttl 2s --no-upgrade-check > /tmp/proxy-capture.log 2>&1 &
+    CAPTURE_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "http://localhost:$CAPTURE_PORT/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"POST\", \"url\": \"http://127.0.0.1:$CAPTURE_MOCK_PORT/\", \"body\": \"captured body\", \"captureRequestBody\": true, \"captureResponseBody\": true}")
+    REQUEST_FILE=$(echo "$RESPONSE" | jq -r '.request_body_file')
+    RESPONSE_FILE=$(echo "$RESPONSE" | jq -r '.response_body_file')
+    check_result "Captured body files are created in --body-capture-dir" "$CAPTURE_DIR $CAPTURE_DIR" "$(dirname "$REQUEST_FILE") $(dirname "$RESPONSE_FILE")"
+    check_result "Captured request body file holds the request body" "captured body" "$(cat "$REQUEST_FILE")"
+    check_result "Captured response body file holds the response body" "echoed captured body" "$(cat "$RESPONSE_FILE")"
+
+    sleep 3
+    check_result "Captured body files are removed after --body-capture-ttl" "0" "$(ls -A "$CAPTURE_DIR" | wc -l | tr -d ' ')"
+
+    # Without a capture directory to write to, the request fails before it is sent
+    rmdir "$CAPTURE_DIR"
+    RESPONSE=$(curl -s -X POST "http://localhost:$CAPTURE_PORT/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"POST\", \"url\": \"http://127.0.0.1:$CAPTURE_MOCK_PORT/\", \"body\": \"captured body\", \"captureRequestBody\": true}")
+    check_result "Failed request body capture reports body_capture_error" "body_capture_error" "$(echo "$RESPONSE" | jq -r '.error_type')"
+
+    kill $CAPTURE_PID $CAPTURE_MOCK_PID 2>/dev/null || true
+    wait $CAPTURE_PID $CAPTURE_MOCK_PID 2>/dev/null || true
+    rm -rf "$CAPTURE_DIR"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping body capture test (python3 not available)"
+fi
+
+# Test 2c16: http10 sends an HTTP/1.0 request line and closeConnection sends Connection: close
+if command -v python3 > /dev/null 2>&1; then
+    PROTO_PORT=$((PORT + 68))
+    python3 -c '
+import http.server, json, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        body = json.dumps({"proto": self.request_version, "connection": self.headers.get("Connection", "")}).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $PROTO_PORT &
+    PROTO_MOCK_PID=$!
+    sleep 1
+
+    proto_request() {
+        curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"url\": \"http://localhost:$PROTO_PORT/\"$1}" | jq -r '.response_data' | jq -r '"\(.proto) \(.connection)"'
+    }
+    check_result "Requests use HTTP/1.1 by default" "HTTP/1.1 " "$(proto_request '')"
+    check_result "http10 sends an HTTP/1.0 request line" "HTTP/1.0 " "$(proto_request ', "http10": true')"
+    check_result "closeConnection sends Connection: close" "HTTP/1.1 close" "$(proto_request ', "closeConnection": true')"
+    check_result "closeConnection sends Connection: close over HTTP/1.0" "HTTP/1.0 close" "$(proto_request ', "http10": true, "closeConnection": true')"
+
+    kill $PROTO_MOCK_PID 2>/dev/null || true
+    wait $PROTO_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping http10 and closeConnection test (python3 not available)"
+fi
+
+# Test 2c17: --content-types overrides whether a response's Content-Type is treated as binary or text
+if command -v python3 > /dev/null 2>&1; then
+    CTYPE_MOCK_PORT=$((PORT + 69))
+    python3 -c '
+import http.server, sys, urllib.parse
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        content_type = urllib.parse.parse_qs(urllib.parse.urlparse(self.path).query)["type"][0]
+        self.send_response(200)
+        self.send_header("Content-Type", content_type)
+        self.send_header("Content-Length", "5")
+        self.end_headers()
+        self.wfile.write(b"hello")
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $CTYPE_MOCK_PORT &
+    CTYPE_MOCK_PID=$!
+    CTYPE_FILE=$(mktemp)
+    printf '# Overrides for the test\napplication/wasm: binary\napplication/x-ndjson: text\n' > "$CTYPE_FILE"
+    CTYPE_PORT=$((PORT + 70))
+    ./build/rbite-proxy --port $CTYPE_PORT --content-types "$CTYPE_FILE" --no-upgrade-check > /tmp/proxy-ctypes.log 2>&1 &
+    CTYPE_PID=$!
+    sleep 1
+
+    # Prints is_binary and the body as returned for the given upstream Content-Type
+    ctype_request() {
+        curl -s -X POST "$1/proxy/request" -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$CTYPE_MOCK_PORT/?type=$2\"}" | jq -r '"\(.is_binary // false) \(.response_data)"'
+    }
+    check_result "application/wasm is text by default" "false hello" "$(ctype_request "$PROXY_URL" application/wasm)"
+    check_result "--content-types classifies application/wasm as binary" "true aGVsbG8=" "$(ctype_request "http://localhost:$CTYPE_PORT" application/wasm)"
+    check_result "application/x-ndjson is binary by default" "true aGVsbG8=" "$(ctype_request "$PROXY_URL" application/x-ndjson)"
+    check_result "--content-types classifies application/x-ndjson as text" "false hello" "$(ctype_request "http://localhost:$CTYPE_PORT" application/x-ndjson)"
+
+    kill $CTYPE_PID $CTYPE_MOCK_PID 2>/dev/null || true
+    wait $CTYPE_PID $CTYPE_MOCK_PID 2>/dev/null || true
+    rm -f "$CTYPE_FILE"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping content types override test (python3 not available)"
+fi
+
+# Test 2c18: --response-headers removes and sets upstream response headers
+if command -v python3 > /dev/null 2>&1; then
+    RULES_MOCK_PORT=$((PORT + 71))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain")
+        self.send_header("X-Frame-Options", "DENY")
+        self.send_header("X-Upstream", "kept")
+        self.send_header("Content-Length", "2")
+        self.end_headers()
+        self.wfile.write(b"ok")
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $RULES_MOCK_PORT &
+    RULES_MOCK_PID=$!
+    RULES_FILE=$(mktemp)
+    printf '# Rules for the test\nremove X-Frame-Options\nset X-Proxied-By: rb-slingshot\n' > "$RULES_FILE"
+    RULES_PORT=$((PORT + 72))
+    ./build/rbite-proxy --port $RULES_PORT --response-headers "$RULES_FILE" --no-upgrade-check > /tmp/proxy-rules.log 2>&1 &
+    RULES_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "http://localhost:$RULES_PORT/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$RULES_MOCK_PORT/\"}")
+    check_result "Header rule removes X-Frame-Options" "null" "$(echo "$RESPONSE" | jq -r '.response_headers["x-frame-options"]')"
+    check_result "Header rule sets a header" "rb-slingshot" "$(echo "$RESPONSE" | jq -r '.response_headers["x-proxied-by"]')"
+    check_result "Headers without a rule are kept" "kept" "$(echo "$RESPONSE" | jq -r '.response_headers["x-upstream"]')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$RULES_MOCK_PORT/\"}")
+    check_result "Headers are returned unchanged without --response-headers" "DENY null" "$(echo "$RESPONSE" | jq -r '"\(.response_headers["x-frame-options"]) \(.response_headers["x-proxied-by"])"')"
+
+    kill $RULES_PID $RULES_MOCK_PID 2>/dev/null || true
+    wait $RULES_PID $RULES_MOCK_PID 2>/dev/null || true
+    rm -f "$RULES_FILE"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping response header rules test (python3 not available)"
+fi
+
+# Test 2c19: [SSE-DEBUG] lines are only logged with --debug, not with --logging alone
+if command -v python3 > /dev/null 2>&1; then
+    DEBUG_MOCK_PORT=$((PORT + 76))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(200)
+        self.send_header("Content-Type", "text/event-stream")
+        self.end_headers()
+        self.wfile.write(b"data: hello\n\n")
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $DEBUG_MOCK_PORT &
+    DEBUG_MOCK_PID=$!
+    NODEBUG_PORT=$((PORT + 77))
+    DEBUG_PORT=$((PORT + 78))
+    ./build/rbite-proxy --port $NODEBUG_PORT --logging --no-upgrade-check > /tmp/proxy-nodebug.log 2>&1 &
+    NODEBUG_PID=$!
+    ./build/rbite-proxy --port $DEBUG_PORT --debug --no-upgrade-check > /tmp/proxy-debug.log 2>&1 &
+    DEBUG_PID=$!
+    sleep 1
+
+    for DEBUG_TARGET in $NODEBUG_PORT $DEBUG_PORT; do
+        curl -s -o /dev/null -X POST "http://localhost:$DEBUG_TARGET/proxy/request" -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$DEBUG_MOCK_PORT/\", \"headers\": [], \"streaming\": true}"
+    done
+    check_result "No [SSE-DEBUG] output without --debug" "0" "$(grep -c 'SSE-DEBUG' /tmp/proxy-nodebug.log)"
+    check_result "--debug logs [SSE-DEBUG] lines through the proxy logger" "true" "$(grep -q '\[PROXY\].*\[SSE-DEBUG\] SSE streaming completed' /tmp/proxy-debug.log && echo true || echo false)"
+
+    kill $NODEBUG_PID $DEBUG_PID $DEBUG_MOCK_PID 2>/dev/null || true
+    wait $NODEBUG_PID $DEBUG_PID $DEBUG_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping --debug logging test (python3 not available)"
+fi
+
+# Test 2d: pinnedCertSHA256 aborts the request when the certificate doesn't match
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/get",
+        "timeout": 10,
+        "pinnedCertSHA256": "0000000000000000000000000000000000000000000000000000000000000000"
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Mismatching certificate pin fails with cert_pin_mismatch" "cert_pin_mismatch" "$ERROR_TYPE"
+
+# Test 2e: pinnedCertSHA256 matching the served certificate lets the request through
+if command -v openssl > /dev/null 2>&1; then
+    PIN=$(openssl s_client -connect httpbin.org:443 -servername httpbin.org < /dev/null 2>/dev/null | openssl x509 -outform der 2>/dev/null | openssl dgst -sha256 -hex | awk '{print $NF}')
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"https://httpbin.org/get\",
+            \"timeout\": 10,
+            \"pinnedCertSHA256\": \"$PIN\"
+        }")
+    SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+    check_result "Matching certificate pin succeeds" "true" "$SUCCESS"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping matching certificate pin test (openssl not available)"
+fi
+
 # Test 3: Redirect with followRedirects=false
 RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
     -H "Content-Type: application/json" \
@@ -185,93 +1192,1976 @@ RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
 HAS_SUCCESS=$(echo "$RESPONSE" | jq 'has("success")')
 check_result "PassThrough=false returns JSON wrapper" "true" "$HAS_SUCCESS"
 
-# Test 6: PassThrough=true with JSON response
-RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+# Test 6: PassThrough=true with JSON response
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/json",
+        "headers": [],
+        "timeout": 10,
+        "passThrough": true
+    }')
+HAS_SLIDESHOW=$(echo "$RESPONSE" | jq 'has("slideshow")')
+check_result "PassThrough=true returns raw JSON" "true" "$HAS_SLIDESHOW"
+
+# Test parseJSON embeds the upstream JSON as a nested object
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/json",
+        "headers": [],
+        "timeout": 10,
+        "parseJSON": true
+    }')
+TITLE=$(echo "$RESPONSE" | jq -r '.response_json.slideshow.title')
+HAS_DATA=$(echo "$RESPONSE" | jq 'has("response_data")')
+check_result "parseJSON returns nested response_json" "Sample Slide Show" "$TITLE"
+check_result "parseJSON omits response_data" "false" "$HAS_DATA"
+
+# Test parseJSON falls back to response_data for non-JSON bodies
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/html",
+        "headers": [],
+        "timeout": 10,
+        "parseJSON": true
+    }')
+HAS_JSON=$(echo "$RESPONSE" | jq 'has("response_json")')
+CONTAINS_HTML=$(echo "$RESPONSE" | jq -r '.response_data' | grep -q "<html>" && echo "true" || echo "false")
+check_result "parseJSON on HTML omits response_json" "false" "$HAS_JSON"
+check_result "parseJSON on HTML keeps response_data" "true" "$CONTAINS_HTML"
+
+# Test 7: PassThrough=true with HTML response
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/html",
+        "headers": [],
+        "timeout": 10,
+        "passThrough": true
+    }')
+CONTAINS_HTML=$(echo "$RESPONSE" | grep -q "<html>" && echo "true" || echo "false")
+check_result "PassThrough=true returns raw HTML" "true" "$CONTAINS_HTML"
+
+# HTML is neutralized to text/plain unless allowlisted
+CONTENT_TYPE=$(curl -s -o /dev/null -w '%{content_type}' -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/html",
+        "headers": [],
+        "timeout": 10,
+        "passThrough": true
+    }')
+check_result "PassThrough HTML is served as text/plain by default" "text/plain; charset=utf-8" "$CONTENT_TYPE"
+
+# Second instance defaulting to pass-through mode, with HTML allowlisted
+PASSTHROUGH_PORT=$((PORT + 1))
+PASS_THROUGH_TYPES=$(mktemp)
+echo "text/html: allow" > "$PASS_THROUGH_TYPES"
+./build/rbite-proxy --port $PASSTHROUGH_PORT --default-pass-through --pass-through-types "$PASS_THROUGH_TYPES" --no-upgrade-check > /tmp/proxy-passthrough.log 2>&1 &
+PASSTHROUGH_PID=$!
+sleep 1
+
+# passThrough omitted uses the server default
+RESPONSE=$(curl -s -X POST "http://localhost:$PASSTHROUGH_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/json",
+        "headers": [],
+        "timeout": 10
+    }')
+HAS_SLIDESHOW=$(echo "$RESPONSE" | jq 'has("slideshow")')
+check_result "Default pass-through applies when passThrough is omitted" "true" "$HAS_SLIDESHOW"
+
+# Explicit passThrough=false overrides the server default
+RESPONSE=$(curl -s -X POST "http://localhost:$PASSTHROUGH_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/json",
+        "headers": [],
+        "timeout": 10,
+        "passThrough": false
+    }')
+HAS_SUCCESS=$(echo "$RESPONSE" | jq 'has("success")')
+check_result "Explicit passThrough=false overrides default pass-through" "true" "$HAS_SUCCESS"
+
+# Allowlisted HTML keeps its original content type
+CONTENT_TYPE=$(curl -s -o /dev/null -w '%{content_type}' -X POST "http://localhost:$PASSTHROUGH_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/html",
+        "headers": [],
+        "timeout": 10
+    }')
+check_result "Allowlisted HTML keeps text/html in pass-through" "text/html; charset=utf-8" "$CONTENT_TYPE"
+
+kill $PASSTHROUGH_PID 2>/dev/null || true
+wait $PASSTHROUGH_PID 2>/dev/null || true
+rm -f "$PASS_THROUGH_TYPES"
+
+# Test omitUserAgent sends no User-Agent header at all
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/headers",
+        "headers": [],
+        "timeout": 10,
+        "omitUserAgent": true
+    }')
+HAS_UA=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .headers | has("User-Agent")')
+check_result "omitUserAgent sends no User-Agent" "false" "$HAS_UA"
+
+# Test a chunked client body is streamed through to the upstream (described in X-Slingshot-Request)
+# httpbin doesn't read chunked request bodies, so a local listener echoes the raw request
+if command -v python3 > /dev/null 2>&1; then
+    CHUNKED_PORT=$((PORT + 10))
+    python3 -c '
+import socket, sys
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+data = b""
+while not data.endswith(b"0\r\n\r\n"):
+    chunk = c.recv(4096)
+    if not chunk:
+        break
+    data += chunk
+c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nConnection: close\r\nContent-Length: %d\r\n\r\n" % len(data) + data)
+c.close()
+' $CHUNKED_PORT &
+    CHUNKED_PID=$!
+    sleep 1
+
+    RESPONSE=$( (echo "first chunk"; sleep 1; echo "second chunk") | curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "X-Slingshot-Request: {\"method\": \"POST\", \"url\": \"http://127.0.0.1:$CHUNKED_PORT/\", \"headers\": [], \"timeout\": 10}" \
+        -H "Transfer-Encoding: chunked" \
+        --data-binary @-)
+    RAW_REQUEST=$(echo "$RESPONSE" | jq -r '.response_data')
+    SENT_CHUNKED=$(echo "$RAW_REQUEST" | grep -qi '^Transfer-Encoding: chunked' && echo "true" || echo "false")
+    SENT_LENGTH=$(echo "$RAW_REQUEST" | grep -qi '^Content-Length:' && echo "true" || echo "false")
+    SENT_BODY=$(echo "$RAW_REQUEST" | grep -c 'chunk$')
+    check_result "Chunked client body is sent upstream chunked" "true" "$SENT_CHUNKED"
+    check_result "Chunked client body is sent without Content-Length" "false" "$SENT_LENGTH"
+    check_result "Chunked client body reaches the upstream" "2" "$SENT_BODY"
+
+    kill $CHUNKED_PID 2>/dev/null || true
+    wait $CHUNKED_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping chunked body test (python3 not available)"
+fi
+
+# Test requestEncoding compresses the body the upstream receives, and compressed client bodies are decoded
+if command -v python3 > /dev/null 2>&1; then
+    GZIP_ECHO_PORT=$((PORT + 18))
+    DECOMPRESS_PORT=$((PORT + 19))
+    python3 -c '
+import http.server, json, sys, zlib
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers.get("Content-Length", 0)))
+        try:
+            decoded = zlib.decompress(body, 47).decode()
+        except zlib.error:
+            decoded = body.decode(errors="replace")
+        out = json.dumps({"encoding": self.headers.get("Content-Encoding", ""), "body": decoded}).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(out)))
+        self.end_headers()
+        self.wfile.write(out)
+    def log_message(self, *args):
+        pass
+http.server.HTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $GZIP_ECHO_PORT &
+    GZIP_ECHO_PID=$!
+    ./build/rbite-proxy --port $DECOMPRESS_PORT --decompress-request-bodies --no-upgrade-check > /tmp/proxy-decompress.log 2>&1 &
+    DECOMPRESS_PID=$!
+    sleep 1
+
+    for ENCODING in gzip deflate; do
+        RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+            -H "Content-Type: application/json" \
+            -d "{
+                \"method\": \"POST\",
+                \"url\": \"http://127.0.0.1:$GZIP_ECHO_PORT/\",
+                \"headers\": [\"Content-Type: application/json\"],
+                \"body\": \"{\\\"hello\\\": \\\"world\\\"}\",
+                \"requestEncoding\": \"$ENCODING\",
+                \"timeout\": 10
+            }")
+        SENT_ENCODING=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .encoding')
+        SENT_BODY=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .body | fromjson | .hello')
+        check_result "requestEncoding $ENCODING sets Content-Encoding upstream" "$ENCODING" "$SENT_ENCODING"
+        check_result "requestEncoding $ENCODING body decompresses to the original" "world" "$SENT_BODY"
+    done
+
+    RESPONSE=$(echo "{\"method\": \"POST\", \"url\": \"http://127.0.0.1:$GZIP_ECHO_PORT/\", \"headers\": [], \"body\": \"plain text\", \"timeout\": 10}" | gzip | \
+        curl -s -X POST "http://localhost:$DECOMPRESS_PORT/proxy/request" \
+            -H "Content-Type: application/json" \
+            -H "Content-Encoding: gzip" \
+            --data-binary @-)
+    SENT_BODY=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .body')
+    check_result "Gzipped client request JSON is decoded with --decompress-request-bodies" "plain text" "$SENT_BODY"
+
+    kill $DECOMPRESS_PID $GZIP_ECHO_PID 2>/dev/null || true
+    wait $DECOMPRESS_PID $GZIP_ECHO_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping request body encoding test (python3 not available)"
+fi
+
+# Test that a body without Content-Type is sniffed, so a PNG is detected and returned as binary
+if command -v python3 > /dev/null 2>&1; then
+    SNIFF_PORT=$((PORT + 22))
+    python3 -c '
+import base64, socket, sys
+png = base64.b64decode("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg==")
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+data = b""
+while b"\r\n\r\n" not in data:
+    chunk = c.recv(4096)
+    if not chunk:
+        break
+    data += chunk
+c.sendall(b"HTTP/1.1 200 OK\r\nConnection: close\r\nContent-Length: %d\r\n\r\n" % len(png) + png)
+c.close()
+' $SNIFF_PORT &
+    SNIFF_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$SNIFF_PORT/pixel\", \"headers\": [], \"timeout\": 10}")
+    CONTENT_TYPE=$(echo "$RESPONSE" | jq -r '.content_type')
+    IS_BINARY=$(echo "$RESPONSE" | jq -r '.is_binary')
+    PNG_SIGNATURE=$(echo "$RESPONSE" | jq -r '.response_data' | base64 -d 2>/dev/null | head -c 4 | tail -c 3)
+    check_result "Untyped PNG body is sniffed as image/png" "image/png" "$CONTENT_TYPE"
+    check_result "Sniffed PNG body is treated as binary" "true" "$IS_BINARY"
+    check_result "Sniffed PNG body is base64 encoded intact" "PNG" "$PNG_SIGNATURE"
+
+    kill $SNIFF_PID 2>/dev/null || true
+    wait $SNIFF_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping content type sniffing test (python3 not available)"
+fi
+
+# Test includeInformational reports 103 Early Hints sent before the final response
+if command -v python3 > /dev/null 2>&1; then
+    HINTS_PORT=$((PORT + 12))
+    python3 -c '
+import socket, sys
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+data = b""
+while b"\r\n\r\n" not in data:
+    chunk = c.recv(4096)
+    if not chunk:
+        break
+    data += chunk
+c.sendall(b"HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload; as=style\r\n\r\n")
+c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nConnection: close\r\nContent-Length: 2\r\n\r\nok")
+c.close()
+' $HINTS_PORT &
+    HINTS_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$HINTS_PORT/\",
+            \"headers\": [],
+            \"timeout\": 10,
+            \"includeInformational\": true
+        }")
+    HINT_STATUS=$(echo "$RESPONSE" | jq -r '.informational_responses[0].status')
+    HINT_LINK=$(echo "$RESPONSE" | jq -r '.informational_responses[0].headers.link')
+    FINAL_STATUS=$(echo "$RESPONSE" | jq -r '.response_status')
+    check_result "includeInformational reports 103 Early Hints" "103" "$HINT_STATUS"
+    check_result "Early Hints Link header is reported" "</style.css>; rel=preload; as=style" "$HINT_LINK"
+    check_result "Final response follows the Early Hints" "200" "$FINAL_STATUS"
+
+    kill $HINTS_PID 2>/dev/null || true
+    wait $HINTS_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping Early Hints test (python3 not available)"
+fi
+
+# Test orderedHeaders returns response headers in the order and case they were sent
+if command -v python3 > /dev/null 2>&1; then
+    ORDER_PORT=$((PORT + 24))
+    python3 -c '
+import socket, sys
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+data = b""
+while b"\r\n\r\n" not in data:
+    chunk = c.recv(4096)
+    if not chunk:
+        break
+    data += chunk
+c.sendall(b"HTTP/1.1 200 OK\r\nzeta: 1\r\nX-Middle: 2\r\nSet-Cookie: a=1\r\nalpha: 3\r\nSet-Cookie: b=2\r\nContent-Type: text/plain\r\nConnection: close\r\nContent-Length: 2\r\n\r\nok")
+c.close()
+' $ORDER_PORT &
+    ORDER_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$ORDER_PORT/\",
+            \"headers\": [],
+            \"timeout\": 10,
+            \"orderedHeaders\": true
+        }")
+    HEADER_ORDER=$(echo "$RESPONSE" | jq -r '[.response_header_list[].name] | join(",")')
+    COOKIE_ORDER=$(echo "$RESPONSE" | jq -r '[.response_header_list[] | select(.name == "Set-Cookie") | .value] | join(",")')
+    check_result "orderedHeaders keeps the HTTP/1.1 header order and name case" "zeta,X-Middle,Set-Cookie,alpha,Set-Cookie,Content-Type,Content-Length" "$HEADER_ORDER"
+    check_result "orderedHeaders keeps repeated headers in order" "a=1,b=2" "$COOKIE_ORDER"
+
+    kill $ORDER_PID 2>/dev/null || true
+    wait $ORDER_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping ordered response headers test (python3 not available)"
+fi
+
+# Test /proxy/preflight forwards a CORS preflight to the upstream and evaluates its answer
+if command -v python3 > /dev/null 2>&1; then
+    CORS_PORT=$((PORT + 28))
+    python3 -c '
+import sys
+from http.server import BaseHTTPRequestHandler, HTTPServer
+class Handler(BaseHTTPRequestHandler):
+    def do_OPTIONS(self):
+        self.send_response(204)
+        if self.headers.get("Origin") == "https://app.example.com":
+            self.send_header("Access-Control-Allow-Origin", "https://app.example.com")
+        self.send_header("Access-Control-Allow-Methods", "GET, PUT")
+        self.send_header("Access-Control-Allow-Headers", "content-type, x-token")
+        self.end_headers()
+    def log_message(self, *args):
+        pass
+HTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $CORS_PORT &
+    CORS_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/preflight" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"url\": \"http://127.0.0.1:$CORS_PORT/api\",
+            \"origin\": \"https://app.example.com\",
+            \"requestMethod\": \"PUT\",
+            \"requestHeaders\": [\"X-Token\", \"Content-Type\"],
+            \"timeout\": 10
+        }")
+    ALLOW_ORIGIN=$(echo "$RESPONSE" | jq -r '.cors_headers["access-control-allow-origin"]')
+    ALLOWED=$(echo "$RESPONSE" | jq -r '.allowed')
+    check_result "Forwarded preflight returns the upstream's CORS headers" "https://app.example.com" "$ALLOW_ORIGIN"
+    check_result "Preflight allowed by the upstream is reported as allowed" "true" "$ALLOWED"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/preflight" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"url\": \"http://127.0.0.1:$CORS_PORT/api\",
+            \"origin\": \"https://other.example.com\",
+            \"requestMethod\": \"DELETE\",
+            \"timeout\": 10
+        }")
+    VERDICT=$(echo "$RESPONSE" | jq -r '[.origin_allowed, .method_allowed, .allowed] | map(tostring) | join(",")')
+    check_result "Preflight from another origin with an unlisted method is refused" "false,false,false" "$VERDICT"
+
+    kill $CORS_PID 2>/dev/null || true
+    wait $CORS_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping forwarded preflight test (python3 not available)"
+fi
+
+# OPTIONS to the proxy itself is still answered by the proxy's own CORS handling
+ALLOW_ORIGIN=$(curl -s -D - -o /dev/null -X OPTIONS "$PROXY_URL/proxy/request" | grep -i '^access-control-allow-origin:' | cut -d' ' -f2 | tr -d '\r')
+check_result "Proxy answers its own preflight" "*" "$ALLOW_ORIGIN"
+
+# Test includeHeaderDiff compares the outbound request headers with the response headers
+if command -v python3 > /dev/null 2>&1; then
+    DIFF_PORT=$((PORT + 33))
+    python3 -c '
+import sys
+from http.server import BaseHTTPRequestHandler, HTTPServer
+class Handler(BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(200)
+        self.send_header("X-Same", self.headers.get("X-Same", ""))
+        self.send_header("X-Mode", "response")
+        self.send_header("X-Added", "yes")
+        self.send_header("Content-Length", "0")
+        self.end_headers()
+    def log_message(self, *args):
+        pass
+HTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $DIFF_PORT &
+    DIFF_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$DIFF_PORT/\",
+            \"headers\": [\"X-Same: kept\", \"X-Mode: request\", \"X-Removed: gone\"],
+            \"timeout\": 10,
+            \"includeHeaderDiff\": true
+        }")
+    ADDED=$(echo "$RESPONSE" | jq -r '.header_diff.added["x-added"]')
+    REMOVED=$(echo "$RESPONSE" | jq -r '.header_diff.removed["x-removed"]')
+    CHANGED=$(echo "$RESPONSE" | jq -r '.header_diff.changed["x-mode"] | "\(.request)->\(.response)"')
+    UNCHANGED=$(echo "$RESPONSE" | jq -r '[.header_diff[] | has("x-same")] | any')
+    check_result "Header diff lists headers added by the response" "yes" "$ADDED"
+    check_result "Header diff lists request headers missing from the response" "gone" "$REMOVED"
+    check_result "Header diff lists headers with changed values" "request->response" "$CHANGED"
+    check_result "Header diff leaves out unchanged headers" "false" "$UNCHANGED"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$DIFF_PORT/\", \"timeout\": 10}")
+    HAS_DIFF=$(echo "$RESPONSE" | jq -r 'has("header_diff")')
+    check_result "Header diff is only computed when requested" "false" "$HAS_DIFF"
+
+    kill $DIFF_PID 2>/dev/null || true
+    wait $DIFF_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping header diff test (python3 not available)"
+fi
+
+# Test includeResolvedIP reports the address the proxy connected to
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"http://127.0.0.1:$PORT/version\",
+        \"headers\": [],
+        \"timeout\": 10,
+        \"includeResolvedIP\": true
+    }")
+RESOLVED_IP=$(echo "$RESPONSE" | jq -r '.resolved_ip')
+check_result "includeResolvedIP reports the connected address" "127.0.0.1" "$RESOLVED_IP"
+
+# Test preserveHeaderCase sends a header name verbatim over HTTP/1.1
+# httpbin normalizes header names, so a local listener echoes the raw request head
+if command -v python3 > /dev/null 2>&1; then
+    RAW_PORT=$((PORT + 4))
+    python3 -c '
+import socket, sys
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+data = b""
+while b"\r\n\r\n" not in data:
+    chunk = c.recv(4096)
+    if not chunk:
+        break
+    data += chunk
+head = data.split(b"\r\n\r\n")[0]
+c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nConnection: close\r\nContent-Length: %d\r\n\r\n" % len(head) + head)
+c.close()
+' $RAW_PORT &
+    RAW_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$RAW_PORT/\",
+            \"headers\": [\"api-KEY: secret\"],
+            \"timeout\": 10,
+            \"preserveHeaderCase\": true
+        }")
+    SENT_VERBATIM=$(echo "$RESPONSE" | jq -r '.response_data' | grep -q '^api-KEY: secret' && echo "true" || echo "false")
+    check_result "preserveHeaderCase sends header name verbatim" "true" "$SENT_VERBATIM"
+
+    kill $RAW_PID 2>/dev/null || true
+    wait $RAW_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping header case test (python3 not available)"
+fi
+
+echo ""
+
+# ========================================
+# Streaming Tests
+# ========================================
+echo -e "${YELLOW}━━━ Streaming Tests ━━━${NC}"
+
+# Last-Event-ID from an SSE reconnection is forwarded upstream
+# (httpbin isn't SSE, so the proxy falls back to a standard JSON response)
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -H "Last-Event-ID: 42" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/headers",
+        "headers": [],
+        "timeout": 10,
+        "streaming": true
+    }')
+LAST_EVENT_ID=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .headers["Last-Event-Id"]')
+check_result "Streaming request forwards Last-Event-ID" "42" "$LAST_EVENT_ID"
+
+# Upstream connections of an idle stream carry TCP keep-alive probes (Linux only: reads timers via ss)
+if command -v python3 > /dev/null 2>&1 && command -v ss > /dev/null 2>&1; then
+    IDLE_PORT=$((PORT + 9))
+    python3 -c '
+import socket, sys, time
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+c.recv(4096)
+c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\n\r\ndata: hello\n\n")
+time.sleep(3)
+c.close()
+' $IDLE_PORT &
+    IDLE_PID=$!
+    sleep 1
+
+    curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$IDLE_PORT/\",
+            \"headers\": [],
+            \"timeout\": 10,
+            \"streaming\": true
+        }" > /dev/null &
+    STREAM_PID=$!
+    sleep 1
+    KEEPALIVE=$(ss -tno state established "( dport = :$IDLE_PORT )" | grep -q 'timer:(keepalive' && echo "true" || echo "false")
+    check_result "Idle streaming connection has TCP keep-alive enabled" "true" "$KEEPALIVE"
+
+    wait $STREAM_PID 2>/dev/null || true
+    kill $IDLE_PID 2>/dev/null || true
+    wait $IDLE_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping TCP keep-alive test (python3 or ss not available)"
+fi
+
+# A stream that ends normally gets a completion record; one cut off by the upstream doesn't
+if command -v python3 > /dev/null 2>&1; then
+    COMPLETION_PORT=$((PORT + 73))
+    python3 -c '
+import socket, sys
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(2)
+while True:
+    c, _ = s.accept()
+    request = c.recv(4096)
+    c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nTransfer-Encoding: chunked\r\n\r\n")
+    c.sendall(b"d\r\ndata: first\n\n\r\n")
+    if b"GET /complete " in request:
+        c.sendall(b"0\r\n\r\n")
+    # /drop closes the connection mid-stream, without the final chunk
+    c.close()
+' $COMPLETION_PORT &
+    COMPLETION_PID=$!
+    sleep 1
+
+    stream_completion() {
+        curl -s --max-time 10 -X POST "$PROXY_URL/proxy/request" -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$COMPLETION_PORT/$1\", \"headers\": [], \"streaming\": true}"
+    }
+    RESPONSE=$(stream_completion complete)
+    COMPLETION=$(echo "$RESPONSE" | grep $'^\036' | tr -d '\036')
+    check_result "Stream that ends normally has a completion record" "true 13" "$(echo "$COMPLETION" | jq -r '"\(.stream_complete) \(.bytes_streamed)"')"
+    check_result "Completion record follows the streamed data" "data: first" "$(echo "$RESPONSE" | sed -n 2p)"
+
+    RESPONSE=$(stream_completion drop)
+    check_result "Stream cut off by the upstream still delivers its data" "data: first" "$(echo "$RESPONSE" | sed -n 2p)"
+    check_result "Stream cut off by the upstream has no completion record" "0" "$(echo "$RESPONSE" | grep -c $'^\036')"
+
+    kill $COMPLETION_PID 2>/dev/null || true
+    wait $COMPLETION_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping stream completion record test (python3 not available)"
+fi
+
+# maxStreamBytes ends an endless SSE stream cleanly with a completion record noting the cap
+if command -v python3 > /dev/null 2>&1; then
+    ENDLESS_PORT=$((PORT + 26))
+    python3 -c '
+import socket, sys, time
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+c.recv(4096)
+c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\n\r\n")
+try:
+    while True:
+        c.sendall(b"data: tick\n\n")
+        time.sleep(0.01)
+except OSError:
+    pass
+' $ENDLESS_PORT &
+    ENDLESS_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s --max-time 10 -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$ENDLESS_PORT/\",
+            \"headers\": [],
+            \"timeout\": 10,
+            \"streaming\": true,
+            \"maxStreamBytes\": 100
+        }")
+    COMPLETION=$(echo "$RESPONSE" | tr -d '\036' | tail -n 1)
+    BYTES_STREAMED=$(echo "$COMPLETION" | jq -r '.bytes_streamed')
+    MAX_BYTES_REACHED=$(echo "$COMPLETION" | jq -r '.max_bytes_reached')
+    check_result "maxStreamBytes caps an endless stream" "100" "$BYTES_STREAMED"
+    check_result "Completion record notes the maxStreamBytes cap" "true" "$MAX_BYTES_REACHED"
+
+    # A stream exactly maxStreamBytes long ends on its own, so the cap isn't reported
+    EXACT_PORT=$((PORT + 82))
+    python3 -c '
+import socket, sys
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+c.recv(4096)
+body = b"data: " + b"x" * 92 + b"\n\n"
+c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nConnection: close\r\n\r\n" + body)
+c.close()
+' $EXACT_PORT &
+    EXACT_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s --max-time 10 -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$EXACT_PORT/\",
+            \"headers\": [],
+            \"timeout\": 10,
+            \"streaming\": true,
+            \"maxStreamBytes\": 100
+        }")
+    COMPLETION=$(echo "$RESPONSE" | tr -d '\036' | tail -n 1)
+    check_result "A stream of exactly maxStreamBytes is not marked as capped" "100 false" \
+        "$(echo "$COMPLETION" | jq -r '"\(.bytes_streamed) \(.max_bytes_reached // false)"')"
+
+    kill $ENDLESS_PID $EXACT_PID 2>/dev/null || true
+    wait $ENDLESS_PID $EXACT_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping maxStreamBytes test (python3 not available)"
+fi
+
+# --stream-max-duration ends an endless stream near the limit with a completion record noting the cap
+if command -v python3 > /dev/null 2>&1; then
+    MAX_DURATION_MOCK_PORT=$((PORT + 74))
+    python3 -c '
+import socket, sys, time
+s = socket.socket()
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.bind(("127.0.0.1", int(sys.argv[1])))
+s.listen(1)
+c, _ = s.accept()
+c.recv(4096)
+c.sendall(b"HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\n\r\n")
+try:
+    while True:
+        c.sendall(b"data: tick\n\n")
+        time.sleep(0.1)
+except OSError:
+    pass
+' $MAX_DURATION_MOCK_PORT &
+    MAX_DURATION_MOCK_PID=$!
+    MAX_DURATION_PORT=$((PORT + 75))
+    ./build/rbite-proxy --port $MAX_DURATION_PORT --stream-max-duration 1s --no-upgrade-check > /tmp/proxy-maxduration.log 2>&1 &
+    MAX_DURATION_PID=$!
+    sleep 1
+
+    START_MS=$(date +%s%3N)
+    RESPONSE=$(curl -s --max-time 10 -X POST "http://localhost:$MAX_DURATION_PORT/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$MAX_DURATION_MOCK_PORT/\", \"headers\": [], \"timeout\": 10, \"streaming\": true}")
+    ELAPSED_MS=$(( $(date +%s%3N) - START_MS ))
+    if [ $ELAPSED_MS -ge 900 ] && [ $ELAPSED_MS -lt 3000 ]; then
+        CUTOFF="near limit"
+    else
+        CUTOFF="${ELAPSED_MS}ms"
+    fi
+    check_result "--stream-max-duration ends an endless stream near the limit" "near limit" "$CUTOFF"
+    COMPLETION=$(echo "$RESPONSE" | grep $'^\036' | tr -d '\036')
+    check_result "Completion record notes the --stream-max-duration cap" "true true" "$(echo "$COMPLETION" | jq -r '"\(.stream_complete) \(.max_duration_reached)"')"
+    check_result "Stream delivers data until the cap" "true" "$(echo "$RESPONSE" | grep -c '^data: tick' | awk '{ print ($1 >= 5) ? "true" : "false" }')"
+
+    kill $MAX_DURATION_PID $MAX_DURATION_MOCK_PID 2>/dev/null || true
+    wait $MAX_DURATION_PID $MAX_DURATION_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping --stream-max-duration test (python3 not available)"
+fi
+
+# --max-streaming-connections rejects streams beyond the limit with 503 and reports active streams
+if command -v python3 > /dev/null 2>&1; then
+    STREAMS_PORT=$((PORT + 29))
+    STREAMS_UPSTREAM_PORT=$((PORT + 30))
+    python3 -c '
+import sys, time
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+class Handler(BaseHTTPRequestHandler):
+    protocol_version = "HTTP/1.1"
+    def do_GET(self):
+        self.send_response(200)
+        self.send_header("Content-Type", "text/event-stream")
+        self.end_headers()
+        try:
+            while True:
+                self.wfile.write(b"data: tick\n\n")
+                self.wfile.flush()
+                time.sleep(0.2)
+        except OSError:
+            pass
+    def log_message(self, *args):
+        pass
+ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $STREAMS_UPSTREAM_PORT &
+    STREAMS_UPSTREAM_PID=$!
+    ./build/rbite-proxy --port $STREAMS_PORT --max-streaming-connections 2 --no-upgrade-check > /tmp/proxy-streams.log 2>&1 &
+    STREAMS_PID=$!
+    sleep 1
+
+    STREAM_REQUEST="{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$STREAMS_UPSTREAM_PORT/\", \"timeout\": 30, \"streaming\": true}"
+    curl -s -N --max-time 4 -X POST "http://localhost:$STREAMS_PORT/proxy/request" -d "$STREAM_REQUEST" > /dev/null &
+    STREAM1_PID=$!
+    curl -s -N --max-time 4 -X POST "http://localhost:$STREAMS_PORT/proxy/request" -d "$STREAM_REQUEST" > /dev/null &
+    STREAM2_PID=$!
+    sleep 1
+
+    ACTIVE=$(curl -s "http://localhost:$STREAMS_PORT/metrics" | jq -r '.activeStreams')
+    check_result "/metrics reports the active streams" "2" "$ACTIVE"
+    ACTIVE=$(curl -s "http://localhost:$STREAMS_PORT/health" | jq -r '.activeStreams')
+    check_result "/health reports the active streams" "2" "$ACTIVE"
+
+    HTTP_CODE=$(curl -s -o /tmp/streams-response.json -w "%{http_code}" -X POST "http://localhost:$STREAMS_PORT/proxy/request" -d "$STREAM_REQUEST")
+    ERROR_TYPE=$(jq -r '.error_type' < /tmp/streams-response.json)
+    check_result "Stream beyond --max-streaming-connections is rejected with 503" "503" "$HTTP_CODE"
+    check_result "Rejected stream reports streaming_limit_reached" "streaming_limit_reached" "$ERROR_TYPE"
+
+    wait $STREAM1_PID $STREAM2_PID 2>/dev/null || true
+    sleep 0.5
+    ACTIVE=$(curl -s "http://localhost:$STREAMS_PORT/metrics" | jq -r '.activeStreams')
+    check_result "Ended streams release their slots" "0" "$ACTIVE"
+
+    kill $STREAMS_PID $STREAMS_UPSTREAM_PID 2>/dev/null || true
+    wait $STREAMS_PID $STREAMS_UPSTREAM_PID 2>/dev/null || true
+    rm -f /tmp/streams-response.json
+else
+    echo -e "${YELLOW}⚠${NC} Skipping streaming connection limit test (python3 not available)"
+fi
+
+# Non-SSE responses in streaming mode are held to --max-response-bytes
+LIMIT_PORT=$((PORT + 6))
+./build/rbite-proxy --port $LIMIT_PORT --max-response-bytes 1024 --no-upgrade-check > /tmp/proxy-limit.log 2>&1 &
+LIMIT_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$LIMIT_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/bytes/2048",
+        "headers": [],
+        "timeout": 10,
+        "streaming": true
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Oversized non-SSE streaming response returns response_too_large" "response_too_large" "$ERROR_TYPE"
+
+kill $LIMIT_PID 2>/dev/null || true
+wait $LIMIT_PID 2>/dev/null || true
+
+echo ""
+
+# ========================================
+# Batch Request Tests
+# ========================================
+echo -e "${YELLOW}━━━ Batch Request Tests ━━━${NC}"
+
+# Results are returned in request order
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/batch" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "requests": [
+            {"method": "GET", "url": "https://httpbin.org/get?n=0", "timeout": 10},
+            {"method": "GET", "timeout": 10}
+        ]
+    }')
+RESULT_COUNT=$(echo "$RESPONSE" | jq '.results | length')
+FIRST_SUCCESS=$(echo "$RESPONSE" | jq -r '.results[0].success')
+SECOND_ERROR=$(echo "$RESPONSE" | jq -r '.results[1].error_type')
+check_result "Batch returns one result per request" "2" "$RESULT_COUNT"
+check_result "Batch sub-request succeeds" "true" "$FIRST_SUCCESS"
+check_result "Invalid batch sub-request fails on its own" "request_format_error" "$SECOND_ERROR"
+
+# The summary aggregates the individual results
+if command -v python3 > /dev/null 2>&1; then
+    SUMMARY_PORT=$((PORT + 37))
+    SUMMARY_DIR=$(mktemp -d)
+    echo "first summary file" > "$SUMMARY_DIR/one.txt"
+    echo "second" > "$SUMMARY_DIR/two.txt"
+    python3 -m http.server $SUMMARY_PORT --bind 127.0.0.1 --directory "$SUMMARY_DIR" > /dev/null 2>&1 &
+    SUMMARY_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/batch" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"requests\": [
+                {\"method\": \"GET\", \"url\": \"http://127.0.0.1:$SUMMARY_PORT/one.txt\", \"timeout\": 10},
+                {\"method\": \"POST\", \"url\": \"http://127.0.0.1:$SUMMARY_PORT/two.txt\", \"body\": \"twelve bytes\", \"timeout\": 10},
+                {\"method\": \"GET\", \"timeout\": 10}
+            ]
+        }")
+    SUMMARY=$(echo "$RESPONSE" | jq -c '.summary | {total, succeeded, failed, request_bytes}')
+    RESULT_BYTES=$(echo "$RESPONSE" | jq '[.results[].response_size // empty | rtrimstr(" B") | tonumber] | add')
+    SUMMARY_BYTES=$(echo "$RESPONSE" | jq '.summary.response_bytes')
+    HAS_TIMES=$(echo "$RESPONSE" | jq -r '.summary | (.total_time | endswith(" ms")) and (.duration | endswith(" ms"))')
+    check_result "Batch summary counts results and request bytes" '{"total":3,"succeeded":2,"failed":1,"request_bytes":12}' "$SUMMARY"
+    check_result "Batch summary response_bytes matches the results" "$RESULT_BYTES" "$SUMMARY_BYTES"
+    check_result "Batch summary reports total_time and duration" "true" "$HAS_TIMES"
+
+    kill $SUMMARY_PID 2>/dev/null || true
+    wait $SUMMARY_PID 2>/dev/null || true
+    rm -rf "$SUMMARY_DIR"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping batch summary test (python3 not available)"
+fi
+
+# Streamed results arrive as they finish, so the slow request comes last
+RESPONSE=$(curl -s -N -X POST "$PROXY_URL/proxy/batch" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "stream": true,
+        "requests": [
+            {"method": "GET", "url": "https://httpbin.org/delay/2", "timeout": 10},
+            {"method": "GET", "url": "https://httpbin.org/get", "timeout": 10}
+        ]
+    }')
+ORDER=$(echo "$RESPONSE" | jq -r '.index' | tr '\n' ' ')
+check_result "Streamed batch results arrive in completion order" "1 0 " "$ORDER"
+
+# multipartResponse returns the metadata JSON and the raw binary body as separate parts
+if command -v python3 > /dev/null 2>&1; then
+    MULTIPART_PORT=$((PORT + 31))
+    MULTIPART_DIR=$(mktemp -d)
+    printf '\x89PNG\r\n\x1a\n' > "$MULTIPART_DIR/image.png"
+    python3 -m http.server $MULTIPART_PORT --bind 127.0.0.1 --directory "$MULTIPART_DIR" > /dev/null 2>&1 &
+    MULTIPART_PID=$!
+    sleep 1
+
+    HEADERS_FILE=$(mktemp)
+    RESPONSE=$(curl -s -D "$HEADERS_FILE" -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"method\": \"GET\",
+            \"url\": \"http://127.0.0.1:$MULTIPART_PORT/image.png\",
+            \"timeout\": 10,
+            \"multipartResponse\": true
+        }")
+    IS_MULTIPART=$(grep -qi '^Content-Type: multipart/mixed; boundary=' "$HEADERS_FILE" && echo "true" || echo "false")
+    METADATA=$(echo "$RESPONSE" | grep '^{')
+    STATUS=$(echo "$METADATA" | jq -r '.response_status')
+    CONTENT_TYPE=$(echo "$METADATA" | jq -r '.content_type')
+    HAS_DATA=$(echo "$METADATA" | jq -r 'has("response_data")')
+    PART_TYPES=$(echo "$RESPONSE" | grep -i '^Content-Type:' | tr -d '\r' | awk '{print $2}' | tr '\n' ' ')
+    RAW_PNG=$(echo "$RESPONSE" | grep -c 'PNG')
+    check_result "multipartResponse returns multipart/mixed" "true" "$IS_MULTIPART"
+    check_result "multipartResponse metadata part keeps the status" "200" "$STATUS"
+    check_result "multipartResponse metadata part keeps the content type" "image/png" "$CONTENT_TYPE"
+    check_result "multipartResponse metadata part leaves out the body" "false" "$HAS_DATA"
+    check_result "multipartResponse parts are metadata, then body" "application/json image/png " "$PART_TYPES"
+    check_result "multipartResponse body part is not base64 encoded" "1" "$RAW_PNG"
+
+    kill $MULTIPART_PID 2>/dev/null || true
+    wait $MULTIPART_PID 2>/dev/null || true
+    rm -rf "$MULTIPART_DIR" "$HEADERS_FILE"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping multipartResponse test (python3 not available)"
+fi
+
+# Multipart batch responses carry each body as its own part, binary bodies unencoded
+if command -v python3 > /dev/null 2>&1; then
+    FILES_PORT=$((PORT + 15))
+    FILES_DIR=$(mktemp -d)
+    echo "hello from file one" > "$FILES_DIR/one.txt"
+    printf '\x89PNG\r\n\x1a\n' > "$FILES_DIR/two.png"
+    python3 -m http.server $FILES_PORT --bind 127.0.0.1 --directory "$FILES_DIR" > /dev/null 2>&1 &
+    FILES_PID=$!
+    sleep 1
+
+    HEADERS_FILE=$(mktemp)
+    RESPONSE=$(curl -s -D "$HEADERS_FILE" -X POST "$PROXY_URL/proxy/batch" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"multipart\": true,
+            \"requests\": [
+                {\"method\": \"GET\", \"url\": \"http://127.0.0.1:$FILES_PORT/one.txt\", \"timeout\": 10},
+                {\"method\": \"GET\", \"url\": \"http://127.0.0.1:$FILES_PORT/two.png\", \"timeout\": 10}
+            ]
+        }")
+    IS_MULTIPART=$(grep -qi '^Content-Type: multipart/mixed; boundary=' "$HEADERS_FILE" && echo "true" || echo "false")
+    PART_INDEXES=$(echo "$RESPONSE" | grep -i '^X-Slingshot-Index:' | tr -d '\r' | awk '{print $2}' | tr '\n' ' ')
+    PART_TYPES=$(echo "$RESPONSE" | grep -i '^Content-Type:' | tr -d '\r' | awk '{print $2}' | tr '\n' ' ')
+    TEXT_BODY=$(echo "$RESPONSE" | grep -c '^hello from file one')
+    RAW_PNG=$(echo "$RESPONSE" | grep -c 'PNG')
+    check_result "Multipart batch response is multipart/mixed" "true" "$IS_MULTIPART"
+    check_result "Multipart batch has one part per result in request order" "0 1 " "$PART_INDEXES"
+    check_result "Multipart batch parts carry their own Content-Type" "text/plain image/png " "$PART_TYPES"
+    check_result "Multipart batch text part holds the file body" "1" "$TEXT_BODY"
+    check_result "Multipart batch binary part is not base64 encoded" "1" "$RAW_PNG"
+
+    kill $FILES_PID 2>/dev/null || true
+    wait $FILES_PID 2>/dev/null || true
+    rm -rf "$FILES_DIR" "$HEADERS_FILE"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping multipart batch test (python3 not available)"
+fi
+
+echo ""
+
+# ========================================
+# Per-Host Concurrency Tests
+# ========================================
+echo -e "${YELLOW}━━━ Per-Host Concurrency Tests ━━━${NC}"
+
+# Saturate httpbin.org (limit is 2) with three slow requests
+SLOW_PIDS=""
+for i in 1 2 3; do
+    curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d '{
+            "method": "GET",
+            "url": "https://httpbin.org/delay/2",
+            "headers": [],
+            "timeout": 15
+        }' > /dev/null &
+    SLOW_PIDS="$SLOW_PIDS $!"
+done
+sleep 1
+
+IN_FLIGHT=$(curl -s "$PROXY_URL/metrics" | jq -r '.upstreamInFlight["httpbin.org"]')
+check_result "Metrics report in-flight requests capped at the per-host limit" "2" "$IN_FLIGHT"
+
+# Another host is not queued behind the saturated one
+START_TIME=$(date +%s)
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"http://localhost:$PORT/health\",
+        \"headers\": [],
+        \"timeout\": 10
+    }")
+ELAPSED=$(($(date +%s) - START_TIME))
+SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+check_result "Request to another host succeeds while one host is saturated" "true" "$SUCCESS"
+check_result "Request to another host is not queued" "true" "$([ $ELAPSED -lt 2 ] && echo true || echo false)"
+
+wait $SLOW_PIDS
+
+echo ""
+
+# ========================================
+# Request Coalescing Tests
+# ========================================
+echo -e "${YELLOW}━━━ Request Coalescing Tests ━━━${NC}"
+
+# Separate instance so coalescing doesn't interfere with the concurrency tests above
+COALESCE_PORT=$((PORT + 2))
+./build/rbite-proxy --port $COALESCE_PORT --coalesce-requests --no-upgrade-check > /tmp/proxy-coalesce.log 2>&1 &
+COALESCE_PID=$!
+sleep 1
+
+# Five identical concurrent GETs share a single upstream call
+COALESCE_DIR=$(mktemp -d)
+for i in 1 2 3 4 5; do
+    curl -s -X POST "http://localhost:$COALESCE_PORT/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d '{
+            "method": "GET",
+            "url": "https://httpbin.org/delay/2?coalesce=1",
+            "headers": ["Accept: application/json"],
+            "timeout": 15
+        }' > "$COALESCE_DIR/$i.json" &
+done
+wait
+
+SUCCESSES=$(cat "$COALESCE_DIR"/*.json | jq -r 'select(.success == true) | .success' | wc -l | tr -d ' ')
+check_result "All coalesced requests succeed" "5" "$SUCCESSES"
+COALESCED=$(curl -s "http://localhost:$COALESCE_PORT/metrics" | jq -r '.coalescedRequests')
+check_result "Identical concurrent GETs hit the upstream once" "4" "$COALESCED"
+
+# POST requests are never coalesced
+for i in 1 2; do
+    curl -s -X POST "http://localhost:$COALESCE_PORT/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d '{
+            "method": "POST",
+            "url": "https://httpbin.org/delay/1",
+            "headers": [],
+            "timeout": 15
+        }' > /dev/null &
+done
+wait
+COALESCED=$(curl -s "http://localhost:$COALESCE_PORT/metrics" | jq -r '.coalescedRequests')
+check_result "Concurrent POSTs are not coalesced" "4" "$COALESCED"
+
+# Requests with a requestId are never coalesced, so /proxy/cancel aborts their own upstream call
+if command -v python3 > /dev/null 2>&1; then
+    COALESCE_CANCEL_PORT=$((PORT + 81))
+    python3 -c '
+import http.server, sys, threading, time
+hits = 0
+lock = threading.Lock()
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        global hits
+        if self.path == "/hits":
+            body = str(hits).encode()
+        else:
+            with lock:
+                hits += 1
+            time.sleep(3)
+            body = b"ok"
+        self.send_response(200)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $COALESCE_CANCEL_PORT 2> /dev/null &
+    COALESCE_CANCEL_MOCK_PID=$!
+    sleep 1
+
+    COALESCE_CANCEL_PIDS=""
+    for i in 1 2; do
+        curl -s -X POST "http://localhost:$COALESCE_PORT/proxy/request" \
+            -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$COALESCE_CANCEL_PORT/slow\", \"timeout\": 15, \"requestId\": \"tests-coalesce-cancel-$i\"}" \
+            > "$COALESCE_DIR/cancel-$i.json" &
+        COALESCE_CANCEL_PIDS="$COALESCE_CANCEL_PIDS $!"
+    done
+    sleep 1
+    curl -s -o /dev/null -X POST "http://localhost:$COALESCE_PORT/proxy/cancel" -H "Content-Type: application/json" -d '{"requestId": "tests-coalesce-cancel-1"}'
+    wait $COALESCE_CANCEL_PIDS 2>/dev/null || true
+
+    check_result "Identical GETs with a requestId each make their own upstream call" "2" "$(curl -s "http://127.0.0.1:$COALESCE_CANCEL_PORT/hits")"
+    check_result "Cancelling one of them only cancels that request" "request_cancelled true" \
+        "$(jq -r '.error_type' "$COALESCE_DIR/cancel-1.json") $(jq -r '.success' "$COALESCE_DIR/cancel-2.json")"
+
+    kill $COALESCE_CANCEL_MOCK_PID 2>/dev/null || true
+    wait $COALESCE_CANCEL_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping coalescing cancellation test (python3 not available)"
+fi
+
+rm -rf "$COALESCE_DIR"
+kill $COALESCE_PID 2>/dev/null || true
+wait $COALESCE_PID 2>/dev/null || true
+
+echo ""
+
+# ========================================
+# Path Parameter Substitution Tests
+# ========================================
+echo -e "${YELLOW}━━━ Path Parameter Substitution Tests ━━━${NC}"
+
+# Test path parameter substitution
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/status/:code",
+        "path_params": {"code": "200"},
+        "headers": [],
+        "timeout": 10,
+        "followRedirects": true
+    }')
+SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+STATUS=$(echo "$RESPONSE" | jq -r '.response_status')
+check_result "Path parameter substitution works" "true" "$SUCCESS"
+check_result "Path parameter :code replaced with 200" "200" "$STATUS"
+
+# Test URL length limit after path parameter substitution
+LONG_VALUE=$(printf 'a%.0s' {1..9000})
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"https://httpbin.org/anything/:value\",
+        \"path_params\": {\"value\": \"$LONG_VALUE\"},
+        \"headers\": [],
+        \"timeout\": 10
+    }")
+ERROR_TITLE=$(echo "$RESPONSE" | jq -r '.error_title')
+check_result "Oversized URL after substitution is rejected" "URL Too Long" "$ERROR_TITLE"
+
+# Test path parameter count limit (default 100)
+MANY_PARAMS=$(seq 1 101 | jq -R '{("p" + .): .}' | jq -sc 'add')
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"https://httpbin.org/anything/:p1\",
+        \"path_params\": $MANY_PARAMS,
+        \"headers\": [],
+        \"timeout\": 10
+    }")
+ERROR_TITLE=$(echo "$RESPONSE" | jq -r '.error_title')
+ERROR_MESSAGE=$(echo "$RESPONSE" | jq -r '.error_message')
+check_result "Too many path parameters are rejected" "Too Many Path Parameters" "$ERROR_TITLE"
+check_result "Path parameter limit error reports count and maximum" "Request has 101 path parameters, maximum is 100" "$ERROR_MESSAGE"
+
+# Test outbound header value length limit
+LONG_HEADER=$(printf 'a%.0s' {1..9000})
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"https://httpbin.org/headers\",
+        \"headers\": [\"X-Long: $LONG_HEADER\"],
+        \"timeout\": 10
+    }")
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+ERROR_TITLE=$(echo "$RESPONSE" | jq -r '.error_title')
+check_result "Oversized header value returns request_format_error" "request_format_error" "$ERROR_TYPE"
+check_result "Oversized header value is rejected" "Header Too Long" "$ERROR_TITLE"
+
+# A forwarded Last-Event-ID counts towards the header limits too
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -H "Last-Event-ID: $LONG_HEADER" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/headers",
+        "headers": [],
+        "timeout": 10,
+        "streaming": true
+    }')
+ERROR_TITLE=$(echo "$RESPONSE" | jq -r '.error_title')
+check_result "Oversized Last-Event-ID is rejected" "Header Too Long" "$ERROR_TITLE"
+
+# Test total outbound header size limit (10 headers of 7000 bytes each)
+HEADERS_JSON=$(for i in $(seq 1 10); do printf '"X-Big-%d: %s",' "$i" "$(printf 'b%.0s' {1..7000})"; done)
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"https://httpbin.org/headers\",
+        \"headers\": [${HEADERS_JSON%,}],
+        \"timeout\": 10
+    }")
+ERROR_TITLE=$(echo "$RESPONSE" | jq -r '.error_title')
+check_result "Oversized total headers are rejected" "Headers Too Large" "$ERROR_TITLE"
+
+# Test header count limit (5000 tiny headers, well above the default of 100)
+HEADERS_JSON=$(for i in $(seq 1 5000); do printf '"X-H-%d: v",' "$i"; done)
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"https://httpbin.org/headers\",
+        \"headers\": [${HEADERS_JSON%,}],
+        \"timeout\": 10
+    }")
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+ERROR_TITLE=$(echo "$RESPONSE" | jq -r '.error_title')
+check_result "Excessive header count returns request_format_error" "request_format_error" "$ERROR_TYPE"
+check_result "Excessive header count is rejected" "Too Many Headers" "$ERROR_TITLE"
+
+# Test that substituted values are not substituted again (single pass)
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/anything/:first",
+        "path_params": {"first": ":second", "second": "oops"},
+        "headers": [],
+        "timeout": 10
+    }')
+REQUEST_URL=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .url')
+check_result "Path parameter values are not re-substituted" "https://httpbin.org/anything/%3Asecond" "$REQUEST_URL"
+
+# Test strict path parameters reject leftover placeholders but not ports
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/anything/users/:userId/posts/:postId",
+        "path_params": {"userId": "42"},
+        "path_params_strict": true,
+        "headers": [],
+        "timeout": 10
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+MISSING=$(echo "$RESPONSE" | jq -r '.error_message | contains(":postId")')
+check_result "Strict path parameters reject a missing parameter" "request_format_error" "$ERROR_TYPE"
+check_result "Strict path parameter error lists the missing parameter" "true" "$MISSING"
+
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org:443/anything/users/:userId",
+        "path_params": {"userId": "42"},
+        "path_params_strict": true,
+        "headers": [],
+        "timeout": 10
+    }')
+SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+check_result "Strict path parameters accept a URL with a port" "true" "$SUCCESS"
+
+# Test list-valued path parameters (comma join is the default)
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/anything/items/:ids",
+        "path_params": {"ids": ["1", "2", "3"]},
+        "headers": [],
+        "timeout": 10
+    }')
+REQUEST_URL=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .url')
+check_result "List path parameter joined with commas" "https://httpbin.org/anything/items/1,2,3" "$REQUEST_URL"
+
+# Test list-valued path parameters joined as path segments
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/anything/items/:ids",
+        "path_params": {"ids": ["1", "2", "3"]},
+        "path_params_join": "segment",
+        "headers": [],
+        "timeout": 10
+    }')
+REQUEST_URL=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .url')
+check_result "List path parameter joined as segments" "https://httpbin.org/anything/items/1/2/3" "$REQUEST_URL"
+
+echo ""
+
+# ========================================
+# Body Parameter Tests
+# ========================================
+echo -e "${YELLOW}━━━ Body Parameter Tests ━━━${NC}"
+
+# Test body parameters are JSON-escaped into a JSON body by default, single-pass
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "POST",
+        "url": "https://httpbin.org/anything",
+        "headers": ["Content-Type: application/json"],
+        "body": "{\"token\": \"{{token}}\", \"note\": \"{{note}}\", \"other\": \"{{missing}}\"}",
+        "body_params": {"token": "abc\"123", "note": "{{token}}"},
+        "timeout": 10
+    }')
+SENT=$(echo "$RESPONSE" | jq -c '.response_data | fromjson | .json')
+check_result "Body parameters are JSON-escaped and never re-substituted" '{"note":"{{token}}","other":"{{missing}}","token":"abc\"123"}' "$(echo "$SENT" | jq -cS '.')"
+
+# Test raw body parameters insert whole JSON values
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "POST",
+        "url": "https://httpbin.org/anything",
+        "headers": ["Content-Type: application/json"],
+        "body": "{\"ids\": {{ids}}, \"count\": {{count}}}",
+        "body_params": {"ids": "[1, 2]", "count": "2"},
+        "body_params_escape": "raw",
+        "timeout": 10
+    }')
+SENT=$(echo "$RESPONSE" | jq -c '.response_data | fromjson | .json')
+check_result "Raw body parameters are inserted unchanged" '{"count":2,"ids":[1,2]}' "$(echo "$SENT" | jq -cS '.')"
+
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "POST", "url": "https://httpbin.org/anything", "body": "{}", "body_params": {"a": "1"}, "body_params_escape": "xml", "timeout": 10}')
+check_result "Unknown body_params_escape is rejected" "Invalid Body Parameter Escaping" "$(echo "$RESPONSE" | jq -r '.error_title')"
+
+echo ""
+
+# ========================================
+# Request Signing Tests
+# ========================================
+echo -e "${YELLOW}━━━ Request Signing Tests ━━━${NC}"
+
+# Test deterministic body signature (no timestamp involved)
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "POST",
+        "url": "https://httpbin.org/post",
+        "headers": ["Content-Type: text/plain"],
+        "body": "hello",
+        "timeout": 10,
+        "signing": {"secret": "secret", "scheme": "body", "header": "X-Hub-Signature-256"}
+    }')
+SIGNATURE=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .headers["X-Hub-Signature-256"]')
+check_result "Body signature header is injected" "sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b" "$SIGNATURE"
+
+# Test invalid signing scheme is rejected
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/get",
+        "headers": [],
+        "timeout": 10,
+        "signing": {"secret": "secret", "scheme": "unknown"}
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Unknown signing scheme returns request_format_error" "request_format_error" "$ERROR_TYPE"
+
+echo ""
+
+# ========================================
+# Loop Detection Tests
+# ========================================
+echo -e "${YELLOW}━━━ Loop Detection Tests ━━━${NC}"
+
+# Test loop detection via hostname blocking
+# Note: /health endpoint is allowed on any hostname, so we test with a different endpoint
+BLOCKED_BEFORE=$(curl -s "$PROXY_URL/metrics" | jq -r '.blockedRequests.hostname_blocked')
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "http://p.requestbite.com/some-endpoint",
+        "headers": [],
+        "timeout": 10,
+        "followRedirects": true
+    }')
+SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Loop detection blocks p.requestbite.com" "false" "$SUCCESS"
+check_result "Loop detection returns loop_detected error" "loop_detected" "$ERROR_TYPE"
+BLOCKED_AFTER=$(curl -s "$PROXY_URL/metrics" | jq -r '.blockedRequests.hostname_blocked')
+check_result "Hostname block increments hostname_blocked counter" "$((BLOCKED_BEFORE + 1))" "$BLOCKED_AFTER"
+
+# Test loop detection via User-Agent
+BLOCKED_BEFORE=$(curl -s "$PROXY_URL/metrics" | jq -r '.blockedRequests.loop_detected')
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -H "User-Agent: rb-slingshot/1.0.0" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/get",
+        "headers": [],
+        "timeout": 10,
+        "followRedirects": true
+    }')
+SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Loop detection blocks rb-slingshot User-Agent" "false" "$SUCCESS"
+check_result "User-Agent loop returns loop_detected error" "loop_detected" "$ERROR_TYPE"
+BLOCKED_AFTER=$(curl -s "$PROXY_URL/metrics" | jq -r '.blockedRequests.loop_detected')
+check_result "User-Agent loop increments loop_detected counter" "$((BLOCKED_BEFORE + 1))" "$BLOCKED_AFTER"
+
+# Test URL policy validation without executing a request
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://httpbin.org/get"}')
+ALLOWED=$(echo "$RESPONSE" | jq -r '.allowed')
+check_result "Validate allows a public URL" "true" "$ALLOWED"
+
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/validate" -H "Content-Type: application/json" -d '{"url": "ftp://example.com/file"}')
+RULE=$(echo "$RESPONSE" | jq -r '.rule')
+check_result "Validate rejects a non-HTTP URL" "url_validation_error" "$RULE"
+
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://p.requestbite.com/proxy/request"}')
+RULE=$(echo "$RESPONSE" | jq -r '.rule')
+check_result "Validate reports a blocked hostname" "hostname_blocked" "$RULE"
+
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/validate" -H "Content-Type: application/json" \
+    -H "User-Agent: rb-slingshot/1.0.0" -d '{"url": "https://httpbin.org/get"}')
+RULE=$(echo "$RESPONSE" | jq -r '.rule')
+check_result "Validate reports an rb-slingshot User-Agent loop" "loop_detected" "$RULE"
+BLOCKED_AFTER_VALIDATE=$(curl -s "$PROXY_URL/metrics" | jq -r '.blockedRequests.loop_detected')
+check_result "Validate does not increment loop_detected counter" "$BLOCKED_AFTER" "$BLOCKED_AFTER_VALIDATE"
+
+# Test --trailing-slash serves or redirects endpoint paths with a trailing slash
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "$PROXY_URL/health/")
+check_result "A trailing slash is an unknown endpoint by default" "404" "$STATUS"
+
+SLASH_PORT=$((PORT + 44))
+./build/rbite-proxy --port $SLASH_PORT --trailing-slash accept --enable-local-files --enable-exec --no-upgrade-check > /tmp/proxy-slash.log 2>&1 &
+SLASH_PID=$!
+sleep 1
+
+for SLASH_PATH in /health/ /ready/ /version/ /metrics/ /errors/; do
+    ERROR_TYPE=$(curl -s "http://localhost:$SLASH_PORT$SLASH_PATH" | jq -r '.error_type // "none"')
+    check_result "--trailing-slash accept serves GET $SLASH_PATH" "none" "$ERROR_TYPE"
+done
+for SLASH_PATH in /proxy/request/ /proxy/form/ /proxy/batch/ /proxy/validate/ /proxy/preflight/ /file/ /dir/ /exec/; do
+    ERROR_TYPE=$(curl -s -X POST "http://localhost:$SLASH_PORT$SLASH_PATH" -H "Content-Type: application/json" -d '{}' | jq -r '.error_type // "none"')
+    [ "$ERROR_TYPE" != "endpoint_not_found" ] && ERROR_TYPE="served"
+    check_result "--trailing-slash accept serves POST $SLASH_PATH" "served" "$ERROR_TYPE"
+done
+
+RESPONSE=$(curl -s -X POST "http://localhost:$SLASH_PORT/proxy/request/" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/", "timeout": 10}')
+check_result "--trailing-slash accept runs the proxied request" "connection_refused" "$(echo "$RESPONSE" | jq -r '.error_type')"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "http://localhost:$SLASH_PORT/unknown/")
+check_result "--trailing-slash accept keeps 404 for unknown endpoints" "404" "$STATUS"
+
+kill $SLASH_PID 2>/dev/null || true
+wait $SLASH_PID 2>/dev/null || true
+
+./build/rbite-proxy --port $SLASH_PORT --trailing-slash redirect --no-upgrade-check > /tmp/proxy-slash.log 2>&1 &
+SLASH_PID=$!
+sleep 1
+
+REDIRECT=$(curl -s -o /dev/null -w "%{http_code} %{redirect_url}" -X POST "http://localhost:$SLASH_PORT/proxy/request/?trace=1" -d '{}')
+check_result "--trailing-slash redirect answers with a 308 to the path without the slash" "308 http://localhost:$SLASH_PORT/proxy/request?trace=1" "$REDIRECT"
+
+RESPONSE=$(curl -s -L -X POST "http://localhost:$SLASH_PORT/proxy/request/" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "http://127.0.0.1:1/", "timeout": 10}')
+check_result "--trailing-slash redirect keeps the method and body" "connection_refused" "$(echo "$RESPONSE" | jq -r '.error_type')"
+
+kill $SLASH_PID 2>/dev/null || true
+wait $SLASH_PID 2>/dev/null || true
+
+# Test --allowed-schemes replaces the accepted URL schemes
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/validate" -H "Content-Type: application/json" -d '{"url": "ws://example.com/socket"}')
+check_result "Validate rejects ws:// by default" "url_validation_error" "$(echo "$RESPONSE" | jq -r '.rule')"
+
+SCHEMES_PORT=$((PORT + 42))
+./build/rbite-proxy --port $SCHEMES_PORT --allowed-schemes http,https,ws --no-upgrade-check > /tmp/proxy-schemes.log 2>&1 &
+SCHEMES_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$SCHEMES_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "ws://example.com/socket"}')
+check_result "--allowed-schemes allows an extra scheme" "true" "$(echo "$RESPONSE" | jq -r '.allowed')"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$SCHEMES_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "ftp://example.com/file", "timeout": 10}')
+check_result "--allowed-schemes rejects an unlisted scheme" "url_validation_error" "$(echo "$RESPONSE" | jq -r '.error_type')"
+check_result "Scheme rejection names the scheme" "true" "$(echo "$RESPONSE" | jq -r '.error_message | contains("\"ftp\"")')"
+
+kill $SCHEMES_PID 2>/dev/null || true
+wait $SCHEMES_PID 2>/dev/null || true
+
+# Test --allowed-ports rejects upstream ports outside the list, using the scheme's default port
+PORTS_PORT=$((PORT + 61))
+PORTS_MOCK_PORT=$((PORT + 62))
+./build/rbite-proxy --port $PORTS_PORT --allowed-ports 80,443,8000-8010,$PORTS_MOCK_PORT --no-upgrade-check > /tmp/proxy-ports.log 2>&1 &
+PORTS_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$PORTS_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://example.com/"}')
+check_result "--allowed-ports allows the scheme's default port" "true" "$(echo "$RESPONSE" | jq -r '.allowed')"
+RESPONSE=$(curl -s -X POST "http://localhost:$PORTS_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "http://example.com:8005/"}')
+check_result "--allowed-ports allows a port in a range" "true" "$(echo "$RESPONSE" | jq -r '.allowed')"
+RESPONSE=$(curl -s -X POST "http://localhost:$PORTS_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "http://example.com:6379/"}')
+check_result "--allowed-ports rejects an unlisted port" "false url_validation_error" "$(echo "$RESPONSE" | jq -r '"\(.allowed) \(.rule)"')"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$PORTS_PORT/proxy/request" -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "https://example.com:22/", "timeout": 10}')
+check_result "--allowed-ports rejects requests to an unlisted port" "url_validation_error" "$(echo "$RESPONSE" | jq -r '.error_type')"
+check_result "Port rejection names the port" "true" "$(echo "$RESPONSE" | jq -r '.error_message | contains("port 22")')"
+
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/validate" -H "Content-Type: application/json" -d '{"url": "http://example.com:6379/"}')
+check_result "Any port is allowed without --allowed-ports" "true" "$(echo "$RESPONSE" | jq -r '.allowed')"
+
+if command -v python3 > /dev/null 2>&1; then
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(302)
+        self.send_header("Location", "http://127.0.0.1:6379/")
+        self.send_header("Content-Length", "0")
+        self.end_headers()
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $PORTS_MOCK_PORT &
+    PORTS_MOCK_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "http://localhost:$PORTS_PORT/proxy/request" -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$PORTS_MOCK_PORT/\", \"timeout\": 10}")
+    check_result "--allowed-ports rejects redirects to an unlisted port" "url_validation_error" "$(echo "$RESPONSE" | jq -r '.error_type')"
+
+    kill $PORTS_MOCK_PID 2>/dev/null || true
+    wait $PORTS_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping --allowed-ports redirect test (python3 not available)"
+fi
+
+kill $PORTS_PID 2>/dev/null || true
+wait $PORTS_PID 2>/dev/null || true
+
+# Test --health-check-hosts limits the /health exemption of blocked hostnames
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://p.requestbite.com/health"}')
+check_result "A blocked hostname's /health is exempt by default" "true" "$(echo "$RESPONSE" | jq -r '.allowed')"
+
+HEALTH_HOSTS_PORT=$((PORT + 43))
+./build/rbite-proxy --port $HEALTH_HOSTS_PORT --health-check-hosts dev.p.requestbite.com --no-upgrade-check > /tmp/proxy-healthhosts.log 2>&1 &
+HEALTH_HOSTS_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$HEALTH_HOSTS_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://p.requestbite.com/health"}')
+check_result "--health-check-hosts blocks an unlisted host's /health" "hostname_blocked" "$(echo "$RESPONSE" | jq -r '.rule')"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$HEALTH_HOSTS_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://p.requestbite.com/"}')
+check_result "--health-check-hosts blocks an unlisted host's welcome page" "hostname_blocked" "$(echo "$RESPONSE" | jq -r '.rule')"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$HEALTH_HOSTS_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://dev.p.requestbite.com/health"}')
+check_result "--health-check-hosts keeps a listed host's /health" "true" "$(echo "$RESPONSE" | jq -r '.allowed')"
+
+kill $HEALTH_HOSTS_PID 2>/dev/null || true
+wait $HEALTH_HOSTS_PID 2>/dev/null || true
+
+./build/rbite-proxy --port $HEALTH_HOSTS_PORT --health-check-hosts "" --no-upgrade-check > /tmp/proxy-healthhosts.log 2>&1 &
+HEALTH_HOSTS_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$HEALTH_HOSTS_PORT/proxy/validate" -H "Content-Type: application/json" -d '{"url": "https://dev.p.requestbite.com/health"}')
+check_result "An empty --health-check-hosts removes the exemption" "hostname_blocked" "$(echo "$RESPONSE" | jq -r '.rule')"
+
+kill $HEALTH_HOSTS_PID 2>/dev/null || true
+wait $HEALTH_HOSTS_PID 2>/dev/null || true
+
+# Instance without loop detection lets a normally blocked request through
+NO_LOOP_PORT=$((PORT + 13))
+./build/rbite-proxy --port $NO_LOOP_PORT --disable-loop-detection --no-upgrade-check > /tmp/proxy-noloop.log 2>&1 &
+NO_LOOP_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$NO_LOOP_PORT/proxy/request" \
     -H "Content-Type: application/json" \
+    -H "User-Agent: rb-slingshot/1.0.0" \
     -d '{
         "method": "GET",
-        "url": "https://httpbin.org/json",
+        "url": "https://httpbin.org/get",
         "headers": [],
-        "timeout": 10,
-        "passThrough": true
+        "timeout": 10
     }')
-HAS_SLIDESHOW=$(echo "$RESPONSE" | jq 'has("slideshow")')
-check_result "PassThrough=true returns raw JSON" "true" "$HAS_SLIDESHOW"
+SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+check_result "Disabled loop detection lets rb-slingshot User-Agent through" "true" "$SUCCESS"
 
-# Test 7: PassThrough=true with HTML response
-RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+kill $NO_LOOP_PID 2>/dev/null || true
+wait $NO_LOOP_PID 2>/dev/null || true
+
+# Instance mounted under a base path serves every route below the prefix only
+BASE_PATH_PORT=$((PORT + 14))
+./build/rbite-proxy --port $BASE_PATH_PORT --base-path /slingshot --no-upgrade-check > /tmp/proxy-basepath.log 2>&1 &
+BASE_PATH_PID=$!
+sleep 1
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "http://localhost:$BASE_PATH_PORT/slingshot/health")
+check_result "Health endpoint is served under the base path" "200" "$STATUS"
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "http://localhost:$BASE_PATH_PORT/health")
+check_result "Health endpoint is not served outside the base path" "404" "$STATUS"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$BASE_PATH_PORT/slingshot/proxy/request" \
     -H "Content-Type: application/json" \
     -d '{
         "method": "GET",
-        "url": "https://httpbin.org/html",
+        "url": "https://httpbin.org/get",
         "headers": [],
-        "timeout": 10,
-        "passThrough": true
+        "timeout": 10
     }')
-CONTAINS_HTML=$(echo "$RESPONSE" | grep -q "<html>" && echo "true" || echo "false")
-check_result "PassThrough=true returns raw HTML" "true" "$CONTAINS_HTML"
+SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
+check_result "Proxy endpoint works under the base path" "true" "$SUCCESS"
 
-echo ""
+kill $BASE_PATH_PID 2>/dev/null || true
+wait $BASE_PATH_PID 2>/dev/null || true
 
-# ========================================
-# Path Parameter Substitution Tests
-# ========================================
-echo -e "${YELLOW}━━━ Path Parameter Substitution Tests ━━━${NC}"
+# Instance with a response cache: the first GET is a miss, an identical one right after is a hit
+CACHE_PORT=$((PORT + 11))
+./build/rbite-proxy --port $CACHE_PORT --response-cache-ttl 60s --no-upgrade-check > /tmp/proxy-cache.log 2>&1 &
+CACHE_PID=$!
+sleep 1
 
-# Test path parameter substitution
-RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+CACHE_REQUEST='{"method": "GET", "url": "https://httpbin.org/uuid", "headers": [], "timeout": 10}'
+RESPONSE=$(curl -s -X POST "http://localhost:$CACHE_PORT/proxy/request" -H "Content-Type: application/json" -d "$CACHE_REQUEST")
+FROM_CACHE=$(echo "$RESPONSE" | jq -r '.from_cache // false')
+FIRST_UUID=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .uuid')
+check_result "First cacheable request is not served from cache" "false" "$FROM_CACHE"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$CACHE_PORT/proxy/request" -H "Content-Type: application/json" -d "$CACHE_REQUEST")
+FROM_CACHE=$(echo "$RESPONSE" | jq -r '.from_cache // false')
+SECOND_UUID=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .uuid')
+check_result "Repeated request is served from cache" "true" "$FROM_CACHE"
+check_result "Cached response matches the original" "$FIRST_UUID" "$SECOND_UUID"
+
+kill $CACHE_PID 2>/dev/null || true
+wait $CACHE_PID 2>/dev/null || true
+
+# Second instance blocking private network upstreams
+SSRF_PORT=$((PORT + 5))
+./build/rbite-proxy --port $SSRF_PORT --block-private-networks --no-upgrade-check > /tmp/proxy-ssrf.log 2>&1 &
+SSRF_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$SSRF_PORT/proxy/request" \
     -H "Content-Type: application/json" \
-    -d '{
-        "method": "GET",
-        "url": "https://httpbin.org/status/:code",
-        "path_params": {"code": "200"},
-        "headers": [],
-        "timeout": 10,
-        "followRedirects": true
-    }')
+    -d "{
+        \"method\": \"GET\",
+        \"url\": \"http://127.0.0.1:$PORT/version\",
+        \"headers\": [],
+        \"timeout\": 10
+    }")
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Private network target returns ssrf_blocked error" "ssrf_blocked" "$ERROR_TYPE"
+SSRF_BLOCKED=$(curl -s "http://localhost:$SSRF_PORT/metrics" | jq -r '.blockedRequests.ssrf_blocked')
+check_result "Private network block increments ssrf_blocked counter" "1" "$SSRF_BLOCKED"
+LOGGED=$(grep -c "^\[PROXY\] .*BLOCKED ssrf_blocked: private network address prevented request to: http://127.0.0.1:$PORT/version" /tmp/proxy-ssrf.log)
+check_result "HTTP client logs through the proxy's [PROXY] logger" "1" "$LOGGED"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$SSRF_PORT/proxy/validate" \
+    -H "Content-Type: application/json" \
+    -d "{\"url\": \"http://127.0.0.1:$PORT/version\"}")
+RULE=$(echo "$RESPONSE" | jq -r '.rule')
+check_result "Validate reports private network target as ssrf_blocked" "ssrf_blocked" "$RULE"
+SSRF_BLOCKED=$(curl -s "http://localhost:$SSRF_PORT/metrics" | jq -r '.blockedRequests.ssrf_blocked')
+check_result "Validate does not increment ssrf_blocked counter" "1" "$SSRF_BLOCKED"
+
+kill $SSRF_PID 2>/dev/null || true
+wait $SSRF_PID 2>/dev/null || true
+
+# Profiling endpoints are only served with --pprof
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "$PROXY_URL/debug/pprof/")
+check_result "Profiling endpoints return 404 when disabled" "404" "$STATUS"
+
+PPROF_PORT=$((PORT + 21))
+./build/rbite-proxy --port $PPROF_PORT --pprof --no-upgrade-check > /tmp/proxy-pprof.log 2>&1 &
+PPROF_PID=$!
+sleep 1
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" "http://localhost:$PPROF_PORT/debug/pprof/")
+check_result "Profiling index responds when enabled" "200" "$STATUS"
+HEAP_PROFILE=$(curl -s "http://localhost:$PPROF_PORT/debug/pprof/heap?debug=1" | head -1 | grep -c '^heap profile:')
+check_result "Heap profile is served when enabled" "1" "$HEAP_PROFILE"
+
+kill $PPROF_PID 2>/dev/null || true
+wait $PPROF_PID 2>/dev/null || true
+
+# Instance dialing upstream with the low-latency socket options changed from Go's defaults
+SOCKET_OPTIONS_PORT=$((PORT + 25))
+./build/rbite-proxy --port $SOCKET_OPTIONS_PORT --tcp-fast-open --tcp-nodelay=false --no-upgrade-check > /tmp/proxy-socketoptions.log 2>&1 &
+SOCKET_OPTIONS_PID=$!
+sleep 1
+
+for ATTEMPT in 1 2; do
+    RESPONSE=$(curl -s -X POST "http://localhost:$SOCKET_OPTIONS_PORT/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"$PROXY_URL/health\", \"headers\": [], \"timeout\": 10}")
+    STATUS=$(echo "$RESPONSE" | jq -r '.response_status')
+    check_result "Upstream request with TCP Fast Open and Nagle enabled succeeds (attempt $ATTEMPT)" "200" "$STATUS"
+done
+
+kill $SOCKET_OPTIONS_PID 2>/dev/null || true
+wait $SOCKET_OPTIONS_PID 2>/dev/null || true
+
+# Instance with --source-address binds upstream connections to the given local IP
+# (Linux routes all of 127.0.0.0/8 to loopback, so 127.0.0.2 is a second local address there)
+if [ "$(uname)" = "Linux" ] && command -v python3 > /dev/null 2>&1; then
+    SOURCE_MOCK_PORT=$((PORT + 51))
+    python3 -c '
+import http.server, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        body = self.client_address[0].encode()
+        self.send_response(200)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $SOURCE_MOCK_PORT &
+    SOURCE_MOCK_PID=$!
+
+    SOURCE_ADDRESS_PORT=$((PORT + 52))
+    ./build/rbite-proxy --port $SOURCE_ADDRESS_PORT --source-address 127.0.0.2 --no-upgrade-check > /tmp/proxy-sourceaddress.log 2>&1 &
+    SOURCE_ADDRESS_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "http://localhost:$SOURCE_ADDRESS_PORT/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$SOURCE_MOCK_PORT/\", \"timeout\": 10}")
+    check_result "--source-address sets the source IP of upstream connections" "127.0.0.2" "$(echo "$RESPONSE" | jq -r '.response_data')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+        -H "Content-Type: application/json" \
+        -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$SOURCE_MOCK_PORT/\", \"timeout\": 10}")
+    check_result "Without --source-address the OS picks the source IP" "127.0.0.1" "$(echo "$RESPONSE" | jq -r '.response_data')"
+
+    kill $SOURCE_ADDRESS_PID $SOURCE_MOCK_PID 2>/dev/null || true
+    wait $SOURCE_ADDRESS_PID $SOURCE_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping --source-address test (needs Linux and python3)"
+fi
+
+# A source address that isn't local is rejected at startup (192.0.2.1 is reserved for documentation)
+if ./build/rbite-proxy --port $((PORT + 52)) --source-address 192.0.2.1 --no-upgrade-check > /tmp/proxy-sourceaddress.log 2>&1; then
+    STARTED=true
+else
+    STARTED=false
+fi
+check_result "--source-address must be a local address" "false" "$STARTED"
+
+# Instance with --form-forward-headers passes allowlisted client headers through /proxy/form
+if command -v python3 > /dev/null 2>&1; then
+    FORWARD_MOCK_PORT=$((PORT + 55))
+    python3 -c '
+import http.server, json, sys
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_POST(self):
+        self.rfile.read(int(self.headers.get("Content-Length", 0)))
+        body = json.dumps({name: self.headers.get(name) for name in ("Authorization", "X-Secret")}).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $FORWARD_MOCK_PORT &
+    FORWARD_MOCK_PID=$!
+
+    FORM_FORWARD_PORT=$((PORT + 56))
+    ./build/rbite-proxy --port $FORM_FORWARD_PORT --form-forward-headers authorization --no-upgrade-check > /tmp/proxy-formforward.log 2>&1 &
+    FORM_FORWARD_PID=$!
+    sleep 1
+
+    RESPONSE=$(curl -s -X POST "http://localhost:$FORM_FORWARD_PORT/proxy/form?url=http://127.0.0.1:$FORWARD_MOCK_PORT/" \
+        -H "Authorization: Bearer client-token" -H "X-Secret: hidden" -d "field=value")
+    UPSTREAM=$(echo "$RESPONSE" | jq -r '.response_data')
+    check_result "Allowlisted Authorization is forwarded by /proxy/form" "Bearer client-token" "$(echo "$UPSTREAM" | jq -r '.Authorization')"
+    check_result "Headers not in the allowlist aren't forwarded" "null" "$(echo "$UPSTREAM" | jq -r '."X-Secret"')"
+
+    RESPONSE=$(curl -s -X POST "http://localhost:$FORM_FORWARD_PORT/proxy/form?url=http://127.0.0.1:$FORWARD_MOCK_PORT/&headers=Authorization:%20Bearer%20param-token" \
+        -H "Authorization: Bearer client-token" -d "field=value")
+    check_result "The headers param wins over a forwarded header" "Bearer param-token" "$(echo "$RESPONSE" | jq -r '.response_data' | jq -r '.Authorization')"
+
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/form?url=http://127.0.0.1:$FORWARD_MOCK_PORT/" \
+        -H "Authorization: Bearer client-token" -d "field=value")
+    check_result "Client headers aren't forwarded by default" "null" "$(echo "$RESPONSE" | jq -r '.response_data' | jq -r '.Authorization')"
+
+    kill $FORM_FORWARD_PID $FORWARD_MOCK_PID 2>/dev/null || true
+    wait $FORM_FORWARD_PID $FORWARD_MOCK_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping --form-forward-headers test (python3 not available)"
+fi
+
+# Instance with --oauth2-config injects client-credentials tokens, shares fetches and refreshes before expiry
+if command -v python3 > /dev/null 2>&1; then
+    OAUTH_MOCK_PORT=$((PORT + 64))
+    python3 -c '
+import http.server, json, sys, threading, time
+issued = [0]
+lock = threading.Lock()
+class Handler(http.server.BaseHTTPRequestHandler):
+    def reply(self, body):
+        body = json.dumps(body).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+    def do_POST(self):
+        self.rfile.read(int(self.headers.get("Content-Length", 0)))
+        time.sleep(0.5)
+        with lock:
+            issued[0] += 1
+            n = issued[0]
+        self.reply({"access_token": "token-%d" % n, "token_type": "Bearer", "expires_in": 4})
+    def do_GET(self):
+        if self.path == "/issued":
+            self.reply({"issued": issued[0]})
+        else:
+            self.reply({"authorization": self.headers.get("Authorization")})
+    def log_message(self, *args):
+        pass
+http.server.ThreadingHTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+' $OAUTH_MOCK_PORT &
+    OAUTH_MOCK_PID=$!
+
+    OAUTH_CONFIG=$(mktemp)
+    echo "[{\"hosts\": [\"127.0.0.1\"], \"tokenUrl\": \"http://127.0.0.1:$OAUTH_MOCK_PORT/token\", \"clientId\": \"id\", \"clientSecret\": \"secret\"}]" > "$OAUTH_CONFIG"
+    OAUTH_PORT=$((PORT + 65))
+    ./build/rbite-proxy --port $OAUTH_PORT --oauth2-config "$OAUTH_CONFIG" --no-upgrade-check > /tmp/proxy-oauth2.log 2>&1 &
+    OAUTH_PID=$!
+    sleep 1
+
+    oauth_request() {
+        curl -s -X POST "http://localhost:$OAUTH_PORT/proxy/request" -H "Content-Type: application/json" \
+            -d "{\"method\": \"GET\", \"url\": \"http://127.0.0.1:$OAUTH_MOCK_PORT/api\"}" | jq -r '.response_data' | jq -r '.authorization'
+    }
+    oauth_issued() {
+        curl -s "http://127.0.0.1:$OAUTH_MOCK_PORT/issued" | jq -r '.issued'
+    }
+
+    # Concurrent requests without a cached token share one fetch
+    OAUTH_OUT=$(mktemp -d)
+    OAUTH_PIDS=""
+    for i in 1 2 3; do
+        oauth_request > "$OAUTH_OUT/$i" &
+        OAUTH_PIDS="$OAUTH_PIDS $!"
+    done
+    wait $OAUTH_PIDS
+    check_result "OAuth2 token is injected from the token endpoint" "Bearer token-1" "$(cat "$OAUTH_OUT/1")"
+    check_result "Concurrent requests share one token fetch" "Bearer token-1 Bearer token-1 1" "$(cat "$OAUTH_OUT/2") $(cat "$OAUTH_OUT/3") $(oauth_issued)"
+    check_result "Cached OAuth2 token is reused" "Bearer token-1 1" "$(oauth_request) $(oauth_issued)"
+
+    # Past the refresh point (half of a 4s lifetime) the unexpired token is still used while a new one is fetched
+    sleep 2.3
+    START=$(date +%s%N)
+    TOKEN=$(oauth_request)
+    ELAPSED_MS=$(( ($(date +%s%N) - START) / 1000000 ))
+    check_result "Token due for refresh is used without waiting for the fetch" "Bearer token-1 true" "$TOKEN $([ $ELAPSED_MS -lt 400 ] && echo true || echo false)"
+    sleep 1
+    check_result "OAuth2 token is refreshed before it expires" "Bearer token-2 2" "$(oauth_request) $(oauth_issued)"
+
+    kill $OAUTH_PID $OAUTH_MOCK_PID 2>/dev/null || true
+    wait $OAUTH_PID $OAUTH_MOCK_PID 2>/dev/null || true
+    rm -rf "$OAUTH_CONFIG" "$OAUTH_OUT"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping --oauth2-config test (python3 not available)"
+fi
+
+# Instance with a read-only allowed methods policy
+ALLOWED_METHODS=$(mktemp)
+printf '*: GET, HEAD\n' > "$ALLOWED_METHODS"
+ALLOWED_METHODS_PORT=$((PORT + 23))
+./build/rbite-proxy --port $ALLOWED_METHODS_PORT --allowed-methods "$ALLOWED_METHODS" --no-upgrade-check > /tmp/proxy-allowedmethods.log 2>&1 &
+ALLOWED_METHODS_PID=$!
+sleep 1
+
+STATUS=$(curl -s -o /tmp/allowed-methods-body.json -w "%{http_code}" -X POST "http://localhost:$ALLOWED_METHODS_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "POST", "url": "https://httpbin.org/post", "body": "{}", "timeout": 10}')
+ERROR_TYPE=$(jq -r '.error_type' /tmp/allowed-methods-body.json)
+check_result "Read-only policy rejects POST via /proxy/request" "405" "$STATUS"
+check_result "Rejected method reports method_not_allowed" "method_not_allowed" "$ERROR_TYPE"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "http://localhost:$ALLOWED_METHODS_PORT/proxy/form?url=https://httpbin.org/post" \
+    -d "name=value")
+check_result "Read-only policy rejects the default POST via /proxy/form" "405" "$STATUS"
+
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "http://localhost:$ALLOWED_METHODS_PORT/proxy/form?url=https://httpbin.org/post" \
+    -H "X-HTTP-Method-Override: delete" -d "name=value")
+check_result "Read-only policy applies to method overrides" "405" "$STATUS"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$ALLOWED_METHODS_PORT/proxy/batch" \
+    -H "Content-Type: application/json" \
+    -d '{"requests": [{"method": "POST", "url": "https://httpbin.org/post", "body": "{}", "timeout": 10}, {"method": "DELETE", "url": "https://httpbin.org/delete", "timeout": 10}]}')
+ERROR_TYPES=$(echo "$RESPONSE" | jq -r '[.results[].error_type] | join(" ")')
+check_result "Read-only policy rejects POST and DELETE wrapped in /proxy/batch" "method_not_allowed method_not_allowed" "$ERROR_TYPES"
+
+RESPONSE=$(curl -s -X POST "http://localhost:$ALLOWED_METHODS_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{"method": "GET", "url": "https://httpbin.org/get", "timeout": 10}')
 SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
-STATUS=$(echo "$RESPONSE" | jq -r '.response_status')
-check_result "Path parameter substitution works" "true" "$SUCCESS"
-check_result "Path parameter :code replaced with 200" "200" "$STATUS"
+check_result "Read-only policy lets GET through" "true" "$SUCCESS"
 
-echo ""
+kill $ALLOWED_METHODS_PID 2>/dev/null || true
+wait $ALLOWED_METHODS_PID 2>/dev/null || true
+rm -f "$ALLOWED_METHODS" /tmp/allowed-methods-body.json
 
-# ========================================
-# Loop Detection Tests
-# ========================================
-echo -e "${YELLOW}━━━ Loop Detection Tests ━━━${NC}"
+# Instance adding default headers to every outbound request, unless the caller sets them
+DEFAULT_HEADERS=$(mktemp)
+printf 'X-Proxy-Source: slingshot\nX-Team: core\n' > "$DEFAULT_HEADERS"
+DEFAULT_HEADERS_PORT=$((PORT + 20))
+./build/rbite-proxy --port $DEFAULT_HEADERS_PORT --default-headers "$DEFAULT_HEADERS" --no-upgrade-check > /tmp/proxy-defaultheaders.log 2>&1 &
+DEFAULT_HEADERS_PID=$!
+sleep 1
 
-# Test loop detection via hostname blocking
-# Note: /health endpoint is allowed on any hostname, so we test with a different endpoint
-RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+RESPONSE=$(curl -s -X POST "http://localhost:$DEFAULT_HEADERS_PORT/proxy/request" \
     -H "Content-Type: application/json" \
     -d '{
         "method": "GET",
-        "url": "http://p.requestbite.com/some-endpoint",
-        "headers": [],
-        "timeout": 10,
-        "followRedirects": true
+        "url": "https://httpbin.org/headers",
+        "headers": ["x-team: caller"],
+        "timeout": 10
     }')
-SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
-ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
-check_result "Loop detection blocks p.requestbite.com" "false" "$SUCCESS"
-check_result "Loop detection returns loop_detected error" "loop_detected" "$ERROR_TYPE"
+SOURCE_HEADER=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .headers["X-Proxy-Source"]')
+TEAM_HEADER=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .headers["X-Team"]')
+check_result "Default header is added to outbound requests" "slingshot" "$SOURCE_HEADER"
+check_result "Caller header overrides default header of the same name" "caller" "$TEAM_HEADER"
 
-# Test loop detection via User-Agent
-RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+kill $DEFAULT_HEADERS_PID 2>/dev/null || true
+wait $DEFAULT_HEADERS_PID 2>/dev/null || true
+rm -f "$DEFAULT_HEADERS"
+
+# Instance with JSON body redaction rules
+BODY_RULES=$(mktemp)
+printf 'redact request password\nredact response origin\n' > "$BODY_RULES"
+BODY_RULES_PORT=$((PORT + 8))
+./build/rbite-proxy --port $BODY_RULES_PORT --body-rules "$BODY_RULES" --no-upgrade-check > /tmp/proxy-bodyrules.log 2>&1 &
+BODY_RULES_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$BODY_RULES_PORT/proxy/request" \
     -H "Content-Type: application/json" \
-    -H "User-Agent: rb-slingshot/1.0.0" \
     -d '{
-        "method": "GET",
-        "url": "https://httpbin.org/get",
-        "headers": [],
+        "method": "POST",
+        "url": "https://httpbin.org/anything",
+        "headers": ["Content-Type: application/json"],
+        "body": "{\"user\": \"alice\", \"password\": \"hunter2\"}",
         "timeout": 10,
-        "followRedirects": true
+        "parseJSON": true
     }')
-SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
-ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
-check_result "Loop detection blocks rb-slingshot User-Agent" "false" "$SUCCESS"
-check_result "User-Agent loop returns loop_detected error" "loop_detected" "$ERROR_TYPE"
+SENT_PASSWORD=$(echo "$RESPONSE" | jq -r '.response_json.json.password')
+SENT_USER=$(echo "$RESPONSE" | jq -r '.response_json.json.user')
+ORIGIN=$(echo "$RESPONSE" | jq -r '.response_json.origin')
+check_result "Body rule redacts JSON request field" "[REDACTED]" "$SENT_PASSWORD"
+check_result "Body rule leaves other request fields intact" "alice" "$SENT_USER"
+check_result "Body rule redacts JSON response field" "[REDACTED]" "$ORIGIN"
+
+kill $BODY_RULES_PID 2>/dev/null || true
+wait $BODY_RULES_PID 2>/dev/null || true
+rm -f "$BODY_RULES"
 
 echo ""
 
@@ -286,6 +3176,35 @@ SUCCESS=$(echo "$RESPONSE" | jq -r '.success')
 ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
 check_result "Invalid endpoint returns success=false" "false" "$SUCCESS"
 check_result "Invalid endpoint returns endpoint_not_found error" "endpoint_not_found" "$ERROR_TYPE"
+STATUS=$(curl -s -o /dev/null -w '%{http_code}' "$PROXY_URL/invalid/endpoint")
+check_result "Invalid endpoint returns 404" "404" "$STATUS"
+
+# Second instance with plain-text 404 responses
+NOT_FOUND_PORT=$((PORT + 3))
+./build/rbite-proxy --port $NOT_FOUND_PORT --not-found-format text --no-upgrade-check > /tmp/proxy-notfound.log 2>&1 &
+NOT_FOUND_PID=$!
+sleep 1
+
+CONTENT_TYPE=$(curl -s -o /tmp/notfound-body -w '%{content_type}' "http://localhost:$NOT_FOUND_PORT/invalid/endpoint")
+check_result "Text 404 mode returns text/plain" "text/plain; charset=utf-8" "$CONTENT_TYPE"
+check_result "Text 404 mode returns a plain message" "Endpoint not found: /invalid/endpoint" "$(cat /tmp/notfound-body)"
+STATUS=$(curl -s -o /dev/null -w '%{http_code}' "http://localhost:$NOT_FOUND_PORT/invalid/endpoint")
+check_result "Text 404 mode keeps the 404 status" "404" "$STATUS"
+
+kill $NOT_FOUND_PID 2>/dev/null || true
+wait $NOT_FOUND_PID 2>/dev/null || true
+
+# Third instance with a custom JSON 404 body
+./build/rbite-proxy --port $NOT_FOUND_PORT --not-found-body '{"error":"no such endpoint"}' --no-upgrade-check > /tmp/proxy-notfound.log 2>&1 &
+NOT_FOUND_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s "http://localhost:$NOT_FOUND_PORT/invalid/endpoint")
+check_result "Custom JSON 404 body is returned as-is" "no such endpoint" "$(echo "$RESPONSE" | jq -r '.error')"
+
+kill $NOT_FOUND_PID 2>/dev/null || true
+wait $NOT_FOUND_PID 2>/dev/null || true
+rm -f /tmp/notfound-body
 
 # Test 405 - Invalid method (returns 400 per code)
 RESPONSE=$(curl -s -X GET "$PROXY_URL/proxy/request")
@@ -305,6 +3224,42 @@ ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
 check_result "Missing URL returns success=false" "false" "$SUCCESS"
 check_result "Missing URL returns request_format_error" "request_format_error" "$ERROR_TYPE"
 
+# Test DNS failure is reported distinctly
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "http://nonexistent.invalid/",
+        "timeout": 10
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Unresolvable host returns dns_error" "dns_error" "$ERROR_TYPE"
+
+# Test connection refused is reported distinctly
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "http://127.0.0.1:1/",
+        "timeout": 10
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Closed port returns connection_refused" "connection_refused" "$ERROR_TYPE"
+
+# Test slow-trickle body with partialOnTimeout returns the bytes read so far
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "method": "GET",
+        "url": "https://httpbin.org/drip?duration=6&numbytes=6&delay=0",
+        "timeout": 3,
+        "partialOnTimeout": true
+    }')
+PARTIAL=$(echo "$RESPONSE" | jq -r '.partial')
+REASON=$(echo "$RESPONSE" | jq -r '.partial_reason')
+check_result "Timeout mid-body returns partial=true" "true" "$PARTIAL"
+check_result "Timeout mid-body returns partial_reason=timeout" "timeout" "$REASON"
+
 echo ""
 
 # ========================================
@@ -320,6 +3275,81 @@ RESPONSE=$(curl -s -X POST "$PROXY_URL/file" \
     }")
 check_result "File endpoint returns file content" "Hello from local file!" "$RESPONSE"
 
+# Test Range with a matching If-Range returns the requested range
+RANGE_FILE=$(mktemp)
+printf '0123456789abcdef' > "$RANGE_FILE"
+ETAG=$(curl -s -D - -o /dev/null -X POST "$PROXY_URL/file" \
+    -H "Content-Type: application/json" \
+    -d "{\"path\": \"$RANGE_FILE\"}" | grep -i '^etag:' | cut -d' ' -f2 | tr -d '\r')
+STATUS=$(curl -s -o /tmp/range-body -w '%{http_code}' -X POST "$PROXY_URL/file" \
+    -H "Content-Type: application/json" \
+    -H "Range: bytes=4-7" \
+    -H "If-Range: $ETAG" \
+    -d "{\"path\": \"$RANGE_FILE\"}")
+check_result "Unchanged file with If-Range returns 206" "206" "$STATUS"
+check_result "Unchanged file with If-Range returns the range" "4567" "$(cat /tmp/range-body)"
+
+# Test Range with a stale If-Range returns the whole file
+sleep 1
+printf 'fedcba9876543210' > "$RANGE_FILE"
+STATUS=$(curl -s -o /tmp/range-body -w '%{http_code}' -X POST "$PROXY_URL/file" \
+    -H "Content-Type: application/json" \
+    -H "Range: bytes=4-7" \
+    -H "If-Range: $ETAG" \
+    -d "{\"path\": \"$RANGE_FILE\"}")
+check_result "Changed file with If-Range returns 200" "200" "$STATUS"
+check_result "Changed file with If-Range returns the whole file" "fedcba9876543210" "$(cat /tmp/range-body)"
+rm -f "$RANGE_FILE" /tmp/range-body
+
+# Test follow streams the existing content, then bytes appended while the request is open
+FOLLOW_FILE=$(mktemp)
+FOLLOW_OUT=$(mktemp)
+printf 'first line\n' > "$FOLLOW_FILE"
+curl -s -N -D "$FOLLOW_OUT.headers" -X POST "$PROXY_URL/file" \
+    -H "Content-Type: application/json" \
+    -d "{\"path\": \"$FOLLOW_FILE\", \"follow\": true, \"followSeconds\": 3}" > "$FOLLOW_OUT" &
+FOLLOW_PID=$!
+sleep 1
+EARLY=$(cat "$FOLLOW_OUT")
+printf 'appended line\n' >> "$FOLLOW_FILE"
+sleep 1
+STREAMED=$(cat "$FOLLOW_OUT" | tr '\n' '|')
+wait $FOLLOW_PID 2>/dev/null
+FOLLOW_STATUS=$?
+check_result "Followed file streams the existing content first" "first line" "$EARLY"
+check_result "Followed file streams appended bytes as they arrive" "first line|appended line|" "$STREAMED"
+check_result "Followed file marks the response as streaming" "1" "$(grep -ci '^X-Slingshot-Streaming: true' "$FOLLOW_OUT.headers")"
+check_result "Follow ends cleanly after followSeconds" "0" "$FOLLOW_STATUS"
+rm -f "$FOLLOW_FILE" "$FOLLOW_OUT" "$FOLLOW_OUT.headers"
+
+# Test --mime-types overrides the Content-Type by extension, leaving other files alone
+MIME_TYPES_PORT=$((PORT + 40))
+MIME_TYPES=$(mktemp)
+MIME_DIR=$(mktemp -d)
+cat > "$MIME_TYPES" << 'EOF'
+.md: text/markdown; charset=utf-8
+WebManifest: application/manifest+json
+EOF
+echo "# Notes" > "$MIME_DIR/notes.MD"
+echo '{"name": "app"}' > "$MIME_DIR/site.webmanifest"
+echo "plain" > "$MIME_DIR/plain.txt"
+./build/rbite-proxy --port $MIME_TYPES_PORT --enable-local-files --mime-types "$MIME_TYPES" --no-upgrade-check > /tmp/proxy-mimetypes.log 2>&1 &
+MIME_TYPES_PID=$!
+sleep 1
+
+for MIME_CASE in "notes.MD|text/markdown; charset=utf-8" "site.webmanifest|application/manifest+json" "plain.txt|text/plain; charset=utf-8"; do
+    MIME_FILE=${MIME_CASE%%|*}
+    MIME_EXPECTED=${MIME_CASE#*|}
+    MIME_ACTUAL=$(curl -s -D - -o /dev/null -X POST "http://localhost:$MIME_TYPES_PORT/file" \
+        -H "Content-Type: application/json" \
+        -d "{\"path\": \"$MIME_DIR/$MIME_FILE\"}" | grep -i '^content-type:' | cut -d' ' -f2- | tr -d '\r')
+    check_result "--mime-types serves $MIME_FILE as $MIME_EXPECTED" "$MIME_EXPECTED" "$MIME_ACTUAL"
+done
+
+kill $MIME_TYPES_PID 2>/dev/null || true
+wait $MIME_TYPES_PID 2>/dev/null || true
+rm -rf "$MIME_TYPES" "$MIME_DIR"
+
 # Test file not found
 RESPONSE=$(curl -s -X POST "$PROXY_URL/file" \
     -H "Content-Type: application/json" \
@@ -342,6 +3372,101 @@ ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
 check_result "Directory path returns success=false" "false" "$SUCCESS"
 check_result "Directory path returns file_access_error" "file_access_error" "$ERROR_TYPE"
 
+# Test HEAD on an existing file returns metadata without a body
+HEADERS=$(curl -s -I "$PROXY_URL/file?path=$TEST_FILE")
+STATUS=$(echo "$HEADERS" | head -1 | awk '{print $2}')
+CONTENT_LENGTH=$(echo "$HEADERS" | grep -i '^content-length:' | awk '{print $2}' | tr -d '\r')
+check_result "HEAD on existing file returns 200" "200" "$STATUS"
+check_result "HEAD on existing file reports Content-Length" "$(wc -c < "$TEST_FILE" | tr -d ' ')" "$CONTENT_LENGTH"
+
+# Test HEAD on a missing file returns 404
+STATUS=$(curl -s -I -o /dev/null -w '%{http_code}' "$PROXY_URL/file?path=/nonexistent/file.txt")
+check_result "HEAD on missing file returns 404" "404" "$STATUS"
+
+# Test HEAD on a directory probes it without listing
+STATUS=$(curl -s -I -o /dev/null -w '%{http_code}' "$PROXY_URL/dir?path=$TEST_DIR")
+check_result "HEAD on existing directory returns 200" "200" "$STATUS"
+
+# Test /file/batch reads several files within the size budget, with errors per file
+BATCH_DIR=$(mktemp -d)
+printf 'first file' > "$BATCH_DIR/a.txt"
+printf 'second file' > "$BATCH_DIR/b.txt"
+printf 'third file' > "$BATCH_DIR/c.txt"
+RESPONSE=$(curl -s -X POST "$PROXY_URL/file/batch" -H "Content-Type: application/json" \
+    -d "{\"files\": [{\"path\": \"$BATCH_DIR/a.txt\"}, {\"path\": \"$BATCH_DIR/missing.txt\"}, {\"path\": \"$BATCH_DIR/b.txt\"}]}")
+check_result "/file/batch returns the content of each file" "first file|second file" "$(echo "$RESPONSE" | jq -r '[.results[0].content, .results[2].content] | join("|")')"
+check_result "/file/batch reports a missing file on its own result" "false file_not_found" "$(echo "$RESPONSE" | jq -r '.results[1] | "\(.success) \(.errorType)"')"
+check_result "/file/batch reports the MIME type and size" "text/plain; charset=utf-8 11" "$(echo "$RESPONSE" | jq -r '.results[2] | "\(.mimeType) \(.sizeBytes)"')"
+
+RESPONSE=$(curl -s -X POST "$PROXY_URL/file/batch" -H "Content-Type: application/json" \
+    -d "{\"maxBytes\": 15, \"files\": [{\"path\": \"$BATCH_DIR/a.txt\"}, {\"path\": \"$BATCH_DIR/b.txt\"}, {\"path\": \"$BATCH_DIR/c.txt\"}]}")
+check_result "/file/batch gives the budget to files in request order" "first file|secon|" "$(echo "$RESPONSE" | jq -r '.results | map(.content // "") | join("|")')"
+check_result "/file/batch marks files cut by the budget as truncated" "false true true 15" "$(echo "$RESPONSE" | jq -r '"\(.results[0].truncated // false) \(.results[1].truncated) \(.truncated) \(.totalBytes)"')"
+
+# Test /file/batch with --file-root resolves relative paths inside it and rejects paths outside
+FILE_ROOT_PORT=$((PORT + 57))
+./build/rbite-proxy --port $FILE_ROOT_PORT --enable-local-files --file-root "$BATCH_DIR" --no-upgrade-check > /tmp/proxy-fileroot.log 2>&1 &
+FILE_ROOT_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$FILE_ROOT_PORT/file/batch" -H "Content-Type: application/json" \
+    -d "{\"files\": [{\"path\": \"c.txt\"}, {\"path\": \"$TEST_FILE\"}]}")
+check_result "/file/batch resolves relative paths inside --file-root" "third file" "$(echo "$RESPONSE" | jq -r '.results[0].content')"
+check_result "/file/batch rejects files outside --file-root" "false file_access_error" "$(echo "$RESPONSE" | jq -r '.results[1] | "\(.success) \(.errorType)"')"
+
+# /file, including follow mode, is confined to --file-root too
+check_result "/file resolves relative paths inside --file-root" "third file" "$(curl -s -X POST "http://localhost:$FILE_ROOT_PORT/file" -d '{"path": "c.txt"}')"
+STATUS=$(curl -s -o /tmp/fileroot-body.json -w "%{http_code}" -X POST "http://localhost:$FILE_ROOT_PORT/file" -d "{\"path\": \"$TEST_FILE\"}")
+check_result "/file rejects files outside --file-root" "403 file_access_error" "$STATUS $(jq -r '.error_type' /tmp/fileroot-body.json)"
+STATUS=$(curl -s -m 5 -o /tmp/fileroot-body.json -w "%{http_code}" -X POST "http://localhost:$FILE_ROOT_PORT/file" \
+    -d "{\"path\": \"$TEST_FILE\", \"follow\": true, \"followSeconds\": 1}")
+check_result "/file follow rejects files outside --file-root" "403 file_access_error" "$STATUS $(jq -r '.error_type' /tmp/fileroot-body.json)"
+
+# /dir listings, HEAD probes and directory size walks stay inside --file-root as well
+check_result "/dir lists relative paths inside --file-root" "a.txt b.txt c.txt" "$(curl -s -X POST "http://localhost:$FILE_ROOT_PORT/dir" -d '{"path": "."}' | jq -r '[.dir[].name] | join(" ")')"
+check_result "/dir defaults to --file-root" "3" "$(curl -s -X POST "http://localhost:$FILE_ROOT_PORT/dir" -d '{}' | jq '.dir | length')"
+STATUS=$(curl -s -o /tmp/fileroot-body.json -w "%{http_code}" -X POST "http://localhost:$FILE_ROOT_PORT/dir" -d "{\"path\": \"$TEST_DIR\"}")
+check_result "/dir rejects directories outside --file-root" "403 file_access_error" "$STATUS $(jq -r '.error_type' /tmp/fileroot-body.json)"
+STATUS=$(curl -s -o /tmp/fileroot-body.json -w "%{http_code}" -X POST "http://localhost:$FILE_ROOT_PORT/dir" -d '{"path": "..", "includeDirSizes": true}')
+check_result "/dir size walks can't leave --file-root" "403 file_access_error" "$STATUS $(jq -r '.error_type' /tmp/fileroot-body.json)"
+STATUS=$(curl -s -o /dev/null -w "%{http_code}" -I "http://localhost:$FILE_ROOT_PORT/dir?path=$TEST_DIR")
+check_result "HEAD /dir rejects directories outside --file-root" "403" "$STATUS"
+rm -f /tmp/fileroot-body.json
+
+kill $FILE_ROOT_PID 2>/dev/null || true
+wait $FILE_ROOT_PID 2>/dev/null || true
+rm -rf "$BATCH_DIR"
+
+# Test bodyFromPath sends a local file as the upstream request body
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"method\": \"POST\",
+        \"url\": \"https://httpbin.org/post\",
+        \"headers\": [\"Content-Type: text/plain\"],
+        \"bodyFromPath\": \"$TEST_FILE\",
+        \"timeout\": 10
+    }")
+UPSTREAM_BODY=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .data')
+check_result "bodyFromPath sends the file contents upstream" "Hello from local file!" "$UPSTREAM_BODY"
+
+# Test bodyFromPath is refused when local files are disabled
+NO_FILES_PORT=$((PORT + 27))
+./build/rbite-proxy --port $NO_FILES_PORT --no-upgrade-check > /tmp/proxy-nofiles.log 2>&1 &
+NO_FILES_PID=$!
+sleep 1
+
+STATUS=$(curl -s -o /tmp/nofiles-body.json -w "%{http_code}" -X POST "http://localhost:$NO_FILES_PORT/proxy/request" \
+    -H "Content-Type: application/json" \
+    -d "{\"method\": \"POST\", \"url\": \"https://httpbin.org/post\", \"bodyFromPath\": \"$TEST_FILE\", \"timeout\": 10}")
+ERROR_TYPE=$(jq -r '.error_type' /tmp/nofiles-body.json)
+check_result "bodyFromPath without --enable-local-files returns 403" "403" "$STATUS"
+check_result "bodyFromPath without --enable-local-files returns feature_disabled" "feature_disabled" "$ERROR_TYPE"
+
+kill $NO_FILES_PID 2>/dev/null || true
+wait $NO_FILES_PID 2>/dev/null || true
+rm -f /tmp/nofiles-body.json
+
 echo ""
 
 # ========================================
@@ -364,6 +3489,14 @@ check_result "Directory contains test.txt" "1" "$DIR_COUNT"
 ENTRY_NAME=$(echo "$RESPONSE" | jq -r '.dir[0].name')
 check_result "Directory entry name is test.txt" "test.txt" "$ENTRY_NAME"
 
+# Test pretty-printed directory listing
+RESPONSE=$(curl -s -X POST "$PROXY_URL/dir?pretty=true" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"path\": \"$TEST_DIR\"
+    }")
+check_result "pretty=true indents the directory listing" "true" "$([ "$(echo "$RESPONSE" | wc -l)" -gt 1 ] && echo "$RESPONSE" | jq -e . > /dev/null && echo true || echo false)"
+
 # Test directory not found
 RESPONSE=$(curl -s -X POST "$PROXY_URL/dir" \
     -H "Content-Type: application/json" \
@@ -386,6 +3519,149 @@ ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
 check_result "File path to /dir returns success=false" "false" "$SUCCESS"
 check_result "File path to /dir returns file_access_error" "file_access_error" "$ERROR_TYPE"
 
+# Test recursive directory size rollup on a nested tree (3 + 5 + 7 bytes)
+SIZE_DIR=$(mktemp -d)
+mkdir -p "$SIZE_DIR/tree/sub/deeper"
+printf 'abc' > "$SIZE_DIR/tree/a.txt"
+printf 'abcde' > "$SIZE_DIR/tree/sub/b.txt"
+printf 'abcdefg' > "$SIZE_DIR/tree/sub/deeper/c.txt"
+RESPONSE=$(curl -s -X POST "$PROXY_URL/dir" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"path\": \"$SIZE_DIR\",
+        \"includeDirSizes\": true
+    }")
+DIR_SIZE=$(echo "$RESPONSE" | jq -r '.dir[] | select(.name == "tree") | .sizeBytes')
+check_result "includeDirSizes reports recursive directory size" "15" "$DIR_SIZE"
+
+# Directory sizes are off by default
+RESPONSE=$(curl -s -X POST "$PROXY_URL/dir" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"path\": \"$SIZE_DIR\"
+    }")
+HAS_SIZE=$(echo "$RESPONSE" | jq -r '.dir[] | select(.name == "tree") | has("sizeBytes")')
+check_result "Directory sizes are omitted by default" "false" "$HAS_SIZE"
+rm -rf "$SIZE_DIR"
+
+# Entries that can't be read are reported in errors instead of silently left out
+# (a readable but unsearchable directory: its names can be listed, the entries not stat'ed)
+if [ "$(id -u)" -ne 0 ]; then
+    LOCKED_DIR=$(mktemp -d)
+    : > "$LOCKED_DIR/secret.txt"
+    chmod 644 "$LOCKED_DIR"
+    RESPONSE=$(curl -s -X POST "$PROXY_URL/dir" \
+        -H "Content-Type: application/json" \
+        -d "{
+            \"path\": \"$LOCKED_DIR\"
+        }")
+    ERROR_NAME=$(echo "$RESPONSE" | jq -r '.errors[0].name')
+    DIR_COUNT=$(echo "$RESPONSE" | jq '.dir | length')
+    check_result "Unreadable directory entry is listed in errors" "secret.txt" "$ERROR_NAME"
+    check_result "Unreadable directory entry is left out of dir" "0" "$DIR_COUNT"
+    chmod 755 "$LOCKED_DIR"
+    rm -rf "$LOCKED_DIR"
+else
+    echo -e "${YELLOW}⚠${NC} Skipping unreadable directory entry test (permissions don't apply to root)"
+fi
+
+# Listings of large directories stop scanning at --max-dir-entries
+LARGE_DIR=$(mktemp -d)
+for i in $(seq 1 500); do : > "$LARGE_DIR/file-$i.txt"; done
+DIR_LIMIT_PORT=$((PORT + 7))
+./build/rbite-proxy --port $DIR_LIMIT_PORT --enable-local-files --max-dir-entries 100 --no-upgrade-check > /tmp/proxy-dirlimit.log 2>&1 &
+DIR_LIMIT_PID=$!
+sleep 1
+
+RESPONSE=$(curl -s -X POST "http://localhost:$DIR_LIMIT_PORT/dir" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"path\": \"$LARGE_DIR\"
+    }")
+DIR_COUNT=$(echo "$RESPONSE" | jq '.dir | length')
+TRUNCATED=$(echo "$RESPONSE" | jq -r '.truncated')
+check_result "Large directory listing stops at --max-dir-entries" "100" "$DIR_COUNT"
+check_result "Capped directory listing is marked truncated" "true" "$TRUNCATED"
+
+kill $DIR_LIMIT_PID 2>/dev/null || true
+wait $DIR_LIMIT_PID 2>/dev/null || true
+rm -rf "$LARGE_DIR"
+
+# --proxy-protocol takes the client address from the PROXY header, so the localhost check
+# applies to the real client rather than the load balancer's connection
+if command -v python3 > /dev/null 2>&1; then
+    PROXY_PROTOCOL_PORT=$((PORT + 32))
+    ./build/rbite-proxy --port $PROXY_PROTOCOL_PORT --proxy-protocol --proxy-protocol-trusted 127.0.0.1 --enable-local-files --enable-body-capture --no-upgrade-check > /tmp/proxy-proxyprotocol.log 2>&1 &
+    PROXY_PROTOCOL_PID=$!
+    # This instance only trusts a load balancer network the test client is not in
+    PROXY_UNTRUSTED_PORT=$((PORT + 80))
+    ./build/rbite-proxy --port $PROXY_UNTRUSTED_PORT --proxy-protocol --proxy-protocol-trusted 192.0.2.0/24 --enable-local-files --no-upgrade-check > /tmp/proxy-proxyprotocol-untrusted.log 2>&1 &
+    PROXY_UNTRUSTED_PID=$!
+    sleep 1
+
+    # Prints the status line code of a request sent after the given PROXY header ("none" = no header)
+    # The request is a /dir listing of the test directory unless an endpoint and JSON body are given
+    # It goes to the PROXY_PROTOCOL_PORT instance unless PROXY_HEADER_PORT is set
+    send_with_proxy_header() {
+        local port=${PROXY_HEADER_PORT:-$PROXY_PROTOCOL_PORT}
+        local endpoint=${3:-/dir}
+        local body=${4:-"{\"path\": \"$TEST_DIR\"}"}
+        python3 -c '
+import socket, struct, sys
+port, version, source, endpoint, body = int(sys.argv[1]), sys.argv[2], sys.argv[3], sys.argv[4], sys.argv[5]
+request = "POST %s HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s" % (endpoint, len(body), body)
+if version == "v1":
+    header = ("PROXY TCP4 %s 127.0.0.1 51234 %d\r\n" % (source, port)).encode()
+elif version == "v2":
+    addresses = socket.inet_aton(source) + socket.inet_aton("127.0.0.1") + struct.pack("!HH", 51234, port)
+    header = b"\r\n\r\n\x00\r\nQUIT\n\x21\x11" + struct.pack("!H", len(addresses)) + addresses
+else:
+    header = b""
+conn = socket.create_connection(("127.0.0.1", port))
+response = b""
+try:
+    conn.sendall(header + request.encode())
+    while True:
+        chunk = conn.recv(4096)
+        if not chunk:
+            break
+        response += chunk
+except ConnectionResetError:
+    pass
+print(response.split(b" ")[1].decode() if response else "closed")
+' "$port" "$1" "$2" "$endpoint" "$body"
+    }
+
+    STATUS=$(send_with_proxy_header v1 203.0.113.7)
+    check_result "PROXY v1 remote client is refused by the localhost check" "403" "$STATUS"
+    LOGGED=$(grep -c "non-localhost: 203.0.113.7:51234" /tmp/proxy-proxyprotocol.log)
+    check_result "PROXY v1 client address is recovered" "1" "$LOGGED"
+
+    STATUS=$(send_with_proxy_header v2 198.51.100.9)
+    check_result "PROXY v2 remote client is refused by the localhost check" "403" "$STATUS"
+    LOGGED=$(grep -c "non-localhost: 198.51.100.9:51234" /tmp/proxy-proxyprotocol.log)
+    check_result "PROXY v2 client address is recovered" "1" "$LOGGED"
+
+    STATUS=$(send_with_proxy_header v1 127.0.0.1)
+    check_result "PROXY header with a localhost client passes the localhost check" "200" "$STATUS"
+
+    STATUS=$(send_with_proxy_header none 127.0.0.1)
+    check_result "Connection without a PROXY header is not served" "400" "$STATUS"
+
+    STATUS=$(send_with_proxy_header v1 203.0.113.7 /proxy/request \
+        '{"method": "POST", "url": "http://127.0.0.1:1/", "body": "x", "captureRequestBody": true}')
+    check_result "Body capture is refused for a remote client" "403" "$STATUS"
+
+    # A peer outside --proxy-protocol-trusted can't claim to be localhost through the header
+    STATUS=$(PROXY_HEADER_PORT=$PROXY_UNTRUSTED_PORT send_with_proxy_header v1 127.0.0.1)
+    check_result "PROXY header from an untrusted peer does not pass the localhost check" "closed" "$STATUS"
+
+    kill $PROXY_PROTOCOL_PID $PROXY_UNTRUSTED_PID 2>/dev/null || true
+    wait $PROXY_PROTOCOL_PID $PROXY_UNTRUSTED_PID 2>/dev/null || true
+else
+    echo -e "${YELLOW}⚠${NC} Skipping PROXY protocol test (python3 not available)"
+fi
+
 echo ""
 
 # ========================================
@@ -403,6 +3679,197 @@ check_result "Form request succeeds" "true" "$SUCCESS"
 FORM_KEY1=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .form.key1')
 check_result "Form data key1 sent correctly" "value1" "$FORM_KEY1"
 
+# Test method override via header takes precedence over the method param
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/form?url=https://httpbin.org/anything&timeout=10&method=POST" \
+    -H "X-HTTP-Method-Override: put" \
+    -d "key1=value1")
+METHOD=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .method')
+check_result "Method override header sends PUT upstream" "PUT" "$METHOD"
+
+# Test method override via _method query param
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/form?url=https://httpbin.org/anything&timeout=10&_method=DELETE" \
+    -d "key1=value1")
+METHOD=$(echo "$RESPONSE" | jq -r '.response_data | fromjson | .method')
+check_result "Method override query param sends DELETE upstream" "DELETE" "$METHOD"
+
+# Test invalid method override is rejected
+RESPONSE=$(curl -s -X POST "$PROXY_URL/proxy/form?url=https://httpbin.org/anything&timeout=10" \
+    -H "X-HTTP-Method-Override: TRACE" \
+    -d "key1=value1")
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.error_type')
+check_result "Invalid method override returns request_format_error" "request_format_error" "$ERROR_TYPE"
+
+echo ""
+
+# ========================================
+# Exec Tests
+# ========================================
+echo -e "${YELLOW}━━━ Exec Tests ━━━${NC}"
+
+# Child only sees the variables provided in the request by default
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "command": "env",
+        "env": {"FOO": "bar"}
+    }')
+STDOUT=$(echo "$RESPONSE" | jq -r '.stdout')
+check_result "Exec child sees only provided env vars" "FOO=bar" "$STDOUT"
+
+# Loader variables are rejected unless the parent env is trusted
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "command": "env",
+        "env": {"LD_PRELOAD": "/tmp/evil.so"}
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.errorType')
+check_result "Exec rejects LD_PRELOAD in env" "exec_failed" "$ERROR_TYPE"
+
+# Working directory inside the exec root is allowed
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"command\": \"ls\",
+        \"workingDir\": \"$TEST_DIR\"
+    }")
+STDOUT=$(echo "$RESPONSE" | jq -r '.stdout')
+check_result "Exec runs in working dir inside exec root" "test.txt" "$STDOUT"
+
+# Working directory escaping the exec root is rejected
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d "{
+        \"command\": \"ls\",
+        \"workingDir\": \"$TEST_DIR/..\"
+    }")
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.errorType')
+check_result "Exec rejects working dir outside exec root" "exec_failed" "$ERROR_TYPE"
+
+# Output beyond the cap is truncated
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "command": "head",
+        "args": ["-c", "2000", "/dev/zero"]
+    }')
+TRUNCATED=$(echo "$RESPONSE" | jq -r '.truncated')
+STDOUT_LENGTH=$(echo "$RESPONSE" | jq -r '.stdout | length')
+check_result "Exec output over the cap is flagged as truncated" "true" "$TRUNCATED"
+check_result "Exec output is cut off at the cap" "1024" "$STDOUT_LENGTH"
+
+# Commands producing far more than the cap are killed
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "command": "yes"
+    }')
+ERROR_TYPE=$(echo "$RESPONSE" | jq -r '.errorType')
+check_result "Exec kills command with runaway output" "exec_failed" "$ERROR_TYPE"
+
+# Stdin is piped to the command
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "command": "cat",
+        "stdin": "piped input"
+    }')
+STDOUT=$(echo "$RESPONSE" | jq -r '.stdout')
+check_result "Exec pipes stdin to command" "piped input" "$STDOUT"
+
+# Base64 stdin is decoded before being piped
+RESPONSE=$(curl -s -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "command": "cat",
+        "stdin": "aGVsbG8gYmFzZTY0",
+        "stdinEncoding": "base64"
+    }')
+STDOUT=$(echo "$RESPONSE" | jq -r '.stdout')
+check_result "Exec decodes base64 stdin" "hello base64" "$STDOUT"
+
+# Streaming mode emits each chunk as it is written, followed by the exit record
+STREAM_EVENTS=$(curl -s -N -X POST "$PROXY_URL/exec" \
+    -H "Content-Type: application/json" \
+    -d '{
+        "command": "sh",
+        "args": ["-c", "echo a; sleep 1; echo b"],
+        "streaming": true
+    }' | while IFS= read -r LINE; do
+        echo "$(date +%s%N) $LINE"
+    done)
+check_result "Exec streaming emits chunks then the exit record in order" "stdout:a stdout:b exit:0" "$(echo "$STREAM_EVENTS" | cut -d' ' -f2- | jq -r '"\(.type):\(.data // .exitCode | tostring | rtrimstr("\n"))"' | paste -sd' ')"
+FIRST_AT=$(echo "$STREAM_EVENTS" | head -n 1 | cut -d' ' -f1)
+LAST_AT=$(echo "$STREAM_EVENTS" | tail -n 1 | cut -d' ' -f1)
+if [ $(( (LAST_AT - FIRST_AT) / 1000000 )) -ge 500 ]; then
+    STREAM_RESULT="incremental"
+else
+    STREAM_RESULT="buffered"
+fi
+check_result "Exec streaming delivers the first chunk before the command exits" "incremental" "$STREAM_RESULT"
+
+# --exec-allowlist permits listed commands and argument patterns only
+EXEC_ALLOW_DIR=$(mktemp -d)
+LS_PATH=$(command -v ls)
+printf 'echo\n%s ^(-a)?$\nprintf\t^ok$\n' "$LS_PATH" > "$EXEC_ALLOW_DIR/allowlist"
+EXEC_ALLOW_PORT=$((PORT + 63))
+# Run from / so a relative command like usr/bin/ls names the allowed binary from the proxy's directory
+(cd / && exec "$OLDPWD/build/rbite-proxy" --port $EXEC_ALLOW_PORT --enable-exec --exec-allowlist "$EXEC_ALLOW_DIR/allowlist" --no-upgrade-check > /tmp/proxy-execallow.log 2>&1) &
+EXEC_ALLOW_PID=$!
+sleep 1
+
+exec_allow() {
+    curl -s -X POST "http://localhost:$EXEC_ALLOW_PORT/exec" -H "Content-Type: application/json" -d "$1"
+}
+check_result "Allowlisted bare command runs" "hi" "$(exec_allow '{"command": "echo", "args": ["hi"]}' | jq -r '.stdout' | tr -d '\n')"
+check_result "Command not in the allowlist is blocked" "exec_failed" "$(exec_allow '{"command": "cat"}' | jq -r '.error_type')"
+check_result "Absolute-path rule allows matching arguments" "true" "$(exec_allow "{\"command\": \"ls\", \"args\": [\"-a\"], \"workingDir\": \"$EXEC_ALLOW_DIR\"}" | jq -r '.success')"
+check_result "Absolute-path rule blocks other arguments" "exec_failed" "$(exec_allow '{"command": "ls", "args": ["-l"]}' | jq -r '.error_type')"
+check_result "Tab-separated argument pattern allows matching arguments" "ok" "$(exec_allow '{"command": "printf", "args": ["ok"]}' | jq -r '.stdout')"
+check_result "Tab-separated argument pattern blocks other arguments" "exec_failed" "$(exec_allow '{"command": "printf", "args": ["other"]}' | jq -r '.error_type')"
+
+# A relative command resolves against the working directory it runs in, not the proxy's
+RELATIVE_LS="${LS_PATH#/}"
+mkdir -p "$EXEC_ALLOW_DIR/$(dirname "$RELATIVE_LS")"
+printf '#!/bin/sh\necho pwned\n' > "$EXEC_ALLOW_DIR/$RELATIVE_LS"
+chmod +x "$EXEC_ALLOW_DIR/$RELATIVE_LS"
+RESPONSE=$(exec_allow "{\"command\": \"$RELATIVE_LS\", \"workingDir\": \"$EXEC_ALLOW_DIR\"}")
+check_result "Relative command planted in the working directory is blocked" "exec_failed" "$(echo "$RESPONSE" | jq -r '.error_type')"
+check_result "Relative command resolving to the allowed binary runs" "true" "$(exec_allow "{\"command\": \"$RELATIVE_LS\", \"workingDir\": \"/\"}" | jq -r '.success')"
+
+kill $EXEC_ALLOW_PID 2>/dev/null || true
+wait $EXEC_ALLOW_PID 2>/dev/null || true
+rm -rf "$EXEC_ALLOW_DIR"
+
+# Exec processes beyond --exec-max-concurrent are rejected until a slot is released
+EXEC_LIMIT_PORT=$((PORT + 59))
+./build/rbite-proxy --port $EXEC_LIMIT_PORT --enable-exec --exec-max-concurrent 2 --no-upgrade-check > /tmp/proxy-execlimit.log 2>&1 &
+EXEC_LIMIT_PID=$!
+sleep 1
+
+EXEC_SLEEP_PIDS=""
+for i in 1 2; do
+    curl -s -o /dev/null -X POST "http://localhost:$EXEC_LIMIT_PORT/exec" -H "Content-Type: application/json" \
+        -d '{"command": "sleep", "args": ["5"], "timeout": 1}' &
+    EXEC_SLEEP_PIDS="$EXEC_SLEEP_PIDS $!"
+done
+sleep 0.5
+STATUS=$(curl -s -o /tmp/execlimit-body.json -w "%{http_code}" -X POST "http://localhost:$EXEC_LIMIT_PORT/exec" \
+    -H "Content-Type: application/json" -d '{"command": "echo", "args": ["hi"]}')
+check_result "Exec beyond --exec-max-concurrent returns 503" "503" "$STATUS"
+check_result "Exec beyond --exec-max-concurrent returns exec_failed" "exec_failed" "$(jq -r '.error_type' /tmp/execlimit-body.json)"
+check_result "/metrics reports the running exec processes" "2 2" "$(curl -s "http://localhost:$EXEC_LIMIT_PORT/metrics" | jq -r '"\(.activeExecs) \(.maxExecs)"')"
+
+# Timed-out processes are killed and give their slots back
+wait $EXEC_SLEEP_PIDS
+RESPONSE=$(curl -s -X POST "http://localhost:$EXEC_LIMIT_PORT/exec" -H "Content-Type: application/json" \
+    -d '{"command": "echo", "args": ["hi"]}')
+check_result "Exec runs again once timed-out processes released their slots" "hi" "$(echo "$RESPONSE" | jq -r '.stdout' | tr -d '\n')"
+
+kill $EXEC_LIMIT_PID 2>/dev/null || true
+wait $EXEC_LIMIT_PID 2>/dev/null || true
+rm -f /tmp/execlimit-body.json
+
 echo ""
 
 echo -e "${GREEN}🎉 All test sections completed!${NC}"